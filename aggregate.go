@@ -0,0 +1,313 @@
+package autoquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Aggregation computes client-side aggregates over the items matching an expression.
+//
+// The query projection is narrowed to the attributes needed for the aggregation, so indexes which
+// project only those attributes are also considered during index selection. If no attributes are
+// needed, such as when only counting items without grouping, the aggregation is executed as a
+// count query.
+type Aggregation struct {
+	client    *Client
+	tableName string
+	expr      *Expression
+
+	sumAttributes []string
+	minAttributes []string
+	maxAttributes []string
+	avgAttributes []string
+	groupBy       []string
+}
+
+// AggregationResult contains the result of an aggregation.
+type AggregationResult struct {
+	// Groups contains the aggregates for each group, in the order in which each group was first
+	// encountered. If GroupBy is not specified, there is a single group containing all items,
+	// unless no items matched the expression.
+	Groups []*AggregateGroup
+}
+
+// AggregateGroup contains the aggregates for a group of items.
+type AggregateGroup struct {
+	// Key contains the value of each group-by attribute for the group. An attribute which is
+	// missing from the group's items is not included in Key.
+	Key map[string]interface{}
+
+	// Count is the number of items in the group.
+	Count int
+
+	// Sum, Min, Max and Avg contain the aggregates for each aggregated attribute. Items where an
+	// aggregated attribute is missing or is not a number are skipped for that attribute. If an
+	// attribute is missing from every item in the group, it is not included.
+	Sum map[string]float64
+	Min map[string]float64
+	Max map[string]float64
+	Avg map[string]float64
+
+	// counts contains the number of numeric values of each aggregated attribute
+	counts map[string]int
+}
+
+// Aggregate initializes an aggregation over the items matching expr on a table. Any attributes
+// selected by expr are replaced by the attributes needed for the aggregation.
+func (client *Client) Aggregate(tableName string, expr *Expression) *Aggregation {
+	return &Aggregation{
+		client:    client,
+		tableName: tableName,
+		expr:      expr,
+	}
+}
+
+// Aggregate initializes an aggregation over the items matching expr on the table. See
+// Client.Aggregate.
+func (table Table) Aggregate(expr *Expression) *Aggregation {
+	return table.autoqueryClient.Aggregate(table.name, expr)
+}
+
+// Sum adds a sum aggregate on each of the numeric attributes attrs.
+func (agg *Aggregation) Sum(attrs ...string) *Aggregation {
+	agg.sumAttributes = append(agg.sumAttributes, attrs...)
+	return agg
+}
+
+// Min adds a minimum aggregate on each of the numeric attributes attrs.
+func (agg *Aggregation) Min(attrs ...string) *Aggregation {
+	agg.minAttributes = append(agg.minAttributes, attrs...)
+	return agg
+}
+
+// Max adds a maximum aggregate on each of the numeric attributes attrs.
+func (agg *Aggregation) Max(attrs ...string) *Aggregation {
+	agg.maxAttributes = append(agg.maxAttributes, attrs...)
+	return agg
+}
+
+// Avg adds an average aggregate on each of the numeric attributes attrs.
+func (agg *Aggregation) Avg(attrs ...string) *Aggregation {
+	agg.avgAttributes = append(agg.avgAttributes, attrs...)
+	return agg
+}
+
+// GroupBy groups items by the values of attrs. Subsequent calls to GroupBy append to the existing
+// group-by attributes.
+func (agg *Aggregation) GroupBy(attrs ...string) *Aggregation {
+	agg.groupBy = append(agg.groupBy, attrs...)
+	return agg
+}
+
+// Execute queries all items matching the expression and computes the aggregates.
+func (agg *Aggregation) Execute(ctx context.Context) (*AggregationResult, error) {
+	attributes := agg.neededAttributes()
+
+	// without any attributes to aggregate, the aggregation is a count
+	if len(attributes) == 0 {
+		countResult, err := agg.client.Count(ctx, agg.tableName, agg.expr)
+		if err != nil {
+			return nil, err
+		}
+		result := &AggregationResult{Groups: []*AggregateGroup{}}
+		if countResult.Count > 0 {
+			group := newAggregateGroup(map[string]interface{}{})
+			group.Count = countResult.Count
+			result.Groups = append(result.Groups, group)
+		}
+		return result, nil
+	}
+
+	// only the needed attributes are queried, though the selected index must also project the
+	// expression's filter attributes
+	expr := agg.expr.clone()
+	expr.attributes = attributes
+	expr.attributesSpecified = true
+
+	// sums are accumulated for both sum and average aggregates
+	summedAttributes := uniqueStrings(agg.sumAttributes, agg.avgAttributes)
+
+	groups := []*AggregateGroup{}
+	groupsByKey := map[string]*AggregateGroup{}
+
+	parser := agg.client.Query(agg.tableName, expr)
+	for {
+		item, err := parser.NextRaw(ctx)
+		if isParsingComplete(err) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		groupKey, err := agg.groupKey(item)
+		if err != nil {
+			return nil, err
+		}
+		group, found := groupsByKey[groupKey.id]
+		if !found {
+			group = newAggregateGroup(groupKey.values)
+			groups = append(groups, group)
+			groupsByKey[groupKey.id] = group
+		}
+
+		agg.addItem(group, item, summedAttributes)
+	}
+
+	for _, group := range groups {
+		for _, attr := range agg.avgAttributes {
+			if count := group.counts[attr]; count > 0 {
+				group.Avg[attr] = group.Sum[attr] / float64(count)
+			}
+		}
+		// sums are only reported for attributes with sum aggregates
+		for attr := range group.Sum {
+			if !containsString(agg.sumAttributes, attr) {
+				delete(group.Sum, attr)
+			}
+		}
+	}
+
+	return &AggregationResult{Groups: groups}, nil
+}
+
+func (agg *Aggregation) neededAttributes() []string {
+	return uniqueStrings(agg.groupBy,
+		agg.sumAttributes, agg.minAttributes, agg.maxAttributes, agg.avgAttributes)
+}
+
+type aggregateGroupKey struct {
+	id     string
+	values map[string]interface{}
+}
+
+// groupKey returns the group-by values of an item and an ID which is identical for items with
+// equal group-by values, including numbers with different formatting.
+func (agg *Aggregation) groupKey(
+	item map[string]*dynamodb.AttributeValue) (*aggregateGroupKey, error) {
+
+	key := &aggregateGroupKey{values: map[string]interface{}{}}
+	idParts := []string{}
+	for _, attr := range agg.groupBy {
+		av, found := item[attr]
+		if !found {
+			idParts = append(idParts, "")
+			continue
+		}
+
+		var value interface{}
+		if err := dynamodbattribute.Unmarshal(av, &value); err != nil {
+			return nil, err
+		}
+		key.values[attr] = value
+
+		idPart, err := json.Marshal(canonicalAttributeValue(av))
+		if err != nil {
+			return nil, err
+		}
+		idParts = append(idParts, string(idPart))
+	}
+	key.id = strings.Join(idParts, "\x00")
+
+	return key, nil
+}
+
+// canonicalAttributeValue returns a copy of av in which numbers are normalized and sets are
+// sorted, so that equal values have identical JSON encodings.
+func canonicalAttributeValue(av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if av == nil {
+		return nil
+	}
+
+	canonical := *av
+	if av.N != nil {
+		canonical.N = aws.String(normalizeNumber(*av.N))
+	}
+	if av.NS != nil {
+		numbers := make([]string, 0, len(av.NS))
+		for _, n := range av.NS {
+			numbers = append(numbers, normalizeNumber(aws.StringValue(n)))
+		}
+		sort.Strings(numbers)
+		canonical.NS = aws.StringSlice(numbers)
+	}
+	if av.SS != nil {
+		strs := aws.StringValueSlice(av.SS)
+		sort.Strings(strs)
+		canonical.SS = aws.StringSlice(strs)
+	}
+	if av.BS != nil {
+		canonical.BS = append([][]byte{}, av.BS...)
+		sort.Slice(canonical.BS, func(i, j int) bool {
+			return bytes.Compare(canonical.BS[i], canonical.BS[j]) < 0
+		})
+	}
+	if av.M != nil {
+		canonical.M = make(map[string]*dynamodb.AttributeValue, len(av.M))
+		for attr, value := range av.M {
+			canonical.M[attr] = canonicalAttributeValue(value)
+		}
+	}
+	if av.L != nil {
+		canonical.L = make([]*dynamodb.AttributeValue, 0, len(av.L))
+		for _, value := range av.L {
+			canonical.L = append(canonical.L, canonicalAttributeValue(value))
+		}
+	}
+	return &canonical
+}
+
+func newAggregateGroup(key map[string]interface{}) *AggregateGroup {
+	return &AggregateGroup{
+		Key:    key,
+		Sum:    map[string]float64{},
+		Min:    map[string]float64{},
+		Max:    map[string]float64{},
+		Avg:    map[string]float64{},
+		counts: map[string]int{},
+	}
+}
+
+func (agg *Aggregation) addItem(group *AggregateGroup,
+	item map[string]*dynamodb.AttributeValue, summedAttributes []string) {
+
+	group.Count++
+
+	for _, attr := range summedAttributes {
+		if value, ok := numberAttribute(item, attr); ok {
+			group.Sum[attr] += value
+			group.counts[attr]++
+		}
+	}
+
+	for _, attr := range agg.minAttributes {
+		if value, ok := numberAttribute(item, attr); ok {
+			if min, found := group.Min[attr]; !found || value < min {
+				group.Min[attr] = value
+			}
+		}
+	}
+	for _, attr := range agg.maxAttributes {
+		if value, ok := numberAttribute(item, attr); ok {
+			if max, found := group.Max[attr]; !found || value > max {
+				group.Max[attr] = value
+			}
+		}
+	}
+}
+
+func numberAttribute(item map[string]*dynamodb.AttributeValue, attr string) (float64, bool) {
+	av, found := item[attr]
+	if !found || av.N == nil {
+		return 0.0, false
+	}
+	value, err := strconv.ParseFloat(*av.N, 64)
+	return value, err == nil
+}
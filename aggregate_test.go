@@ -0,0 +1,155 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// newAggregateService creates a fake table whose items have the given group and amount values.
+// Nil values are left out of the items.
+func newAggregateService(groups []*dynamodb.AttributeValue,
+	amounts []*dynamodb.AttributeValue) *fakeService {
+
+	svc := newFakeService(len(groups), 2)
+	for i, item := range svc.items {
+		if groups[i] != nil {
+			item["group"] = groups[i]
+		}
+		if amounts[i] != nil {
+			item["amount"] = amounts[i]
+		}
+	}
+	return svc
+}
+
+func number(n string) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(n)}
+}
+
+func TestAggregateGroupsEqualNumbers(t *testing.T) {
+	ctx := context.Background()
+	svc := newAggregateService(
+		[]*dynamodb.AttributeValue{number("1"), number("1.0"), number("2"), number("10e-1"), nil},
+		[]*dynamodb.AttributeValue{
+			number("3"), number("5"), number("7"), number("10"), number("1"),
+		})
+	client := NewClient(svc)
+
+	result, err := client.Aggregate(fakeTableName, NewExpression().Equal("pk", "p")).
+		GroupBy("group").Sum("amount").Min("amount").Max("amount").Avg("amount").
+		Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Groups) != 3 {
+		t.Fatalf("expected 3 groups, found %d", len(result.Groups))
+	}
+
+	one, two, missing := result.Groups[0], result.Groups[1], result.Groups[2]
+	if one.Key["group"] != 1.0 || one.Count != 3 {
+		t.Errorf("expected 3 items in group 1, found %d in group %v", one.Count, one.Key)
+	}
+	if one.Sum["amount"] != 18 || one.Min["amount"] != 3 || one.Max["amount"] != 10 ||
+		one.Avg["amount"] != 6 {
+		t.Errorf("unexpected aggregates for group 1: %+v", one)
+	}
+	if two.Key["group"] != 2.0 || two.Count != 1 || two.Sum["amount"] != 7 {
+		t.Errorf("unexpected group 2: %+v", two)
+	}
+	if _, found := missing.Key["group"]; found || missing.Count != 1 {
+		t.Errorf("expected a group for the item without a group, found %+v", missing)
+	}
+}
+
+func TestAggregateGroupsEqualCompositeValues(t *testing.T) {
+	ctx := context.Background()
+	svc := newAggregateService(
+		[]*dynamodb.AttributeValue{
+			{M: map[string]*dynamodb.AttributeValue{
+				"size": number("1"),
+				"tags": {SS: aws.StringSlice([]string{"a", "b"})},
+			}},
+			{M: map[string]*dynamodb.AttributeValue{
+				"tags": {SS: aws.StringSlice([]string{"b", "a"})},
+				"size": number("1.00"),
+			}},
+			{M: map[string]*dynamodb.AttributeValue{
+				"size": number("2"),
+				"tags": {SS: aws.StringSlice([]string{"a", "b"})},
+			}},
+		},
+		[]*dynamodb.AttributeValue{nil, nil, nil})
+	client := NewClient(svc)
+
+	result, err := client.Aggregate(fakeTableName, NewExpression().Equal("pk", "p")).
+		GroupBy("group").Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Groups) != 2 || result.Groups[0].Count != 2 || result.Groups[1].Count != 1 {
+		t.Errorf("expected groups of 2 and 1 items, found %+v", result.Groups)
+	}
+}
+
+func TestAggregateSkipsNonNumericValues(t *testing.T) {
+	ctx := context.Background()
+	svc := newAggregateService(
+		[]*dynamodb.AttributeValue{nil, nil, nil},
+		[]*dynamodb.AttributeValue{number("4"), {S: aws.String("many")}, nil})
+	client := NewClient(svc)
+
+	result, err := client.Aggregate(fakeTableName, NewExpression().Equal("pk", "p")).
+		Sum("amount").Avg("amount").Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Groups) != 1 {
+		t.Fatalf("expected a single group, found %d", len(result.Groups))
+	}
+	group := result.Groups[0]
+	if group.Count != 3 || group.Sum["amount"] != 4 || group.Avg["amount"] != 4 {
+		t.Errorf("unexpected aggregates: %+v", group)
+	}
+	if _, found := group.Min["amount"]; found {
+		t.Errorf("expected no min aggregate, found %v", group.Min)
+	}
+}
+
+func TestAggregateCountOnly(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 2))
+
+	result, err := client.Aggregate(fakeTableName, NewExpression().Equal("pk", "p")).Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Groups) != 1 || result.Groups[0].Count != 5 {
+		t.Errorf("expected a single group of 5 items, found %+v", result.Groups)
+	}
+
+	empty, err := NewClient(newFakeService(0, 2)).
+		Aggregate(fakeTableName, NewExpression().Equal("pk", "p")).Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.Groups) != 0 {
+		t.Errorf("expected no groups, found %+v", empty.Groups)
+	}
+}
+
+func TestCanonicalAttributeValue(t *testing.T) {
+	a := canonicalAttributeValue(&dynamodb.AttributeValue{
+		NS: aws.StringSlice([]string{"2", "1.50"}),
+		L:  []*dynamodb.AttributeValue{number("3.0")},
+	})
+	b := canonicalAttributeValue(&dynamodb.AttributeValue{
+		NS: aws.StringSlice([]string{"1.5", "2.0"}),
+		L:  []*dynamodb.AttributeValue{number("3")},
+	})
+	if a.String() != b.String() {
+		t.Errorf("expected equal canonical values, found %s and %s", a, b)
+	}
+}
@@ -0,0 +1,86 @@
+// Package awsv2autoquery provides autoquery clients backed by the AWS SDK for Go v2.
+//
+// A client created with NewClient makes its DynamoDB calls, including DescribeTable and Query,
+// with a v2 DynamoDB client, and marshals items with the v2 attributevalue package. Expressions,
+// parsers and index selection behave the same as with a client created by autoquery.NewClient:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	client := awsv2autoquery.NewClient(dynamodb.NewFromConfig(cfg))
+//	expr := autoquery.NewExpression().Equal("Director", "Christopher Nolan")
+//	parser := client.Query("Movies", expr)
+//
+// Items are marshaled and unmarshaled with "dynamodbav" struct tags, as interpreted by the v2
+// attributevalue package, including its support for types implementing attributevalue.Marshaler
+// and attributevalue.Unmarshaler. Values in query conditions and Update actions are marshaled
+// with the same encoder.
+//
+// This module requires an untagged pseudo-version of the core autoquery module, which is replaced
+// with the local copy when building within the repository. The requirement must be updated to
+// the tagged core release when this module is tagged.
+package awsv2autoquery
+
+import (
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	dynamodbv1 "github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// NewClient creates an autoquery client which makes its calls with a v2 DynamoDB client and
+// marshals items with the v2 attributevalue package. Table metadata is retrieved with
+// DescribeTable.
+func NewClient(api DynamoDBAPI) *autoquery.Client {
+	client := autoquery.NewClient(NewService(api))
+	client.Decoder = NewDecoder(nil)
+	client.Encoder = NewEncoder(nil)
+	return client
+}
+
+// NewClientWithMetadataProvider creates an autoquery client which makes its calls with a v2
+// DynamoDB client and retrieves table metadata from provider, as with
+// autoquery.NewClientWithMetadataProvider.
+func NewClientWithMetadataProvider(
+	api DynamoDBAPI, provider autoquery.TableDescriptionProvider) *autoquery.Client {
+
+	client := autoquery.NewClientWithMetadataProvider(NewService(api), provider)
+	client.Decoder = NewDecoder(nil)
+	client.Encoder = NewEncoder(nil)
+	return client
+}
+
+// NewDecoder creates an autoquery.Decoder from a v2 attributevalue.Decoder. If decoder is nil, a
+// decoder with default options is used.
+func NewDecoder(decoder *attributevalue.Decoder) autoquery.Decoder {
+	if decoder == nil {
+		decoder = attributevalue.NewDecoder()
+	}
+	return autoquery.DecoderFunc(
+		func(item map[string]*dynamodbv1.AttributeValue, out interface{}) error {
+			return decoder.Decode(&types.AttributeValueMemberM{Value: toV2Item(item)}, out)
+		})
+}
+
+// NewEncoder creates an autoquery.Encoder from a v2 attributevalue.Encoder. If encoder is nil, an
+// encoder with default options is used.
+func NewEncoder(encoder *attributevalue.Encoder) autoquery.Encoder {
+	if encoder == nil {
+		encoder = attributevalue.NewEncoder()
+	}
+	return autoquery.EncoderFunc(
+		func(in interface{}) (map[string]*dynamodbv1.AttributeValue, error) {
+			av, err := encoder.Encode(in)
+			if err != nil {
+				return nil, err
+			}
+			// as with attributevalue.MarshalMap, values which are not maps encode as empty items
+			m, ok := av.(*types.AttributeValueMemberM)
+			if !ok {
+				return map[string]*dynamodbv1.AttributeValue{}, nil
+			}
+			return toV1Item(m.Value), nil
+		})
+}
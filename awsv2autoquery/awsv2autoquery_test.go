@@ -0,0 +1,291 @@
+package awsv2autoquery
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/aws/smithy-go"
+)
+
+const fakeTableName = "fake-table"
+
+// fakeAPI is a v2 DynamoDB client holding the items of a single table with a string partition
+// key pk and a number sort key sk. Calls which are not used by the tests panic.
+type fakeAPI struct {
+	DynamoDBAPI
+
+	mutex     sync.Mutex
+	items     []map[string]types.AttributeValue
+	pageSize  int
+	queries   []*dynamodb.QueryInput
+	queryErrs []error
+}
+
+func newFakeAPI(numItems, pageSize int) *fakeAPI {
+	api := &fakeAPI{pageSize: pageSize}
+	for i := 0; i < numItems; i++ {
+		api.items = append(api.items, map[string]types.AttributeValue{
+			"pk":    &types.AttributeValueMemberS{Value: "p"},
+			"sk":    &types.AttributeValueMemberN{Value: strconv.Itoa(i)},
+			"value": &types.AttributeValueMemberS{Value: "v" + strconv.Itoa(i)},
+		})
+	}
+	return api
+}
+
+func (api *fakeAPI) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+
+	if aws.ToString(input.TableName) != fakeTableName {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("table not found")}
+	}
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{
+		TableName: aws.String(fakeTableName),
+		ItemCount: aws.Int64(int64(len(api.items))),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
+		},
+	}}, nil
+}
+
+func (api *fakeAPI) Query(ctx context.Context, input *dynamodb.QueryInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	api.queries = append(api.queries, input)
+	if len(api.queryErrs) > 0 {
+		err := api.queryErrs[0]
+		api.queryErrs = api.queryErrs[1:]
+		return nil, err
+	}
+
+	start := 0
+	if input.ExclusiveStartKey != nil {
+		start = api.find(input.ExclusiveStartKey) + 1
+	}
+	end := start + api.pageSize
+	if end > len(api.items) {
+		end = len(api.items)
+	}
+	output := &dynamodb.QueryOutput{
+		Items:        api.items[start:end],
+		Count:        int32(end - start),
+		ScannedCount: int32(end - start),
+	}
+	if end < len(api.items) {
+		output.LastEvaluatedKey = map[string]types.AttributeValue{
+			"pk": api.items[end-1]["pk"],
+			"sk": api.items[end-1]["sk"],
+		}
+	}
+	return output, nil
+}
+
+func (api *fakeAPI) GetItem(ctx context.Context, input *dynamodb.GetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	output := &dynamodb.GetItemOutput{}
+	if i := api.find(input.Key); i >= 0 {
+		output.Item = api.items[i]
+	}
+	return output, nil
+}
+
+func (api *fakeAPI) PutItem(ctx context.Context, input *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	// the only condition used by the tests is that the item does not exist
+	i := api.find(input.Item)
+	if i >= 0 && strings.Contains(aws.ToString(input.ConditionExpression), "attribute_not_exists") {
+		return nil, &smithy.OperationError{
+			ServiceID:     "DynamoDB",
+			OperationName: "PutItem",
+			Err: &types.ConditionalCheckFailedException{
+				Message: aws.String("The conditional request failed"),
+			},
+		}
+	}
+	if i >= 0 {
+		api.items[i] = input.Item
+	} else {
+		api.items = append(api.items, input.Item)
+	}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// find returns the index of the item with the same key as item, or -1 if there is none.
+func (api *fakeAPI) find(item map[string]types.AttributeValue) int {
+	pk, _ := item["pk"].(*types.AttributeValueMemberS)
+	sk, _ := item["sk"].(*types.AttributeValueMemberN)
+	if pk == nil || sk == nil {
+		return -1
+	}
+	for i, stored := range api.items {
+		if stored["pk"].(*types.AttributeValueMemberS).Value == pk.Value &&
+			stored["sk"].(*types.AttributeValueMemberN).Value == sk.Value {
+			return i
+		}
+	}
+	return -1
+}
+
+type testItem struct {
+	PK    string `dynamodbav:"pk"`
+	SK    int    `dynamodbav:"sk"`
+	Value string `dynamodbav:"value"`
+}
+
+type testKey struct {
+	PK string `dynamodbav:"pk"`
+	SK int    `dynamodbav:"sk"`
+}
+
+// upperString is marshaled in upper case by the v2 attributevalue package.
+type upperString string
+
+func (s upperString) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return &types.AttributeValueMemberS{Value: strings.ToUpper(string(s))}, nil
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeAPI(5, 2)
+	client := NewClient(api)
+
+	parser := client.Query(fakeTableName, autoquery.NewExpression().Equal("pk", "p"))
+	sortKeys := []int{}
+	for {
+		item := testItem{}
+		err := parser.Next(ctx, &item)
+		if _, complete := err.(*autoquery.ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if item.Value != "v"+strconv.Itoa(item.SK) {
+			t.Errorf("unexpected item: %+v", item)
+		}
+		sortKeys = append(sortKeys, item.SK)
+	}
+	if len(sortKeys) != 5 || sortKeys[4] != 4 {
+		t.Errorf("expected items 0 through 4, found %v", sortKeys)
+	}
+
+	// pages are continued from the converted last evaluated keys
+	if len(api.queries) != 3 {
+		t.Fatalf("expected 3 queries, found %d", len(api.queries))
+	}
+	startKey := api.queries[2].ExclusiveStartKey
+	if sk, _ := startKey["sk"].(*types.AttributeValueMemberN); sk == nil || sk.Value != "3" {
+		t.Errorf("unexpected exclusive start key: %v", startKey)
+	}
+	if aws.ToString(api.queries[0].KeyConditionExpression) == "" {
+		t.Error("expected a key condition expression")
+	}
+}
+
+func TestQueryValuesUseEncoder(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeAPI(1, 1)
+	client := NewClient(api)
+
+	parser := client.Query(fakeTableName, autoquery.NewExpression().Equal("pk", upperString("p")))
+	if err := parser.Next(ctx, &testItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, value := range api.queries[0].ExpressionAttributeValues {
+		if s, isString := value.(*types.AttributeValueMemberS); isString && s.Value == "P" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the value to be marshaled by the v2 encoder, found %v",
+			api.queries[0].ExpressionAttributeValues)
+	}
+}
+
+func TestQueryRetriesConvertedErrors(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeAPI(1, 1)
+	api.queryErrs = []error{&smithy.OperationError{
+		ServiceID:     "DynamoDB",
+		OperationName: "Query",
+		Err: &types.ProvisionedThroughputExceededException{
+			Message: aws.String("throttled"),
+		},
+	}}
+	client := NewClient(api)
+	client.RetryPolicy = &autoquery.RetryPolicy{MaxAttempts: 2}
+
+	parser := client.Query(fakeTableName, autoquery.NewExpression().Equal("pk", "p"))
+	if err := parser.Next(ctx, &testItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.queries) != 2 {
+		t.Errorf("expected the throttled query to be retried, found %d queries", len(api.queries))
+	}
+}
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeAPI(3, 3))
+
+	item := testItem{}
+	if err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 2}, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.SK != 2 || item.Value != "v2" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+
+	err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 5}, &item)
+	if _, notFound := err.(*autoquery.ErrItemNotFound); !notFound {
+		t.Errorf("expected ErrItemNotFound, found %v", err)
+	}
+}
+
+func TestPutConditionFailed(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeAPI(1, 1)
+	client := NewClient(api)
+	notExists := autoquery.WithCondition(expression.AttributeNotExists(expression.Name("pk")))
+
+	err := client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 1, Value: "new"}, notExists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.items) != 2 {
+		t.Fatalf("expected the item to be put, found %v", api.items)
+	}
+
+	err = client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 0, Value: "replaced"}, notExists)
+	var conditionErr *autoquery.ErrConditionFailed
+	if !errors.As(err, &conditionErr) {
+		t.Errorf("expected ErrConditionFailed, found %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+package awsv2autoquery
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	dynamodbv1 "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/smithy-go"
+)
+
+// attribute values
+
+func toV2AttributeValue(av *dynamodbv1.AttributeValue) types.AttributeValue {
+	switch {
+	case av == nil:
+		return nil
+	case av.S != nil:
+		return &types.AttributeValueMemberS{Value: *av.S}
+	case av.N != nil:
+		return &types.AttributeValueMemberN{Value: *av.N}
+	case av.B != nil:
+		return &types.AttributeValueMemberB{Value: av.B}
+	case av.BOOL != nil:
+		return &types.AttributeValueMemberBOOL{Value: *av.BOOL}
+	case av.NULL != nil:
+		return &types.AttributeValueMemberNULL{Value: *av.NULL}
+	case av.SS != nil:
+		return &types.AttributeValueMemberSS{Value: awsv1.StringValueSlice(av.SS)}
+	case av.NS != nil:
+		return &types.AttributeValueMemberNS{Value: awsv1.StringValueSlice(av.NS)}
+	case av.BS != nil:
+		return &types.AttributeValueMemberBS{Value: av.BS}
+	case av.M != nil:
+		return &types.AttributeValueMemberM{Value: toV2Item(av.M)}
+	case av.L != nil:
+		list := make([]types.AttributeValue, 0, len(av.L))
+		for _, element := range av.L {
+			list = append(list, toV2AttributeValue(element))
+		}
+		return &types.AttributeValueMemberL{Value: list}
+	}
+	return &types.AttributeValueMemberNULL{Value: true}
+}
+
+func toV1AttributeValue(av types.AttributeValue) *dynamodbv1.AttributeValue {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &dynamodbv1.AttributeValue{S: awsv1.String(v.Value)}
+	case *types.AttributeValueMemberN:
+		return &dynamodbv1.AttributeValue{N: awsv1.String(v.Value)}
+	case *types.AttributeValueMemberB:
+		return &dynamodbv1.AttributeValue{B: v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return &dynamodbv1.AttributeValue{BOOL: awsv1.Bool(v.Value)}
+	case *types.AttributeValueMemberNULL:
+		return &dynamodbv1.AttributeValue{NULL: awsv1.Bool(v.Value)}
+	case *types.AttributeValueMemberSS:
+		return &dynamodbv1.AttributeValue{SS: awsv1.StringSlice(v.Value)}
+	case *types.AttributeValueMemberNS:
+		return &dynamodbv1.AttributeValue{NS: awsv1.StringSlice(v.Value)}
+	case *types.AttributeValueMemberBS:
+		return &dynamodbv1.AttributeValue{BS: v.Value}
+	case *types.AttributeValueMemberM:
+		return &dynamodbv1.AttributeValue{M: toV1Item(v.Value)}
+	case *types.AttributeValueMemberL:
+		list := make([]*dynamodbv1.AttributeValue, 0, len(v.Value))
+		for _, element := range v.Value {
+			list = append(list, toV1AttributeValue(element))
+		}
+		return &dynamodbv1.AttributeValue{L: list}
+	}
+	return nil
+}
+
+func toV2Item(item map[string]*dynamodbv1.AttributeValue) map[string]types.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	converted := make(map[string]types.AttributeValue, len(item))
+	for attribute, value := range item {
+		converted[attribute] = toV2AttributeValue(value)
+	}
+	return converted
+}
+
+func toV1Item(item map[string]types.AttributeValue) map[string]*dynamodbv1.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	converted := make(map[string]*dynamodbv1.AttributeValue, len(item))
+	for attribute, value := range item {
+		converted[attribute] = toV1AttributeValue(value)
+	}
+	return converted
+}
+
+func toV2Items(items []map[string]*dynamodbv1.AttributeValue) []map[string]types.AttributeValue {
+	if items == nil {
+		return nil
+	}
+	converted := make([]map[string]types.AttributeValue, 0, len(items))
+	for _, item := range items {
+		converted = append(converted, toV2Item(item))
+	}
+	return converted
+}
+
+func toV1Items(items []map[string]types.AttributeValue) []map[string]*dynamodbv1.AttributeValue {
+	if items == nil {
+		return nil
+	}
+	converted := make([]map[string]*dynamodbv1.AttributeValue, 0, len(items))
+	for _, item := range items {
+		converted = append(converted, toV1Item(item))
+	}
+	return converted
+}
+
+// scalar parameters
+
+func toV2Limit(limit *int64) *int32 {
+	if limit == nil {
+		return nil
+	}
+	converted := int32(*limit)
+	return &converted
+}
+
+func toV1Names(names map[string]string) map[string]*string {
+	if names == nil {
+		return nil
+	}
+	return awsv1.StringMap(names)
+}
+
+func toV2Names(names map[string]*string) map[string]string {
+	if names == nil {
+		return nil
+	}
+	return awsv1.StringValueMap(names)
+}
+
+// consumed capacity
+
+func toV1Capacity(capacity *types.Capacity) *dynamodbv1.Capacity {
+	if capacity == nil {
+		return nil
+	}
+	return &dynamodbv1.Capacity{
+		CapacityUnits:      capacity.CapacityUnits,
+		ReadCapacityUnits:  capacity.ReadCapacityUnits,
+		WriteCapacityUnits: capacity.WriteCapacityUnits,
+	}
+}
+
+func toV1IndexCapacities(capacities map[string]types.Capacity) map[string]*dynamodbv1.Capacity {
+	if capacities == nil {
+		return nil
+	}
+	converted := make(map[string]*dynamodbv1.Capacity, len(capacities))
+	for indexName, capacity := range capacities {
+		capacity := capacity
+		converted[indexName] = toV1Capacity(&capacity)
+	}
+	return converted
+}
+
+func toV1ConsumedCapacity(capacity *types.ConsumedCapacity) *dynamodbv1.ConsumedCapacity {
+	if capacity == nil {
+		return nil
+	}
+	return &dynamodbv1.ConsumedCapacity{
+		TableName:              capacity.TableName,
+		CapacityUnits:          capacity.CapacityUnits,
+		ReadCapacityUnits:      capacity.ReadCapacityUnits,
+		WriteCapacityUnits:     capacity.WriteCapacityUnits,
+		Table:                  toV1Capacity(capacity.Table),
+		GlobalSecondaryIndexes: toV1IndexCapacities(capacity.GlobalSecondaryIndexes),
+		LocalSecondaryIndexes:  toV1IndexCapacities(capacity.LocalSecondaryIndexes),
+	}
+}
+
+func toV1ConsumedCapacities(capacities []types.ConsumedCapacity) []*dynamodbv1.ConsumedCapacity {
+	if capacities == nil {
+		return nil
+	}
+	converted := make([]*dynamodbv1.ConsumedCapacity, 0, len(capacities))
+	for i := range capacities {
+		converted = append(converted, toV1ConsumedCapacity(&capacities[i]))
+	}
+	return converted
+}
+
+// table schemas
+
+func toV2KeySchema(keySchema []*dynamodbv1.KeySchemaElement) []types.KeySchemaElement {
+	converted := make([]types.KeySchemaElement, 0, len(keySchema))
+	for _, element := range keySchema {
+		converted = append(converted, types.KeySchemaElement{
+			AttributeName: element.AttributeName,
+			KeyType:       types.KeyType(awsv1.StringValue(element.KeyType)),
+		})
+	}
+	return converted
+}
+
+func toV1KeySchema(keySchema []types.KeySchemaElement) []*dynamodbv1.KeySchemaElement {
+	converted := make([]*dynamodbv1.KeySchemaElement, 0, len(keySchema))
+	for _, element := range keySchema {
+		converted = append(converted, &dynamodbv1.KeySchemaElement{
+			AttributeName: element.AttributeName,
+			KeyType:       awsv1.String(string(element.KeyType)),
+		})
+	}
+	return converted
+}
+
+func toV2Projection(projection *dynamodbv1.Projection) *types.Projection {
+	if projection == nil {
+		return nil
+	}
+	return &types.Projection{
+		ProjectionType:   types.ProjectionType(awsv1.StringValue(projection.ProjectionType)),
+		NonKeyAttributes: awsv1.StringValueSlice(projection.NonKeyAttributes),
+	}
+}
+
+func toV1Projection(projection *types.Projection) *dynamodbv1.Projection {
+	if projection == nil {
+		return nil
+	}
+	return &dynamodbv1.Projection{
+		ProjectionType:   awsv1.String(string(projection.ProjectionType)),
+		NonKeyAttributes: awsv1.StringSlice(projection.NonKeyAttributes),
+	}
+}
+
+func toV2AttributeDefinitions(
+	definitions []*dynamodbv1.AttributeDefinition) []types.AttributeDefinition {
+
+	converted := make([]types.AttributeDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		converted = append(converted, types.AttributeDefinition{
+			AttributeName: definition.AttributeName,
+			AttributeType: types.ScalarAttributeType(awsv1.StringValue(definition.AttributeType)),
+		})
+	}
+	return converted
+}
+
+func toV1AttributeDefinitions(
+	definitions []types.AttributeDefinition) []*dynamodbv1.AttributeDefinition {
+
+	converted := make([]*dynamodbv1.AttributeDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		converted = append(converted, &dynamodbv1.AttributeDefinition{
+			AttributeName: definition.AttributeName,
+			AttributeType: awsv1.String(string(definition.AttributeType)),
+		})
+	}
+	return converted
+}
+
+func toV2ProvisionedThroughput(
+	throughput *dynamodbv1.ProvisionedThroughput) *types.ProvisionedThroughput {
+
+	if throughput == nil {
+		return nil
+	}
+	return &types.ProvisionedThroughput{
+		ReadCapacityUnits:  throughput.ReadCapacityUnits,
+		WriteCapacityUnits: throughput.WriteCapacityUnits,
+	}
+}
+
+func toV1ProvisionedThroughput(throughput *types.ProvisionedThroughputDescription,
+) *dynamodbv1.ProvisionedThroughputDescription {
+
+	if throughput == nil {
+		return nil
+	}
+	return &dynamodbv1.ProvisionedThroughputDescription{
+		ReadCapacityUnits:  throughput.ReadCapacityUnits,
+		WriteCapacityUnits: throughput.WriteCapacityUnits,
+	}
+}
+
+func toV1TableDescription(table *types.TableDescription) *dynamodbv1.TableDescription {
+	if table == nil {
+		return nil
+	}
+
+	converted := &dynamodbv1.TableDescription{
+		TableName:             table.TableName,
+		TableStatus:           awsv1.String(string(table.TableStatus)),
+		KeySchema:             toV1KeySchema(table.KeySchema),
+		AttributeDefinitions:  toV1AttributeDefinitions(table.AttributeDefinitions),
+		ItemCount:             table.ItemCount,
+		ProvisionedThroughput: toV1ProvisionedThroughput(table.ProvisionedThroughput),
+	}
+	if table.BillingModeSummary != nil {
+		converted.BillingModeSummary = &dynamodbv1.BillingModeSummary{
+			BillingMode: awsv1.String(string(table.BillingModeSummary.BillingMode)),
+		}
+	}
+
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		converted.GlobalSecondaryIndexes = append(converted.GlobalSecondaryIndexes,
+			&dynamodbv1.GlobalSecondaryIndexDescription{
+				IndexName:             gsi.IndexName,
+				IndexStatus:           awsv1.String(string(gsi.IndexStatus)),
+				KeySchema:             toV1KeySchema(gsi.KeySchema),
+				Projection:            toV1Projection(gsi.Projection),
+				ItemCount:             gsi.ItemCount,
+				ProvisionedThroughput: toV1ProvisionedThroughput(gsi.ProvisionedThroughput),
+			})
+	}
+	for _, lsi := range table.LocalSecondaryIndexes {
+		converted.LocalSecondaryIndexes = append(converted.LocalSecondaryIndexes,
+			&dynamodbv1.LocalSecondaryIndexDescription{
+				IndexName:  lsi.IndexName,
+				KeySchema:  toV1KeySchema(lsi.KeySchema),
+				Projection: toV1Projection(lsi.Projection),
+				ItemCount:  lsi.ItemCount,
+			})
+	}
+
+	return converted
+}
+
+// errors
+
+// toV1Error converts errors returned by the v2 SDK into the error types returned by the v1 SDK,
+// which are used by autoquery to detect condition failures, canceled transactions and retryable
+// errors.
+func toV1Error(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var canceledErr *types.TransactionCanceledException
+	if errors.As(err, &canceledErr) {
+		converted := &dynamodbv1.TransactionCanceledException{Message_: canceledErr.Message}
+		for _, reason := range canceledErr.CancellationReasons {
+			converted.CancellationReasons = append(converted.CancellationReasons,
+				&dynamodbv1.CancellationReason{
+					Code:    reason.Code,
+					Message: reason.Message,
+					Item:    toV1Item(reason.Item),
+				})
+		}
+		return converted
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return awserr.New(apiErr.ErrorCode(), apiErr.ErrorMessage(), err)
+	}
+
+	return err
+}
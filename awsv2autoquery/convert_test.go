@@ -0,0 +1,177 @@
+package awsv2autoquery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	dynamodbv1 "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/smithy-go"
+)
+
+func TestAttributeValueRoundTrip(t *testing.T) {
+	values := map[string]*dynamodbv1.AttributeValue{
+		"S":        {S: awsv1.String("s")},
+		"empty S":  {S: awsv1.String("")},
+		"N":        {N: awsv1.String("1.5")},
+		"B":        {B: []byte("b")},
+		"BOOL":     {BOOL: awsv1.Bool(false)},
+		"NULL":     {NULL: awsv1.Bool(true)},
+		"SS":       {SS: awsv1.StringSlice([]string{"a", "b"})},
+		"empty SS": {SS: []*string{}},
+		"NS":       {NS: awsv1.StringSlice([]string{"1", "2"})},
+		"empty NS": {NS: []*string{}},
+		"BS":       {BS: [][]byte{[]byte("a"), []byte("b")}},
+		"empty BS": {BS: [][]byte{}},
+		"M": {M: map[string]*dynamodbv1.AttributeValue{
+			"s":    {S: awsv1.String("s")},
+			"null": {NULL: awsv1.Bool(true)},
+		}},
+		"empty M": {M: map[string]*dynamodbv1.AttributeValue{}},
+		"L": {L: []*dynamodbv1.AttributeValue{
+			{N: awsv1.String("1")},
+			{L: []*dynamodbv1.AttributeValue{{SS: awsv1.StringSlice([]string{"x"})}}},
+		}},
+		"empty L": {L: []*dynamodbv1.AttributeValue{}},
+	}
+
+	for name, value := range values {
+		converted := toV1AttributeValue(toV2AttributeValue(value))
+		if !reflect.DeepEqual(converted, value) {
+			t.Errorf("%s: expected %v after conversion, found %v", name, value, converted)
+		}
+	}
+}
+
+func TestV2AttributeValueRoundTrip(t *testing.T) {
+	values := map[string]types.AttributeValue{
+		"S":        &types.AttributeValueMemberS{Value: "s"},
+		"N":        &types.AttributeValueMemberN{Value: "1.5"},
+		"B":        &types.AttributeValueMemberB{Value: []byte("b")},
+		"BOOL":     &types.AttributeValueMemberBOOL{Value: true},
+		"NULL":     &types.AttributeValueMemberNULL{Value: true},
+		"SS":       &types.AttributeValueMemberSS{Value: []string{"a"}},
+		"empty SS": &types.AttributeValueMemberSS{Value: []string{}},
+		"NS":       &types.AttributeValueMemberNS{Value: []string{"1"}},
+		"empty NS": &types.AttributeValueMemberNS{Value: []string{}},
+		"BS":       &types.AttributeValueMemberBS{Value: [][]byte{[]byte("a")}},
+		"empty BS": &types.AttributeValueMemberBS{Value: [][]byte{}},
+		"M": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"n": &types.AttributeValueMemberN{Value: "1"},
+		}},
+		"empty M": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}},
+		"L": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberNULL{Value: true},
+		}},
+		"empty L": &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+	}
+
+	for name, value := range values {
+		converted := toV2AttributeValue(toV1AttributeValue(value))
+		if !reflect.DeepEqual(converted, value) {
+			t.Errorf("%s: expected %v after conversion, found %v", name, value, converted)
+		}
+	}
+}
+
+func TestAttributeValueNil(t *testing.T) {
+	if converted := toV2AttributeValue(nil); converted != nil {
+		t.Errorf("expected nil, found %v", converted)
+	}
+	if converted := toV1AttributeValue(nil); converted != nil {
+		t.Errorf("expected nil, found %v", converted)
+	}
+	if toV2Item(nil) != nil || toV1Item(nil) != nil || toV2Items(nil) != nil ||
+		toV1Items(nil) != nil {
+		t.Error("expected nil items to convert to nil")
+	}
+}
+
+// operationError wraps err as the v2 SDK does for errors returned by operations.
+func operationError(err error) error {
+	return &smithy.OperationError{ServiceID: "DynamoDB", OperationName: "Query", Err: err}
+}
+
+func TestToV1ErrorRetryable(t *testing.T) {
+	retryable := []error{
+		&types.ProvisionedThroughputExceededException{Message: awsv1.String("throttled")},
+		&types.RequestLimitExceeded{Message: awsv1.String("limit exceeded")},
+		&types.InternalServerError{Message: awsv1.String("internal")},
+		&smithy.GenericAPIError{Code: "ThrottlingException", Message: "throttled"},
+	}
+	for _, err := range retryable {
+		converted := toV1Error(operationError(err))
+		if !autoquery.IsRetryableError(converted) {
+			t.Errorf("expected %v to be retryable after conversion, found %v", err, converted)
+		}
+	}
+
+	notRetryable := []error{
+		&types.ResourceNotFoundException{Message: awsv1.String("not found")},
+		errors.New("connection reset"),
+	}
+	for _, err := range notRetryable {
+		if converted := toV1Error(operationError(err)); autoquery.IsRetryableError(converted) {
+			t.Errorf("expected %v not to be retryable after conversion", err)
+		}
+	}
+}
+
+func TestToV1ErrorConditionFailed(t *testing.T) {
+	err := toV1Error(operationError(&types.ConditionalCheckFailedException{
+		Message: awsv1.String("The conditional request failed"),
+	}))
+
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != dynamodbv1.ErrCodeConditionalCheckFailedException {
+		t.Fatalf("expected a conditional check failure, found %v", err)
+	}
+	if aerr.Message() != "The conditional request failed" {
+		t.Errorf("unexpected message %q", aerr.Message())
+	}
+
+	// the original error remains available
+	var conditionErr *types.ConditionalCheckFailedException
+	if !errors.As(aerr.OrigErr(), &conditionErr) {
+		t.Errorf("expected the v2 error to be wrapped, found %v", aerr.OrigErr())
+	}
+}
+
+func TestToV1ErrorTransactionCanceled(t *testing.T) {
+	err := toV1Error(operationError(&types.TransactionCanceledException{
+		Message: awsv1.String("Transaction cancelled"),
+		CancellationReasons: []types.CancellationReason{
+			{Code: awsv1.String("None")},
+			{
+				Code:    awsv1.String("ConditionalCheckFailed"),
+				Message: awsv1.String("The conditional request failed"),
+				Item: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: "p"},
+				},
+			},
+		},
+	}))
+
+	var canceledErr *dynamodbv1.TransactionCanceledException
+	if !errors.As(err, &canceledErr) {
+		t.Fatalf("expected a v1 TransactionCanceledException, found %T", err)
+	}
+	if canceledErr.Code() != dynamodbv1.ErrCodeTransactionCanceledException {
+		t.Errorf("unexpected code %s", canceledErr.Code())
+	}
+	reasons := canceledErr.CancellationReasons
+	if len(reasons) != 2 || awsv1.StringValue(reasons[0].Code) != "None" ||
+		awsv1.StringValue(reasons[1].Code) != "ConditionalCheckFailed" ||
+		awsv1.StringValue(reasons[1].Item["pk"].S) != "p" {
+		t.Errorf("unexpected cancellation reasons: %v", reasons)
+	}
+
+	if toV1Error(nil) != nil {
+		t.Error("expected nil error to convert to nil")
+	}
+}
@@ -0,0 +1,25 @@
+module github.com/dgravesa/dynamodb-autoquery/awsv2autoquery
+
+go 1.24
+
+require (
+	github.com/aws/aws-sdk-go v1.42.9
+	github.com/aws/aws-sdk-go-v2 v1.47.1
+	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.21.8
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.70.0
+	github.com/aws/smithy-go v1.28.2
+	// pseudo-version of the unreleased core module; update to its tagged release at merge
+	github.com/dgravesa/dynamodb-autoquery v0.0.0-20261018134013-5f9ac51caaa3
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.5.4 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.8.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.43.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.13.19 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.13.4 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+)
+
+// the replace directive applies only when building within this repository
+replace github.com/dgravesa/dynamodb-autoquery => ../
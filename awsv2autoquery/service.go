@@ -0,0 +1,554 @@
+package awsv2autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	dynamodbv1 "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoDBAPI is the subset of the AWS SDK for Go v2 DynamoDB client used by autoquery. It is
+// satisfied by *dynamodb.Client.
+type DynamoDBAPI interface {
+	DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	Query(ctx context.Context, input *dynamodb.QueryInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	GetItem(ctx context.Context, input *dynamodb.GetItemInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, input *dynamodb.PutItemInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactGetItems(ctx context.Context, input *dynamodb.TransactGetItemsInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error)
+	TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	CreateTable(ctx context.Context, input *dynamodb.CreateTableInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	UpdateTable(ctx context.Context, input *dynamodb.UpdateTableInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+	DescribeTimeToLive(ctx context.Context, input *dynamodb.DescribeTimeToLiveInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error)
+	UpdateTimeToLive(ctx context.Context, input *dynamodb.UpdateTimeToLiveInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error)
+}
+
+// service adapts a v2 DynamoDB client to the v1 DynamoDB interface used by autoquery. Only the
+// context methods called by autoquery are implemented; calling any other method panics.
+type service struct {
+	dynamodbiface.DynamoDBAPI
+
+	api DynamoDBAPI
+}
+
+// NewService returns a v1 DynamoDB service which makes its calls with api. The service supports
+// the operations made by autoquery clients, using expression parameters only; legacy parameters
+// such as KeyConditions and AttributesToGet are not supported. Request options are ignored.
+//
+// Errors returned by api are converted to awserr.Error values with the same error codes, and
+// canceled transactions are returned as *dynamodb.TransactionCanceledException from the v1 SDK,
+// so that autoquery may detect condition failures and retryable errors.
+//
+// api retries throttling and transient errors with its own retryer before returning them, and
+// autoquery retries the same errors again according to the client's RetryPolicy, so the attempts
+// of both stack. When a RetryPolicy is set on the client, api should be configured to make a
+// single attempt, for example with aws.NopRetryer:
+//
+//	api := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+//		o.Retryer = aws.NopRetryer{}
+//	})
+func NewService(api DynamoDBAPI) dynamodbiface.DynamoDBAPI {
+	return &service{api: api}
+}
+
+func (svc *service) DescribeTableWithContext(ctx awsv1.Context,
+	input *dynamodbv1.DescribeTableInput,
+	_ ...request.Option) (*dynamodbv1.DescribeTableOutput, error) {
+
+	output, err := svc.api.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: input.TableName,
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.DescribeTableOutput{Table: toV1TableDescription(output.Table)}, nil
+}
+
+func (svc *service) QueryWithContext(ctx awsv1.Context, input *dynamodbv1.QueryInput,
+	_ ...request.Option) (*dynamodbv1.QueryOutput, error) {
+
+	output, err := svc.api.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 input.TableName,
+		IndexName:                 input.IndexName,
+		KeyConditionExpression:    input.KeyConditionExpression,
+		FilterExpression:          input.FilterExpression,
+		ProjectionExpression:      input.ProjectionExpression,
+		ExpressionAttributeNames:  toV2Names(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: toV2Item(input.ExpressionAttributeValues),
+		ExclusiveStartKey:         toV2Item(input.ExclusiveStartKey),
+		ConsistentRead:            input.ConsistentRead,
+		ScanIndexForward:          input.ScanIndexForward,
+		Limit:                     toV2Limit(input.Limit),
+		Select:                    types.Select(awsv1.StringValue(input.Select)),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.QueryOutput{
+		Items:            toV1Items(output.Items),
+		Count:            awsv1.Int64(int64(output.Count)),
+		ScannedCount:     awsv1.Int64(int64(output.ScannedCount)),
+		LastEvaluatedKey: toV1Item(output.LastEvaluatedKey),
+		ConsumedCapacity: toV1ConsumedCapacity(output.ConsumedCapacity),
+	}, nil
+}
+
+func (svc *service) GetItemWithContext(ctx awsv1.Context, input *dynamodbv1.GetItemInput,
+	_ ...request.Option) (*dynamodbv1.GetItemOutput, error) {
+
+	output, err := svc.api.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:                input.TableName,
+		Key:                      toV2Item(input.Key),
+		ProjectionExpression:     input.ProjectionExpression,
+		ExpressionAttributeNames: toV2Names(input.ExpressionAttributeNames),
+		ConsistentRead:           input.ConsistentRead,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.GetItemOutput{
+		Item:             toV1Item(output.Item),
+		ConsumedCapacity: toV1ConsumedCapacity(output.ConsumedCapacity),
+	}, nil
+}
+
+func (svc *service) PutItemWithContext(ctx awsv1.Context, input *dynamodbv1.PutItemInput,
+	_ ...request.Option) (*dynamodbv1.PutItemOutput, error) {
+
+	output, err := svc.api.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 input.TableName,
+		Item:                      toV2Item(input.Item),
+		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeNames:  toV2Names(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: toV2Item(input.ExpressionAttributeValues),
+		ReturnValues:              types.ReturnValue(awsv1.StringValue(input.ReturnValues)),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.PutItemOutput{
+		Attributes:       toV1Item(output.Attributes),
+		ConsumedCapacity: toV1ConsumedCapacity(output.ConsumedCapacity),
+	}, nil
+}
+
+func (svc *service) UpdateItemWithContext(ctx awsv1.Context, input *dynamodbv1.UpdateItemInput,
+	_ ...request.Option) (*dynamodbv1.UpdateItemOutput, error) {
+
+	output, err := svc.api.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 input.TableName,
+		Key:                       toV2Item(input.Key),
+		UpdateExpression:          input.UpdateExpression,
+		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeNames:  toV2Names(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: toV2Item(input.ExpressionAttributeValues),
+		ReturnValues:              types.ReturnValue(awsv1.StringValue(input.ReturnValues)),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.UpdateItemOutput{
+		Attributes:       toV1Item(output.Attributes),
+		ConsumedCapacity: toV1ConsumedCapacity(output.ConsumedCapacity),
+	}, nil
+}
+
+func (svc *service) DeleteItemWithContext(ctx awsv1.Context, input *dynamodbv1.DeleteItemInput,
+	_ ...request.Option) (*dynamodbv1.DeleteItemOutput, error) {
+
+	output, err := svc.api.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 input.TableName,
+		Key:                       toV2Item(input.Key),
+		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeNames:  toV2Names(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: toV2Item(input.ExpressionAttributeValues),
+		ReturnValues:              types.ReturnValue(awsv1.StringValue(input.ReturnValues)),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.DeleteItemOutput{
+		Attributes:       toV1Item(output.Attributes),
+		ConsumedCapacity: toV1ConsumedCapacity(output.ConsumedCapacity),
+	}, nil
+}
+
+func (svc *service) BatchGetItemWithContext(ctx awsv1.Context,
+	input *dynamodbv1.BatchGetItemInput,
+	_ ...request.Option) (*dynamodbv1.BatchGetItemOutput, error) {
+
+	requestItems := make(map[string]types.KeysAndAttributes, len(input.RequestItems))
+	for tableName, keysAndAttributes := range input.RequestItems {
+		requestItems[tableName] = types.KeysAndAttributes{
+			Keys:                     toV2Items(keysAndAttributes.Keys),
+			ProjectionExpression:     keysAndAttributes.ProjectionExpression,
+			ExpressionAttributeNames: toV2Names(keysAndAttributes.ExpressionAttributeNames),
+			ConsistentRead:           keysAndAttributes.ConsistentRead,
+		}
+	}
+
+	output, err := svc.api.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+		RequestItems: requestItems,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+
+	converted := &dynamodbv1.BatchGetItemOutput{
+		Responses:        map[string][]map[string]*dynamodbv1.AttributeValue{},
+		UnprocessedKeys:  map[string]*dynamodbv1.KeysAndAttributes{},
+		ConsumedCapacity: toV1ConsumedCapacities(output.ConsumedCapacity),
+	}
+	for tableName, items := range output.Responses {
+		converted.Responses[tableName] = toV1Items(items)
+	}
+	for tableName, keysAndAttributes := range output.UnprocessedKeys {
+		converted.UnprocessedKeys[tableName] = &dynamodbv1.KeysAndAttributes{
+			Keys:                     toV1Items(keysAndAttributes.Keys),
+			ProjectionExpression:     keysAndAttributes.ProjectionExpression,
+			ExpressionAttributeNames: toV1Names(keysAndAttributes.ExpressionAttributeNames),
+			ConsistentRead:           keysAndAttributes.ConsistentRead,
+		}
+	}
+	return converted, nil
+}
+
+func (svc *service) BatchWriteItemWithContext(ctx awsv1.Context,
+	input *dynamodbv1.BatchWriteItemInput,
+	_ ...request.Option) (*dynamodbv1.BatchWriteItemOutput, error) {
+
+	requestItems := make(map[string][]types.WriteRequest, len(input.RequestItems))
+	for tableName, requests := range input.RequestItems {
+		requestItems[tableName] = toV2WriteRequests(requests)
+	}
+
+	output, err := svc.api.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: requestItems,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+
+	converted := &dynamodbv1.BatchWriteItemOutput{
+		UnprocessedItems: map[string][]*dynamodbv1.WriteRequest{},
+		ConsumedCapacity: toV1ConsumedCapacities(output.ConsumedCapacity),
+	}
+	for tableName, requests := range output.UnprocessedItems {
+		converted.UnprocessedItems[tableName] = toV1WriteRequests(requests)
+	}
+	return converted, nil
+}
+
+func toV2WriteRequests(requests []*dynamodbv1.WriteRequest) []types.WriteRequest {
+	converted := make([]types.WriteRequest, 0, len(requests))
+	for _, writeRequest := range requests {
+		var convertedRequest types.WriteRequest
+		if writeRequest.PutRequest != nil {
+			convertedRequest.PutRequest = &types.PutRequest{
+				Item: toV2Item(writeRequest.PutRequest.Item),
+			}
+		}
+		if writeRequest.DeleteRequest != nil {
+			convertedRequest.DeleteRequest = &types.DeleteRequest{
+				Key: toV2Item(writeRequest.DeleteRequest.Key),
+			}
+		}
+		converted = append(converted, convertedRequest)
+	}
+	return converted
+}
+
+func toV1WriteRequests(requests []types.WriteRequest) []*dynamodbv1.WriteRequest {
+	converted := make([]*dynamodbv1.WriteRequest, 0, len(requests))
+	for _, writeRequest := range requests {
+		convertedRequest := &dynamodbv1.WriteRequest{}
+		if writeRequest.PutRequest != nil {
+			convertedRequest.PutRequest = &dynamodbv1.PutRequest{
+				Item: toV1Item(writeRequest.PutRequest.Item),
+			}
+		}
+		if writeRequest.DeleteRequest != nil {
+			convertedRequest.DeleteRequest = &dynamodbv1.DeleteRequest{
+				Key: toV1Item(writeRequest.DeleteRequest.Key),
+			}
+		}
+		converted = append(converted, convertedRequest)
+	}
+	return converted
+}
+
+func (svc *service) TransactGetItemsWithContext(ctx awsv1.Context,
+	input *dynamodbv1.TransactGetItemsInput,
+	_ ...request.Option) (*dynamodbv1.TransactGetItemsOutput, error) {
+
+	transactItems := make([]types.TransactGetItem, 0, len(input.TransactItems))
+	for _, item := range input.TransactItems {
+		var convertedItem types.TransactGetItem
+		if item.Get != nil {
+			convertedItem.Get = &types.Get{
+				TableName:                item.Get.TableName,
+				Key:                      toV2Item(item.Get.Key),
+				ProjectionExpression:     item.Get.ProjectionExpression,
+				ExpressionAttributeNames: toV2Names(item.Get.ExpressionAttributeNames),
+			}
+		}
+		transactItems = append(transactItems, convertedItem)
+	}
+
+	output, err := svc.api.TransactGetItems(ctx, &dynamodb.TransactGetItemsInput{
+		TransactItems: transactItems,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+
+	converted := &dynamodbv1.TransactGetItemsOutput{
+		ConsumedCapacity: toV1ConsumedCapacities(output.ConsumedCapacity),
+	}
+	for _, response := range output.Responses {
+		converted.Responses = append(converted.Responses,
+			&dynamodbv1.ItemResponse{Item: toV1Item(response.Item)})
+	}
+	return converted, nil
+}
+
+func (svc *service) TransactWriteItemsWithContext(ctx awsv1.Context,
+	input *dynamodbv1.TransactWriteItemsInput,
+	_ ...request.Option) (*dynamodbv1.TransactWriteItemsOutput, error) {
+
+	transactItems := make([]types.TransactWriteItem, 0, len(input.TransactItems))
+	for _, item := range input.TransactItems {
+		transactItems = append(transactItems, toV2TransactWriteItem(item))
+	}
+
+	output, err := svc.api.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems:      transactItems,
+		ClientRequestToken: input.ClientRequestToken,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacity(
+			awsv1.StringValue(input.ReturnConsumedCapacity)),
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.TransactWriteItemsOutput{
+		ConsumedCapacity: toV1ConsumedCapacities(output.ConsumedCapacity),
+	}, nil
+}
+
+func toV2TransactWriteItem(item *dynamodbv1.TransactWriteItem) types.TransactWriteItem {
+	var converted types.TransactWriteItem
+	if put := item.Put; put != nil {
+		converted.Put = &types.Put{
+			TableName:                 put.TableName,
+			Item:                      toV2Item(put.Item),
+			ConditionExpression:       put.ConditionExpression,
+			ExpressionAttributeNames:  toV2Names(put.ExpressionAttributeNames),
+			ExpressionAttributeValues: toV2Item(put.ExpressionAttributeValues),
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailure(
+				awsv1.StringValue(put.ReturnValuesOnConditionCheckFailure)),
+		}
+	}
+	if update := item.Update; update != nil {
+		converted.Update = &types.Update{
+			TableName:                 update.TableName,
+			Key:                       toV2Item(update.Key),
+			UpdateExpression:          update.UpdateExpression,
+			ConditionExpression:       update.ConditionExpression,
+			ExpressionAttributeNames:  toV2Names(update.ExpressionAttributeNames),
+			ExpressionAttributeValues: toV2Item(update.ExpressionAttributeValues),
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailure(
+				awsv1.StringValue(update.ReturnValuesOnConditionCheckFailure)),
+		}
+	}
+	if del := item.Delete; del != nil {
+		converted.Delete = &types.Delete{
+			TableName:                 del.TableName,
+			Key:                       toV2Item(del.Key),
+			ConditionExpression:       del.ConditionExpression,
+			ExpressionAttributeNames:  toV2Names(del.ExpressionAttributeNames),
+			ExpressionAttributeValues: toV2Item(del.ExpressionAttributeValues),
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailure(
+				awsv1.StringValue(del.ReturnValuesOnConditionCheckFailure)),
+		}
+	}
+	if check := item.ConditionCheck; check != nil {
+		converted.ConditionCheck = &types.ConditionCheck{
+			TableName:                 check.TableName,
+			Key:                       toV2Item(check.Key),
+			ConditionExpression:       check.ConditionExpression,
+			ExpressionAttributeNames:  toV2Names(check.ExpressionAttributeNames),
+			ExpressionAttributeValues: toV2Item(check.ExpressionAttributeValues),
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailure(
+				awsv1.StringValue(check.ReturnValuesOnConditionCheckFailure)),
+		}
+	}
+	return converted
+}
+
+func (svc *service) CreateTableWithContext(ctx awsv1.Context,
+	input *dynamodbv1.CreateTableInput,
+	_ ...request.Option) (*dynamodbv1.CreateTableOutput, error) {
+
+	convertedInput := &dynamodb.CreateTableInput{
+		TableName:             input.TableName,
+		AttributeDefinitions:  toV2AttributeDefinitions(input.AttributeDefinitions),
+		KeySchema:             toV2KeySchema(input.KeySchema),
+		BillingMode:           types.BillingMode(awsv1.StringValue(input.BillingMode)),
+		ProvisionedThroughput: toV2ProvisionedThroughput(input.ProvisionedThroughput),
+	}
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		convertedInput.GlobalSecondaryIndexes = append(convertedInput.GlobalSecondaryIndexes,
+			types.GlobalSecondaryIndex{
+				IndexName:             gsi.IndexName,
+				KeySchema:             toV2KeySchema(gsi.KeySchema),
+				Projection:            toV2Projection(gsi.Projection),
+				ProvisionedThroughput: toV2ProvisionedThroughput(gsi.ProvisionedThroughput),
+			})
+	}
+	for _, lsi := range input.LocalSecondaryIndexes {
+		convertedInput.LocalSecondaryIndexes = append(convertedInput.LocalSecondaryIndexes,
+			types.LocalSecondaryIndex{
+				IndexName:  lsi.IndexName,
+				KeySchema:  toV2KeySchema(lsi.KeySchema),
+				Projection: toV2Projection(lsi.Projection),
+			})
+	}
+
+	output, err := svc.api.CreateTable(ctx, convertedInput)
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.CreateTableOutput{
+		TableDescription: toV1TableDescription(output.TableDescription),
+	}, nil
+}
+
+func (svc *service) UpdateTableWithContext(ctx awsv1.Context,
+	input *dynamodbv1.UpdateTableInput,
+	_ ...request.Option) (*dynamodbv1.UpdateTableOutput, error) {
+
+	convertedInput := &dynamodb.UpdateTableInput{
+		TableName:             input.TableName,
+		AttributeDefinitions:  toV2AttributeDefinitions(input.AttributeDefinitions),
+		BillingMode:           types.BillingMode(awsv1.StringValue(input.BillingMode)),
+		ProvisionedThroughput: toV2ProvisionedThroughput(input.ProvisionedThroughput),
+	}
+	for _, update := range input.GlobalSecondaryIndexUpdates {
+		var convertedUpdate types.GlobalSecondaryIndexUpdate
+		if create := update.Create; create != nil {
+			convertedUpdate.Create = &types.CreateGlobalSecondaryIndexAction{
+				IndexName:             create.IndexName,
+				KeySchema:             toV2KeySchema(create.KeySchema),
+				Projection:            toV2Projection(create.Projection),
+				ProvisionedThroughput: toV2ProvisionedThroughput(create.ProvisionedThroughput),
+			}
+		}
+		if indexUpdate := update.Update; indexUpdate != nil {
+			convertedUpdate.Update = &types.UpdateGlobalSecondaryIndexAction{
+				IndexName: indexUpdate.IndexName,
+				ProvisionedThroughput: toV2ProvisionedThroughput(
+					indexUpdate.ProvisionedThroughput),
+			}
+		}
+		if del := update.Delete; del != nil {
+			convertedUpdate.Delete = &types.DeleteGlobalSecondaryIndexAction{
+				IndexName: del.IndexName,
+			}
+		}
+		convertedInput.GlobalSecondaryIndexUpdates = append(
+			convertedInput.GlobalSecondaryIndexUpdates, convertedUpdate)
+	}
+
+	output, err := svc.api.UpdateTable(ctx, convertedInput)
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+	return &dynamodbv1.UpdateTableOutput{
+		TableDescription: toV1TableDescription(output.TableDescription),
+	}, nil
+}
+
+func (svc *service) DescribeTimeToLiveWithContext(ctx awsv1.Context,
+	input *dynamodbv1.DescribeTimeToLiveInput,
+	_ ...request.Option) (*dynamodbv1.DescribeTimeToLiveOutput, error) {
+
+	output, err := svc.api.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: input.TableName,
+	})
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+
+	converted := &dynamodbv1.DescribeTimeToLiveOutput{}
+	if description := output.TimeToLiveDescription; description != nil {
+		converted.TimeToLiveDescription = &dynamodbv1.TimeToLiveDescription{
+			AttributeName:    description.AttributeName,
+			TimeToLiveStatus: awsv1.String(string(description.TimeToLiveStatus)),
+		}
+	}
+	return converted, nil
+}
+
+func (svc *service) UpdateTimeToLiveWithContext(ctx awsv1.Context,
+	input *dynamodbv1.UpdateTimeToLiveInput,
+	_ ...request.Option) (*dynamodbv1.UpdateTimeToLiveOutput, error) {
+
+	convertedInput := &dynamodb.UpdateTimeToLiveInput{TableName: input.TableName}
+	if specification := input.TimeToLiveSpecification; specification != nil {
+		convertedInput.TimeToLiveSpecification = &types.TimeToLiveSpecification{
+			AttributeName: specification.AttributeName,
+			Enabled:       specification.Enabled,
+		}
+	}
+
+	output, err := svc.api.UpdateTimeToLive(ctx, convertedInput)
+	if err != nil {
+		return nil, toV1Error(err)
+	}
+
+	converted := &dynamodbv1.UpdateTimeToLiveOutput{}
+	if specification := output.TimeToLiveSpecification; specification != nil {
+		converted.TimeToLiveSpecification = &dynamodbv1.TimeToLiveSpecification{
+			AttributeName: specification.AttributeName,
+			Enabled:       specification.Enabled,
+		}
+	}
+	return converted, nil
+}
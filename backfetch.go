@@ -0,0 +1,130 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// backFetchScoreMultiplier is applied to the score of indexes which require back-fetching
+// attributes from the table. Back-fetching costs at least one additional read per returned item.
+const backFetchScoreMultiplier = 0.4
+
+// SetAllowBackFetch sets whether indexes which do not project all needed attributes may be
+// selected for the query. If allowed, such an index is considered viable and the parser fetches
+// the missing attributes of each returned item from the table using BatchGetItem, preserving the
+// order of items returned by the index. Items which are deleted from the table before they are
+// fetched are skipped. Filters are evaluated on the index, so the index must still project every
+// attribute the expression filters on.
+//
+// Indexes which require back-fetching are scored lower to account for the additional read cost,
+// so they are only selected over fully projected indexes when they are much more selective.
+//
+// By default, back-fetching is not allowed.
+func (parser *Parser) SetAllowBackFetch(allow bool) *Parser {
+	parser.options.allowBackFetch = allow
+	return parser
+}
+
+// backFetchSpec describes how missing attributes are fetched from the table for query items.
+type backFetchSpec struct {
+	tableKeys         []string
+	selectedKeys      []string
+	keysAndAttributes *dynamodb.KeysAndAttributes
+}
+
+func newBackFetchSpec(tableIndex *tableIndex, expr *Expression) (*backFetchSpec, error) {
+	spec := &backFetchSpec{
+		tableKeys:         tableIndex.getKeys(),
+		keysAndAttributes: &dynamodb.KeysAndAttributes{},
+	}
+
+	if expr.consistentRead {
+		spec.keysAndAttributes.ConsistentRead = aws.Bool(true)
+	}
+
+	if expr.attributesSpecified {
+		// table keys are always fetched in order to match fetched items to query items
+		attributes := uniqueStrings(expr.attributes, spec.tableKeys)
+		names := []expression.NameBuilder{}
+		for _, attribute := range attributes {
+			names = append(names, expression.Name(attribute))
+		}
+		projection := expression.NamesList(names[0], names[1:]...)
+		dynamodbExpr, err := expression.NewBuilder().WithProjection(projection).Build()
+		if err != nil {
+			return nil, err
+		}
+		spec.keysAndAttributes.ProjectionExpression = dynamodbExpr.Projection()
+		spec.keysAndAttributes.ExpressionAttributeNames = dynamodbExpr.Names()
+		spec.selectedKeys = expr.attributes
+	}
+
+	return spec, nil
+}
+
+// backFetchResult contains items fetched from the table in the order of the query items.
+type backFetchResult struct {
+	items            []map[string]*dynamodb.AttributeValue
+	consumedCapacity []*dynamodb.ConsumedCapacity
+}
+
+func (fetcher *pageFetcher) backFetchItems(ctx context.Context,
+	queryItems []map[string]*dynamodb.AttributeValue,
+	returnConsumedCapacity *string) (*backFetchResult, error) {
+
+	spec := fetcher.backFetch
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(queryItems))
+	for _, queryItem := range queryItems {
+		key := map[string]*dynamodb.AttributeValue{}
+		for _, keyAttr := range spec.tableKeys {
+			key[keyAttr] = queryItem[keyAttr]
+		}
+		keys = append(keys, key)
+	}
+
+	// back-fetched items are read from the table, so the table's rate limit applies
+	limiter := fetcher.backFetchLimiter
+	if limiter != nil {
+		if err := limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	fetchedItems, consumedCapacity, err := fetcher.client.batchGetTableItems(ctx,
+		fetcher.tableName, keys, spec.tableKeys, spec.keysAndAttributes, returnConsumedCapacity,
+		fetcher.retryPolicy)
+	if limiter != nil {
+		for _, capacity := range consumedCapacity {
+			limiter.debit(aws.Float64Value(capacity.CapacityUnits))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &backFetchResult{
+		items:            make([]map[string]*dynamodb.AttributeValue, 0, len(keys)),
+		consumedCapacity: consumedCapacity,
+	}
+	for _, key := range keys {
+		item, found := fetchedItems[keyID(key, spec.tableKeys)]
+		if !found {
+			// item was deleted after it was queried
+			continue
+		}
+		// remove table keys which were not selected
+		if spec.selectedKeys != nil {
+			for _, keyAttr := range spec.tableKeys {
+				if !containsString(spec.selectedKeys, keyAttr) {
+					delete(item, keyAttr)
+				}
+			}
+		}
+		result.items = append(result.items, item)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,414 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// maxBatchGetKeys is the maximum number of keys in a single BatchGetItem call.
+	maxBatchGetKeys = 100
+
+	// maxBatchWriteRequests is the maximum number of requests in a single BatchWriteItem call.
+	maxBatchWriteRequests = 25
+)
+
+// BatchGet retrieves multiple items by their keys using BatchGetItem. The keys are specified in
+// itemKeys, which should be a slice of structs with the appropriate dynamodbav attribute tags
+// pertaining to the table's primary key. The items are returned in returnItems, which should be a
+// pointer to a slice of items with dynamodbav attribute tags pertaining to the desired return
+// attributes in the table.
+//
+// The returned items are in the same order as itemKeys, and the returned slice of found flags
+// reports whether the item for each key was found. Items which are not found are left as zero
+// values in returnItems.
+//
+// Keys are requested in chunks of up to 100 keys, with up to BatchConcurrency chunks requested
+// concurrently. Unprocessed keys are retried with exponential backoff according to the client's
+// retry policy, or the default retry policy if the client's retry policy is nil. If keys remain
+// unprocessed after max attempts, an *ErrBatchUnprocessed error is returned.
+func (client *Client) BatchGet(ctx context.Context, tableName string,
+	itemKeys, returnItems interface{}) ([]bool, error) {
+
+	keys, err := marshalList(client.Encoder, itemKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	returnValue := reflect.ValueOf(returnItems)
+	if returnValue.Kind() != reflect.Ptr || returnValue.IsNil() ||
+		returnValue.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cannot return items into %T, must be a pointer to a slice",
+			returnItems)
+	}
+
+	keyAttributes := []string{}
+	if len(keys) > 0 {
+		for attr := range keys[0] {
+			keyAttributes = append(keyAttributes, attr)
+		}
+		sort.Strings(keyAttributes)
+	}
+
+	// duplicate keys are not allowed in a single BatchGetItem call
+	uniqueKeys := []map[string]*dynamodb.AttributeValue{}
+	uniqueKeyIDs := map[string]struct{}{}
+	for _, key := range keys {
+		id := keyID(key, keyAttributes)
+		if _, found := uniqueKeyIDs[id]; !found {
+			uniqueKeyIDs[id] = struct{}{}
+			uniqueKeys = append(uniqueKeys, key)
+		}
+	}
+
+	fetchedItems, _, err := client.batchGetTableItems(ctx, tableName, uniqueKeys,
+		keyAttributes, &dynamodb.KeysAndAttributes{}, nil, client.RetryPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]bool, len(keys))
+	slice := reflect.MakeSlice(returnValue.Elem().Type(), len(keys), len(keys))
+	for i, key := range keys {
+		item, itemFound := fetchedItems[keyID(key, keyAttributes)]
+		if !itemFound {
+			continue
+		}
+		found[i] = true
+		err := client.decodeTableItem(tableName, client.Decoder, item,
+			slice.Index(i).Addr().Interface())
+		if err != nil {
+			return nil, err
+		}
+	}
+	returnValue.Elem().Set(slice)
+
+	return found, nil
+}
+
+// BatchPut inserts or replaces multiple items using BatchWriteItem. The items should be a slice of
+// structs with the appropriate dynamodbav attribute tags.
+//
+// Items are written in chunks of up to 25 items, with up to BatchConcurrency chunks written
+// concurrently. Unprocessed items are retried in the same way as BatchGet. If multiple items have
+// the same key, only the last of them is written, since DynamoDB does not allow duplicate keys in
+// a single call. The table's key attributes are found using the client's metadata provider.
+func (client *Client) BatchPut(ctx context.Context, tableName string, items interface{}) error {
+	tableItems, err := marshalList(client.Encoder, items)
+	if err != nil {
+		return err
+	}
+
+	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	tableItems = lastItemsByKey(tableItems, indexMetadata.primaryIndex().getKeys())
+
+	requests := make([]*dynamodb.WriteRequest, 0, len(tableItems))
+	for _, tableItem := range tableItems {
+		requests = append(requests, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{Item: tableItem},
+		})
+	}
+
+	return client.batchWriteTableItems(ctx, tableName, requests)
+}
+
+// BatchDelete deletes multiple items by their keys using BatchWriteItem. The keys should be a
+// slice of structs with the appropriate dynamodbav attribute tags pertaining to the table's
+// primary key.
+//
+// Keys are deleted in chunks of up to 25 keys, with up to BatchConcurrency chunks deleted
+// concurrently. Unprocessed keys are retried in the same way as BatchGet. Duplicate keys are
+// deleted once.
+func (client *Client) BatchDelete(
+	ctx context.Context, tableName string, itemKeys interface{}) error {
+
+	keys, err := marshalList(client.Encoder, itemKeys)
+	if err != nil {
+		return err
+	}
+
+	keyAttributes := []string{}
+	if len(keys) > 0 {
+		for attr := range keys[0] {
+			keyAttributes = append(keyAttributes, attr)
+		}
+		sort.Strings(keyAttributes)
+	}
+	keys = lastItemsByKey(keys, keyAttributes)
+
+	requests := make([]*dynamodb.WriteRequest, 0, len(keys))
+	for _, key := range keys {
+		requests = append(requests, &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{Key: key},
+		})
+	}
+
+	return client.batchWriteTableItems(ctx, tableName, requests)
+}
+
+// batchGetTableItems gets items by key from a table using BatchGetItem. Keys are requested in
+// chunks of up to 100 keys, and unprocessed keys are retried with exponential backoff according
+// to the retry policy, or the default retry policy if nil. The fetched items are returned keyed
+// by keyID.
+func (client *Client) batchGetTableItems(ctx context.Context, tableName string,
+	keys []map[string]*dynamodb.AttributeValue, keyAttributes []string,
+	keysAndAttributes *dynamodb.KeysAndAttributes, returnConsumedCapacity *string,
+	policy *RetryPolicy) (map[string]map[string]*dynamodb.AttributeValue,
+	[]*dynamodb.ConsumedCapacity, error) {
+
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+
+	var mutex sync.Mutex
+	items := map[string]map[string]*dynamodb.AttributeValue{}
+	consumedCapacity := []*dynamodb.ConsumedCapacity{}
+
+	err := client.forEachChunk(ctx, len(keys), maxBatchGetKeys,
+		func(ctx context.Context, start, end int) error {
+			requestKeys := *keysAndAttributes
+			requestKeys.Keys = keys[start:end]
+			requestItems := map[string]*dynamodb.KeysAndAttributes{tableName: &requestKeys}
+
+			for attempt := 0; len(requestItems) > 0; attempt++ {
+				if attempt > 0 {
+					unprocessedCount := len(requestItems[tableName].Keys)
+					if err := waitForBatchRetry(ctx, policy, attempt, unprocessedCount); err != nil {
+						return err
+					}
+				}
+
+				output, err := client.dynamodbService.BatchGetItemWithContext(ctx,
+					&dynamodb.BatchGetItemInput{
+						RequestItems:           requestItems,
+						ReturnConsumedCapacity: returnConsumedCapacity,
+					})
+				if err != nil {
+					return err
+				}
+
+				mutex.Lock()
+				for _, item := range output.Responses[tableName] {
+					items[keyID(item, keyAttributes)] = item
+				}
+				consumedCapacity = append(consumedCapacity, output.ConsumedCapacity...)
+				mutex.Unlock()
+
+				requestItems = output.UnprocessedKeys
+			}
+
+			return nil
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return items, consumedCapacity, nil
+}
+
+// batchWriteTableItems writes requests to a table using BatchWriteItem. Requests are written in
+// chunks of up to 25 requests, and unprocessed requests are retried with exponential backoff
+// according to the client's retry policy, or the default retry policy if nil.
+func (client *Client) batchWriteTableItems(
+	ctx context.Context, tableName string, requests []*dynamodb.WriteRequest) error {
+
+	policy := client.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+
+	writtenItems := make([]map[string]*dynamodb.AttributeValue, 0, len(requests))
+	for _, request := range requests {
+		if request.PutRequest != nil {
+			writtenItems = append(writtenItems, request.PutRequest.Item)
+		} else if request.DeleteRequest != nil {
+			writtenItems = append(writtenItems, request.DeleteRequest.Key)
+		}
+	}
+	cacheKeys, err := client.itemCacheKeys(ctx, tableName, writtenItems...)
+	if err != nil {
+		return err
+	}
+	defer client.invalidateCachedItems(cacheKeys)
+
+	return client.forEachChunk(ctx, len(requests), maxBatchWriteRequests,
+		func(ctx context.Context, start, end int) error {
+			requestItems := map[string][]*dynamodb.WriteRequest{tableName: requests[start:end]}
+
+			for attempt := 0; len(requestItems) > 0; attempt++ {
+				if attempt > 0 {
+					unprocessedCount := len(requestItems[tableName])
+					if err := waitForBatchRetry(ctx, policy, attempt, unprocessedCount); err != nil {
+						return err
+					}
+				}
+
+				output, err := client.dynamodbService.BatchWriteItemWithContext(ctx,
+					&dynamodb.BatchWriteItemInput{
+						RequestItems: requestItems,
+					})
+				if err != nil {
+					return err
+				}
+
+				requestItems = output.UnprocessedItems
+			}
+
+			return nil
+		})
+}
+
+// forEachChunk calls fn for each chunk of items, with up to BatchConcurrency calls running
+// concurrently. If any call returns an error, the context passed to remaining calls is canceled
+// and the first error is returned.
+func (client *Client) forEachChunk(ctx context.Context, numItems, chunkSize int,
+	fn func(ctx context.Context, start, end int) error) error {
+
+	concurrency := client.BatchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	semaphore := make(chan struct{}, concurrency)
+
+	for start := 0; start < numItems && ctx.Err() == nil; start += chunkSize {
+		end := start + chunkSize
+		if end > numItems {
+			end = numItems
+		}
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := fn(ctx, start, end); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(start, end)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// waitForBatchRetry waits before retrying unprocessed batch requests, or returns an
+// *ErrBatchUnprocessed error if max attempts have been made.
+func waitForBatchRetry(
+	ctx context.Context, policy *RetryPolicy, attempt, unprocessedCount int) error {
+
+	if attempt >= policy.MaxAttempts {
+		return &ErrBatchUnprocessed{Attempts: attempt, UnprocessedCount: unprocessedCount}
+	}
+
+	timer := time.NewTimer(policy.delay(attempt - 1))
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// marshalList marshals each element of a slice into an item using encoder.
+func marshalList(
+	encoder Encoder, list interface{}) ([]map[string]*dynamodb.AttributeValue, error) {
+
+	listValue := reflect.ValueOf(list)
+	if listValue.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cannot marshal %T, must be a slice", list)
+	}
+
+	items := make([]map[string]*dynamodb.AttributeValue, 0, listValue.Len())
+	for i := 0; i < listValue.Len(); i++ {
+		item, err := encodeItem(encoder, listValue.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// keyID returns a string which uniquely identifies an item by its key attributes. Numbers are
+// normalized, since DynamoDB may return a number in a different form than it was written, such as
+// 1 for 1.0.
+func keyID(item map[string]*dynamodb.AttributeValue, keyAttributes []string) string {
+	idParts := make([]string, 0, len(keyAttributes))
+	for _, keyAttr := range keyAttributes {
+		av := item[keyAttr]
+		switch {
+		case av == nil:
+			idParts = append(idParts, "")
+		case av.N != nil:
+			idParts = append(idParts, "N:"+normalizeNumber(*av.N))
+		default:
+			idParts = append(idParts, av.String())
+		}
+	}
+	return strings.Join(idParts, "\x00")
+}
+
+// lastItemsByKey returns items without duplicate keys, keeping the last item for each key in its
+// position.
+func lastItemsByKey(items []map[string]*dynamodb.AttributeValue,
+	keyAttributes []string) []map[string]*dynamodb.AttributeValue {
+
+	lastIndexes := make(map[string]int, len(items))
+	for i, item := range items {
+		lastIndexes[keyID(item, keyAttributes)] = i
+	}
+	if len(lastIndexes) == len(items) {
+		return items
+	}
+
+	unique := make([]map[string]*dynamodb.AttributeValue, 0, len(lastIndexes))
+	for i, item := range items {
+		if lastIndexes[keyID(item, keyAttributes)] == i {
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
+
+// normalizeNumber returns the canonical form of a DynamoDB number, or the number unchanged if it
+// cannot be parsed.
+func normalizeNumber(n string) string {
+	r, ok := new(big.Rat).SetString(n)
+	if !ok {
+		return n
+	}
+	return r.RatString()
+}
@@ -0,0 +1,69 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func TestKeyIDNormalizesNumbers(t *testing.T) {
+	keyAttributes := []string{"pk", "sk"}
+	key := func(sk string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			"pk": {S: aws.String("p")},
+			"sk": {N: aws.String(sk)},
+		}
+	}
+
+	for _, equal := range [][2]string{{"1", "1.0"}, {"1.5", "1.50"}, {"100", "1e2"}, {"0", "-0"}} {
+		if keyID(key(equal[0]), keyAttributes) != keyID(key(equal[1]), keyAttributes) {
+			t.Errorf("expected %s and %s to have equal key IDs", equal[0], equal[1])
+		}
+	}
+	if keyID(key("1"), keyAttributes) == keyID(key("1.01"), keyAttributes) {
+		t.Error("expected 1 and 1.01 to have different key IDs")
+	}
+}
+
+func TestBatchGetMatchesNormalizedNumbers(t *testing.T) {
+	client := NewClient(newFakeService(5, 5))
+
+	type itemKey struct {
+		PK string                   `dynamodbav:"pk"`
+		SK dynamodbattribute.Number `dynamodbav:"sk"`
+	}
+	keys := []itemKey{{"p", "1.0"}, {"p", "3.00"}, {"p", "7"}}
+
+	items := []struct {
+		Value string `dynamodbav:"value"`
+	}{}
+	found, err := client.BatchGet(context.Background(), fakeTableName, keys, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found[0] || !found[1] || found[2] {
+		t.Fatalf("expected found to be [true true false], found %v", found)
+	}
+	if items[0].Value != "v1" || items[1].Value != "v3" {
+		t.Errorf("expected values v1 and v3, found %s and %s", items[0].Value, items[1].Value)
+	}
+}
+
+func TestLastItemsByKey(t *testing.T) {
+	items := []map[string]*dynamodb.AttributeValue{
+		fakeItem(1, "a"), fakeItem(2, "b"), fakeItem(1, "c"), fakeItem(3, "d"),
+	}
+	items[2]["sk"] = &dynamodb.AttributeValue{N: aws.String("1.0")}
+
+	unique := lastItemsByKey(items, []string{"pk", "sk"})
+	values := []string{}
+	for _, item := range unique {
+		values = append(values, aws.StringValue(item["value"].S))
+	}
+	if len(values) != 3 || values[0] != "b" || values[1] != "c" || values[2] != "d" {
+		t.Errorf("expected values [b c d], found %v", values)
+	}
+}
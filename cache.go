@@ -0,0 +1,301 @@
+package autoquery
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ResultCache is a backend for the client's read-through result cache. Cached values are opaque
+// serialized results, so backends may store them outside of the process. Implementations must be
+// safe for concurrent use.
+type ResultCache interface {
+	// Get returns the value stored for key, or false if no value is stored or it has expired.
+	Get(key string) ([]byte, bool)
+
+	// Set stores value for key.
+	Set(key string, value []byte)
+
+	// Delete removes the value stored for key, if any.
+	Delete(key string)
+}
+
+// LRUCache is an in-memory ResultCache which evicts the least recently used entries when full and
+// expires entries after a time to live.
+type LRUCache struct {
+	mutex    sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUCache creates an in-memory cache which holds up to capacity entries, each of which
+// expires ttl after it is set. If ttl is 0 or less, entries do not expire.
+func NewLRUCache(capacity int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get returns the value stored for key, or false if no value is stored or it has expired.
+func (cache *LRUCache) Get(key string) ([]byte, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, found := cache.entries[key]
+	if !found {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if cache.ttl > 0 && time.Now().After(entry.expiresAt) {
+		cache.remove(element)
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores value for key, evicting the least recently used entry if the cache is full.
+func (cache *LRUCache) Set(key string, value []byte) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry := &lruEntry{key: key, value: value, expiresAt: time.Now().Add(cache.ttl)}
+	if element, found := cache.entries[key]; found {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(entry)
+	for cache.capacity > 0 && cache.order.Len() > cache.capacity {
+		cache.remove(cache.order.Back())
+	}
+}
+
+// Delete removes the value stored for key, if any.
+func (cache *LRUCache) Delete(key string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, found := cache.entries[key]; found {
+		cache.remove(element)
+	}
+}
+
+// Len returns the number of entries in the cache, including expired entries which have not yet
+// been removed.
+func (cache *LRUCache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.order.Len()
+}
+
+func (cache *LRUCache) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*lruEntry).key)
+}
+
+// cachedItem is a cached Get result. Items which were not found are cached with Found false.
+type cachedItem struct {
+	Item  map[string]*dynamodb.AttributeValue
+	Found bool
+}
+
+// cachedQueryOutput is a cached query page. Consumed capacity is not cached.
+type cachedQueryOutput struct {
+	Items            []map[string]*dynamodb.AttributeValue
+	Count            *int64
+	ScannedCount     *int64
+	LastEvaluatedKey map[string]*dynamodb.AttributeValue
+}
+
+// itemCacheKey returns the cache key of a Get result. Item keys are encoded as JSON, which sorts
+// attribute names, with numbers normalized so that equal keys have equal cache keys.
+func itemCacheKey(tableName string, key map[string]*dynamodb.AttributeValue) (string, error) {
+	normalizedKey := make(map[string]*dynamodb.AttributeValue, len(key))
+	for attribute, value := range key {
+		if value != nil && value.N != nil {
+			value = &dynamodb.AttributeValue{N: aws.String(normalizeNumber(*value.N))}
+		}
+		normalizedKey[attribute] = value
+	}
+	encodedKey, err := json.Marshal(normalizedKey)
+	if err != nil {
+		return "", err
+	}
+	return "get:" + tableName + ":" + string(encodedKey), nil
+}
+
+// queryCacheKey returns the cache key of a query page. The key includes the table, index and all
+// other query parameters except for the consumed capacity to return.
+func queryCacheKey(queryInput *dynamodb.QueryInput) (string, error) {
+	normalizedInput := *queryInput
+	normalizedInput.ReturnConsumedCapacity = nil
+	encodedInput, err := json.Marshal(&normalizedInput)
+	if err != nil {
+		return "", err
+	}
+	return "query:" + string(encodedInput), nil
+}
+
+// getCachedItem returns a cached Get result, or false if the result is not cached.
+func (client *Client) getCachedItem(cacheKey string) (*cachedItem, bool) {
+	value, found := client.Cache.Get(cacheKey)
+	if !found {
+		return nil, false
+	}
+	cached := &cachedItem{}
+	if err := json.Unmarshal(value, cached); err != nil {
+		return nil, false
+	}
+	return cached, true
+}
+
+// itemRead is a Get result being read from DynamoDB. Its version is incremented whenever the item
+// is invalidated by a write, so that a read which races with a write does not cache the item from
+// before the write.
+type itemRead struct {
+	cacheKey string
+	version  uint64
+	readers  int
+}
+
+// beginItemRead registers a read of a Get result from DynamoDB. endItemRead must be called once
+// the read is complete.
+func (client *Client) beginItemRead(cacheKey string) *itemRead {
+	client.itemReadsMutex.Lock()
+	defer client.itemReadsMutex.Unlock()
+
+	if client.itemReads == nil {
+		client.itemReads = map[string]*itemRead{}
+	}
+	current, found := client.itemReads[cacheKey]
+	if !found {
+		current = &itemRead{cacheKey: cacheKey}
+		client.itemReads[cacheKey] = current
+	}
+	current.readers++
+	return &itemRead{cacheKey: cacheKey, version: current.version}
+}
+
+func (client *Client) endItemRead(read *itemRead) {
+	client.itemReadsMutex.Lock()
+	defer client.itemReadsMutex.Unlock()
+
+	current := client.itemReads[read.cacheKey]
+	if current.readers--; current.readers == 0 {
+		delete(client.itemReads, read.cacheKey)
+	}
+}
+
+// setCachedItem caches the result of a read, unless the item has been invalidated since the read
+// began.
+func (client *Client) setCachedItem(read *itemRead, item map[string]*dynamodb.AttributeValue) {
+	value, err := json.Marshal(&cachedItem{Item: item, Found: item != nil})
+	if err != nil {
+		return
+	}
+
+	client.itemReadsMutex.Lock()
+	defer client.itemReadsMutex.Unlock()
+
+	if client.itemReads[read.cacheKey].version == read.version {
+		client.Cache.Set(read.cacheKey, value)
+	}
+}
+
+// getCachedQueryOutput returns a cached query page, or false if the page is not cached.
+func (client *Client) getCachedQueryOutput(cacheKey string) (*dynamodb.QueryOutput, bool) {
+	value, found := client.Cache.Get(cacheKey)
+	if !found {
+		return nil, false
+	}
+	cached := &cachedQueryOutput{}
+	if err := json.Unmarshal(value, cached); err != nil {
+		return nil, false
+	}
+	return &dynamodb.QueryOutput{
+		Items:            cached.Items,
+		Count:            cached.Count,
+		ScannedCount:     cached.ScannedCount,
+		LastEvaluatedKey: cached.LastEvaluatedKey,
+	}, true
+}
+
+func (client *Client) setCachedQueryOutput(cacheKey string, output *dynamodb.QueryOutput) {
+	value, err := json.Marshal(&cachedQueryOutput{
+		Items:            output.Items,
+		Count:            output.Count,
+		ScannedCount:     output.ScannedCount,
+		LastEvaluatedKey: output.LastEvaluatedKey,
+	})
+	if err == nil {
+		client.Cache.Set(cacheKey, value)
+	}
+}
+
+// itemCacheKeys returns the cache keys of Get results for items written to a table, which may be
+// full items or keys. If the client does not have a cache, no keys are returned.
+func (client *Client) itemCacheKeys(ctx context.Context, tableName string,
+	items ...map[string]*dynamodb.AttributeValue) ([]string, error) {
+
+	if client.Cache == nil {
+		return nil, nil
+	}
+
+	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	keyAttributes := indexMetadata.primaryIndex().getKeys()
+
+	cacheKeys := make([]string, 0, len(items))
+	for _, item := range items {
+		key := map[string]*dynamodb.AttributeValue{}
+		for _, attribute := range keyAttributes {
+			key[attribute] = item[attribute]
+		}
+		cacheKey, err := itemCacheKey(tableName, key)
+		if err != nil {
+			return nil, err
+		}
+		cacheKeys = append(cacheKeys, cacheKey)
+	}
+	return cacheKeys, nil
+}
+
+func (client *Client) invalidateCachedItems(cacheKeys []string) {
+	for _, cacheKey := range cacheKeys {
+		// reads in progress may have read the item before the write, so they are not cached
+		client.itemReadsMutex.Lock()
+		if current, found := client.itemReads[cacheKey]; found {
+			current.version++
+		}
+		client.itemReadsMutex.Unlock()
+
+		client.Cache.Delete(cacheKey)
+	}
+}
+
+// isCacheableQuery returns true if query pages may be served from the client's cache. Strongly
+// consistent queries are never served from the cache.
+func (client *Client) isCacheableQuery(queryInput *dynamodb.QueryInput) bool {
+	return client.Cache != nil && !aws.BoolValue(queryInput.ConsistentRead)
+}
@@ -0,0 +1,146 @@
+package autoquery
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+type testKey struct {
+	PK string `dynamodbav:"pk"`
+	SK int    `dynamodbav:"sk"`
+}
+
+type testItem struct {
+	PK    string `dynamodbav:"pk"`
+	SK    int    `dynamodbav:"sk"`
+	Value string `dynamodbav:"value"`
+}
+
+func TestLRUCacheConcurrentUse(t *testing.T) {
+	cache := NewLRUCache(50, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := strconv.Itoa((i*200 + j) % 100)
+				cache.Set(key, []byte(key))
+				if value, found := cache.Get(key); found && string(value) != key {
+					t.Errorf("expected value %s, found %s", key, value)
+				}
+				if j%10 == 0 {
+					cache.Delete(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if cache.Len() > 50 {
+		t.Errorf("expected at most 50 entries, found %d", cache.Len())
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewLRUCache(2, 0)
+	cache.Set("a", []byte("a"))
+	cache.Set("b", []byte("b"))
+	cache.Get("a")
+	cache.Set("c", []byte("c"))
+
+	if _, found := cache.Get("b"); found {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found := cache.Get(key); !found {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+}
+
+func TestCachedGetServesRepeatedReads(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(3, 1)
+	client := NewClient(svc)
+	client.Cache = NewLRUCache(10, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		item := testItem{}
+		if err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 1}, &item); err != nil {
+			t.Fatal(err)
+		}
+		if item.Value != "v1" {
+			t.Fatalf("expected v1, found %s", item.Value)
+		}
+	}
+	if _, getCalls, _ := svc.calls(); getCalls != 1 {
+		t.Errorf("expected 1 get call, found %d", getCalls)
+	}
+}
+
+func TestCachedGetRacingWriteIsNotCached(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(3, 1)
+	svc.block = make(chan struct{})
+	client := NewClient(svc)
+	client.Cache = NewLRUCache(10, time.Minute)
+	key := testKey{PK: "p", SK: 1}
+
+	// the item is read before the write, but the read completes after the write
+	readDone := make(chan error)
+	go func() {
+		readDone <- client.Get(ctx, fakeTableName, key, &testItem{})
+	}()
+	for _, getCalls, _ := svc.calls(); getCalls == 0; _, getCalls, _ = svc.calls() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 1, Value: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	close(svc.block)
+	if err := <-readDone; err != nil {
+		t.Fatal(err)
+	}
+
+	item := testItem{}
+	if err := client.Get(ctx, fakeTableName, key, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Value != "new" {
+		t.Errorf("expected the written value, found %s", item.Value)
+	}
+}
+
+func TestCachedGetInvalidatedByNormalizedKey(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(3, 1)
+	client := NewClient(svc)
+	client.Cache = NewLRUCache(10, time.Minute)
+
+	type numberKey struct {
+		PK string                   `dynamodbav:"pk"`
+		SK dynamodbattribute.Number `dynamodbav:"sk"`
+	}
+	if err := client.Get(ctx, fakeTableName, numberKey{"p", "1.0"}, &testItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 1, Value: "new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	item := testItem{}
+	if err := client.Get(ctx, fakeTableName, numberKey{"p", "1.0"}, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Value != "new" {
+		t.Errorf("expected the written value, found %s", item.Value)
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"math"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
@@ -20,8 +20,25 @@ type Client struct {
 
 	metadataProvider TableDescriptionProvider
 
+	interceptors []Interceptor
+
+	rateLimitersMutex sync.Mutex
+	rateLimiters      map[rateLimiterKey]*tokenBucket
+
+	tableIndexMetadataMutex sync.Mutex
 	tableIndexMetadataCache map[string]*tableIndexMetadata
 
+	itemTypesMutex sync.RWMutex
+	itemTypes      map[itemTypeKey]struct{}
+
+	entitiesMutex sync.RWMutex
+	entities      map[string][]*Entity
+
+	flights flightGroup
+
+	itemReadsMutex sync.Mutex
+	itemReads      map[string]*itemRead
+
 	// SecondaryIndexSparsenessThreshold sets the threshold for secondary indexes to be considered
 	// sparse vs non-sparse.
 	//
@@ -46,11 +63,72 @@ type Client struct {
 	// By default, all secondary indexes are considered sparse. If non-default behavior is
 	// desired, this value should be set before any queries are parsed with Parser.Next.
 	SecondaryIndexSparsenessThreshold float64
+
+	// RetryPolicy sets the retry policy for page query calls made by parsers created from the
+	// client. A parser's retry policy may be overridden with Parser.SetRetryPolicy.
+	//
+	// By default, RetryPolicy is nil and failed page query calls are not retried by the client.
+	RetryPolicy *RetryPolicy
+
+	// Decoder sets the decoder used to unmarshal items returned by Get and by parsers created from
+	// the client. A parser's decoder may be overridden with Parser.SetDecoder.
+	//
+	// Decoder only applies to whole items. Values grouped by an Aggregation are always
+	// unmarshaled with the default dynamodbattribute rules.
+	//
+	// By default, Decoder is nil and items are unmarshaled with "dynamodbav" struct tags.
+	Decoder Decoder
+
+	// Encoder sets the encoder used to marshal items and keys passed to Get, Put, Delete, Update,
+	// batch operations and transactions.
+	//
+	// Values in query conditions and in Update actions are also marshaled with Encoder, so that
+	// they match the attributes of encoded items. Conditions built with the expression package,
+	// such as those passed to WithCondition or Expression.Filter, are marshaled by the expression
+	// package.
+	//
+	// By default, Encoder is nil and items are marshaled with "dynamodbav" struct tags.
+	Encoder Encoder
+
+	// BatchConcurrency sets the maximum number of concurrent calls made by batch operations, such
+	// as BatchGet and BatchPut. By default, BatchConcurrency is 4.
+	BatchConcurrency int
+
+	// Cache sets a read-through cache for the results of Get and of page query calls made by
+	// parsers created from the client. Get results are keyed by table and item key, including
+	// items which were not found, and query pages are keyed by table, index and query parameters.
+	// Strongly consistent queries are never served from the cache.
+	//
+	// Cached Get results are invalidated when items are written through the client with Put,
+	// Delete, Update, batch writes or write transactions, which then require the table's metadata
+	// to determine item keys. Query pages are not invalidated by writes and are only refreshed
+	// when they expire from the cache. Writes made by other clients are not observed until cached
+	// results expire.
+	//
+	// By default, Cache is nil and results are not cached. NewLRUCache creates an in-memory cache.
+	Cache ResultCache
+
+	// CoalesceRequests enables coalescing of identical concurrent reads. When enabled, concurrent
+	// Get calls for the same item and concurrent first page query calls with the same query
+	// parameters share a single call to DynamoDB, and each caller receives its own copy of the
+	// result. Each caller may stop waiting when its context is done; the shared call is only
+	// canceled once all of its callers have stopped waiting.
+	//
+	// The shared call is made by the first caller, using its retry policy, rate limiter and
+	// context values, including those seen by interceptors. Other callers wait on that call
+	// regardless of their own parser settings, and only the first caller reports the call's
+	// retries and consumed capacity in its parser's statistics.
+	//
+	// By default, CoalesceRequests is false and every read calls DynamoDB.
+	CoalesceRequests bool
 }
 
 // NewClient creates a new Client instance.
 func NewClient(service dynamodbiface.DynamoDBAPI) *Client {
-	return NewClientWithMetadataProvider(service, newDefaultDescriptionProvider(service))
+	client := NewClientWithMetadataProvider(service, nil)
+	// describe table calls are routed through the client's interceptors
+	client.metadataProvider = newDefaultDescriptionProvider(client.dynamodbService)
+	return client
 }
 
 // NewClientWithMetadataProvider creates a new Client instance with a specified metadata provider.
@@ -63,13 +141,20 @@ func NewClient(service dynamodbiface.DynamoDBAPI) *Client {
 // described using DescribeTable.
 func NewClientWithMetadataProvider(
 	service dynamodbiface.DynamoDBAPI, provider TableDescriptionProvider) *Client {
-	return &Client{
-		dynamodbService:         service,
+	client := &Client{
 		metadataProvider:        provider,
 		tableIndexMetadataCache: map[string]*tableIndexMetadata{},
+		itemTypes:               map[itemTypeKey]struct{}{},
+		entities:                map[string][]*Entity{},
 		// by default, all secondary indexes are considered sparse
 		SecondaryIndexSparsenessThreshold: 1.1,
+		BatchConcurrency:                  4,
 	}
+	client.dynamodbService = &interceptedService{
+		DynamoDBAPI: service,
+		client:      client,
+	}
+	return client
 }
 
 // Get retrieves a single item by its key. The key is specified in itemKey and should be a struct
@@ -81,40 +166,220 @@ func NewClientWithMetadataProvider(
 func (client *Client) Get(ctx context.Context, tableName string, itemKey,
 	returnItem interface{}) error {
 
-	key, err := dynamodbattribute.MarshalMap(itemKey)
+	key, err := encodeItem(client.Encoder, itemKey)
 	if err != nil {
 		return err
 	}
 
-	response, err := client.dynamodbService.GetItemWithContext(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(tableName),
-		Key:       key,
-	})
+	return client.getItem(ctx, tableName, key, returnItem)
+}
+
+func (client *Client) getItem(ctx context.Context, tableName string,
+	key map[string]*dynamodb.AttributeValue, returnItem interface{}) error {
+
+	cacheKey := ""
+	if client.Cache != nil || client.CoalesceRequests {
+		var err error
+		if cacheKey, err = itemCacheKey(tableName, key); err != nil {
+			return err
+		}
+	}
+
+	if client.Cache != nil {
+		if cached, found := client.getCachedItem(cacheKey); found {
+			if !cached.Found {
+				return &ErrItemNotFound{}
+			}
+			return client.decodeTableItem(tableName, client.Decoder, cached.Item, returnItem)
+		}
+	}
+
+	fetch := func(ctx context.Context) (interface{}, error) {
+		var read *itemRead
+		if client.Cache != nil {
+			read = client.beginItemRead(cacheKey)
+			defer client.endItemRead(read)
+		}
+
+		response, err := client.dynamodbService.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(tableName),
+			Key:       key,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if read != nil {
+			client.setCachedItem(read, response.Item)
+		}
+		return response.Item, nil
+	}
+
+	var fetched interface{}
+	var err error
+	if client.CoalesceRequests {
+		fetched, _, err = client.flights.do(ctx, cacheKey, fetch)
+	} else {
+		fetched, err = fetch(ctx)
+	}
 	if err != nil {
 		return err
 	}
 
-	if response.Item == nil {
+	item := fetched.(map[string]*dynamodb.AttributeValue)
+	if item == nil {
 		return &ErrItemNotFound{}
 	}
 
-	return dynamodbattribute.UnmarshalMap(response.Item, returnItem)
+	return client.decodeTableItem(tableName, client.Decoder, copyItem(item), returnItem)
 }
 
 // Put inserts a new item into the table, or replaces it if an item with the same primary key
 // already exists. The item should be a struct with the appropriate dynamodbav attribute tags.
-func (client *Client) Put(ctx context.Context, tableName string, item interface{}) error {
-	tableItem, err := dynamodbattribute.MarshalMap(item)
+//
+// Options may be specified to add write conditions with WithCondition or to return the replaced
+// item with WithReturnValues. If a condition is not met, an *ErrConditionFailed error is returned.
+func (client *Client) Put(
+	ctx context.Context, tableName string, item interface{}, opts ...WriteOption) error {
+
+	tableItem, err := encodeItem(client.Encoder, item)
+	if err != nil {
+		return err
+	}
+
+	return client.putItem(ctx, tableName, tableItem, newWriteOptions(opts))
+}
+
+func (client *Client) putItem(ctx context.Context, tableName string,
+	tableItem map[string]*dynamodb.AttributeValue, options *writeOptions) error {
+
+	cacheKeys, err := client.itemCacheKeys(ctx, tableName, tableItem)
+	if err != nil {
+		return err
+	}
+	defer client.invalidateCachedItems(cacheKeys)
+
+	input := &dynamodb.PutItemInput{
+		TableName:    aws.String(tableName),
+		Item:         tableItem,
+		ReturnValues: options.returnValuesParam(),
+	}
+
+	dynamodbExpr, hasExpression, err := options.buildExpression(client.Encoder, nil)
 	if err != nil {
 		return err
+	} else if hasExpression {
+		input.ConditionExpression = dynamodbExpr.Condition()
+		input.ExpressionAttributeNames = dynamodbExpr.Names()
+		input.ExpressionAttributeValues = dynamodbExpr.Values()
 	}
 
-	_, err = client.dynamodbService.PutItemWithContext(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(tableName),
-		Item:      tableItem,
+	output, err := client.dynamodbService.PutItemWithContext(ctx, input)
+	if err != nil {
+		return convertWriteError(err)
+	}
+
+	return options.decodeReturnItem(client, tableName, output.Attributes)
+}
+
+// Delete deletes a single item by its key. The key is specified in itemKey and should be a struct
+// with the appropriate dynamodbav attribute tags pertaining to the table's primary key. Deleting
+// an item which does not exist is not an error, unless a condition requires the item to exist.
+//
+// Options may be specified to add write conditions with WithCondition or to return the deleted
+// item with WithReturnValues. If a condition is not met, an *ErrConditionFailed error is returned.
+func (client *Client) Delete(
+	ctx context.Context, tableName string, itemKey interface{}, opts ...WriteOption) error {
+
+	key, err := encodeItem(client.Encoder, itemKey)
+	if err != nil {
+		return err
+	}
+
+	return client.deleteItem(ctx, tableName, key, newWriteOptions(opts))
+}
+
+func (client *Client) deleteItem(ctx context.Context, tableName string,
+	key map[string]*dynamodb.AttributeValue, options *writeOptions) error {
+
+	cacheKeys, err := client.itemCacheKeys(ctx, tableName, key)
+	if err != nil {
+		return err
+	}
+	defer client.invalidateCachedItems(cacheKeys)
+
+	input := &dynamodb.DeleteItemInput{
+		TableName:    aws.String(tableName),
+		Key:          key,
+		ReturnValues: options.returnValuesParam(),
+	}
+
+	dynamodbExpr, hasExpression, err := options.buildExpression(client.Encoder, nil)
+	if err != nil {
+		return err
+	} else if hasExpression {
+		input.ConditionExpression = dynamodbExpr.Condition()
+		input.ExpressionAttributeNames = dynamodbExpr.Names()
+		input.ExpressionAttributeValues = dynamodbExpr.Values()
+	}
+
+	output, err := client.dynamodbService.DeleteItemWithContext(ctx, input)
+	if err != nil {
+		return convertWriteError(err)
+	}
+
+	return options.decodeReturnItem(client, tableName, output.Attributes)
+}
+
+// Update applies update actions to a single item by its key. The key is specified in itemKey and
+// should be a struct with the appropriate dynamodbav attribute tags pertaining to the table's
+// primary key. If the item does not exist, it is created unless a condition prevents it.
+//
+// Options may be specified to add write conditions with WithCondition or to return the old or new
+// item attributes with WithReturnValues. If a condition is not met, an *ErrConditionFailed error is
+// returned.
+func (client *Client) Update(ctx context.Context, tableName string, itemKey interface{},
+	update *Update, opts ...WriteOption) error {
+
+	if update == nil {
+		return fmt.Errorf("update does not contain any actions")
+	}
+
+	key, err := encodeItem(client.Encoder, itemKey)
+	if err != nil {
+		return err
+	}
+
+	return client.updateItem(ctx, tableName, key, update, newWriteOptions(opts))
+}
+
+func (client *Client) updateItem(ctx context.Context, tableName string,
+	key map[string]*dynamodb.AttributeValue, update *Update, options *writeOptions) error {
+
+	cacheKeys, err := client.itemCacheKeys(ctx, tableName, key)
+	if err != nil {
+		return err
+	}
+	defer client.invalidateCachedItems(cacheKeys)
+
+	dynamodbExpr, _, err := options.buildExpression(client.Encoder, update)
+	if err != nil {
+		return err
+	}
+
+	output, err := client.dynamodbService.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(tableName),
+		Key:                       key,
+		UpdateExpression:          dynamodbExpr.Update(),
+		ConditionExpression:       dynamodbExpr.Condition(),
+		ExpressionAttributeNames:  dynamodbExpr.Names(),
+		ExpressionAttributeValues: dynamodbExpr.Values(),
+		ReturnValues:              options.returnValuesParam(),
 	})
+	if err != nil {
+		return convertWriteError(err)
+	}
 
-	return err
+	return options.decodeReturnItem(client, tableName, output.Attributes)
 }
 
 // Query initializes a query defined by expr on a table. The returned parser may be used to
@@ -136,7 +401,9 @@ func (client *Client) Query(tableName string, expr *Expression) *Parser {
 func (client *Client) pullIndexMetadata(
 	ctx context.Context, tableName string) (*tableIndexMetadata, error) {
 
+	client.tableIndexMetadataMutex.Lock()
 	indexMetadata, found := client.tableIndexMetadataCache[tableName]
+	client.tableIndexMetadataMutex.Unlock()
 	if !found {
 		// attempt to pull table description from metadata provider
 		tableDescription, err := client.metadataProvider.Get(ctx, tableName)
@@ -145,12 +412,22 @@ func (client *Client) pullIndexMetadata(
 		}
 		indexMetadata = client.parseTableIndexMetadata(tableDescription)
 		// add metadata to cache
+		client.tableIndexMetadataMutex.Lock()
 		client.tableIndexMetadataCache[tableName] = indexMetadata
+		client.tableIndexMetadataMutex.Unlock()
 	}
 
 	return indexMetadata, nil
 }
 
+// invalidateIndexMetadata removes a table's index metadata from the cache, so that it is pulled
+// again on the next call to the table.
+func (client *Client) invalidateIndexMetadata(tableName string) {
+	client.tableIndexMetadataMutex.Lock()
+	delete(client.tableIndexMetadataCache, tableName)
+	client.tableIndexMetadataMutex.Unlock()
+}
+
 func (client *Client) parseTableIndexMetadata(table *dynamodb.TableDescription) *tableIndexMetadata {
 	output := &tableIndexMetadata{
 		Indexes: []*tableIndex{},
@@ -212,13 +489,49 @@ func (client *Client) parseTableIndexMetadata(table *dynamodb.TableDescription)
 }
 
 func (client *Client) chooseIndex(ctx context.Context,
-	tableName string, expr *Expression) (*tableIndex, error) {
+	tableName string, expr *Expression, options queryOptions) (*tableIndex, error) {
+
+	output, err := client.invoke(ctx, OperationSelectIndex,
+		&IndexSelectionInput{
+			TableName:          tableName,
+			Expression:         expr,
+			CountOnly:          options.countOnly,
+			AllowBackFetch:     options.allowBackFetch,
+			ClientSideOrdering: options.clientSideOrdering,
+		},
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return client.selectIndex(ctx, input.(*IndexSelectionInput))
+		})
+	if err != nil {
+		return nil, err
+	}
+	selection, ok := output.(*IndexSelectionOutput)
+	if !ok || selection == nil {
+		return nil, &ErrUnexpectedOutput{Operation: OperationSelectIndex, Output: output}
+	}
 
-	// pull metadata from cache
+	// resolve the selected index from the table's metadata
 	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
 	if err != nil {
 		return nil, err
 	}
+	for _, index := range indexMetadata.Indexes {
+		if index.queryIndexName() == selection.IndexName {
+			return index, nil
+		}
+	}
+
+	return nil, fmt.Errorf("selected index not found in table metadata: %s", selection.IndexName)
+}
+
+func (client *Client) selectIndex(
+	ctx context.Context, input *IndexSelectionInput) (*IndexSelectionOutput, error) {
+
+	// pull metadata from cache
+	indexMetadata, err := client.pullIndexMetadata(ctx, input.TableName)
+	if err != nil {
+		return nil, err
+	}
 
 	var bestIndex *tableIndex
 	bestIndexScore := 0.0
@@ -226,7 +539,8 @@ func (client *Client) chooseIndex(ctx context.Context,
 	// select index with best score based on the expression
 	inviableErrs := []*ErrIndexNotViable{}
 	for _, index := range indexMetadata.Indexes {
-		indexScore, inviableErr := client.scoreIndexOnExpr(index, expr)
+		indexScore, inviableErr := client.scoreIndexOnExpr(
+			index, input.Expression, input.queryOptions())
 		if inviableErr != nil {
 			inviableErrs = append(inviableErrs, inviableErr)
 		} else if indexScore > bestIndexScore {
@@ -240,13 +554,16 @@ func (client *Client) chooseIndex(ctx context.Context,
 		return nil, &ErrNoViableIndexes{IndexErrs: inviableErrs}
 	}
 
-	return bestIndex, nil
+	return &IndexSelectionOutput{
+		IndexName: bestIndex.queryIndexName(),
+		Score:     bestIndexScore,
+	}, nil
 }
 
-func (client *Client) scoreIndexOnExpr(
-	index *tableIndex, expr *Expression) (float64, *ErrIndexNotViable) {
+func (client *Client) scoreIndexOnExpr(index *tableIndex,
+	expr *Expression, options queryOptions) (float64, *ErrIndexNotViable) {
 
-	indexNotViableReasons := client.listIndexViabilityInfractions(index, expr)
+	indexNotViableReasons := client.listIndexViabilityInfractions(index, expr, options)
 	if len(indexNotViableReasons) > 0 {
 		return 0.0, &ErrIndexNotViable{
 			IndexName:        index.Name,
@@ -291,11 +608,24 @@ func (client *Client) scoreIndexOnExpr(
 
 	indexScore := index.SparsityMultiplier * sortKeyFilterTypeScore
 
+	// Indexes which require back-fetching attributes from the table cost an additional read for
+	// each returned item, so they are only preferred when they are much more selective.
+	if options.allowBackFetch && !options.countOnly && index.requiresBackFetch(expr) {
+		indexScore *= backFetchScoreMultiplier
+	}
+
+	// Indexes which do not sort on the order attribute require every item to be buffered and
+	// ordered client-side, so indexes which sort on it are preferred.
+	if options.clientSideOrdering && !options.countOnly && expr.orderSpecified &&
+		expr.orderAttribute != index.SortKey {
+		indexScore *= clientSideOrderScoreMultiplier
+	}
+
 	return indexScore, nil
 }
 
 func (client *Client) listIndexViabilityInfractions(
-	index *tableIndex, expr *Expression) []string {
+	index *tableIndex, expr *Expression, options queryOptions) []string {
 
 	notViableReasons := []string{}
 
@@ -313,23 +643,27 @@ func (client *Client) listIndexViabilityInfractions(
 			"global secondary index does not support consistent read")
 	}
 
-	// if order is specified, index must sort on that attribute
-	if expr.orderSpecified && expr.orderAttribute != index.SortKey {
+	// if order is specified, index must sort on that attribute unless ordered client-side
+	if expr.orderSpecified && expr.orderAttribute != index.SortKey && !options.clientSideOrdering {
 		reason := fmt.Sprintf(
 			"expression specifies order, so it requires an index with sort key: %s",
 			expr.orderAttribute)
 		notViableReasons = append(notViableReasons, reason)
 	}
 
+	// secondary sort attributes can only be ordered client-side
+	if len(expr.thenByOrders) > 0 && !options.clientSideOrdering {
+		notViableReasons = append(notViableReasons,
+			"expression specifies multiple order attributes, so it requires client-side ordering")
+	}
+
 	// index must include selected attributes, or project all attributes if not specified
-	if !index.IncludesAllAttributes {
+	// missing attributes may be back-fetched from the table if allowed
+	if !index.IncludesAllAttributes && !options.countOnly && !options.allowBackFetch {
 		if expr.attributesSpecified {
-			indexMissingAttrs := []string{}
-			for _, selectedAttr := range expr.attributes {
-				if _, found := index.AttributeSet[selectedAttr]; !found {
-					indexMissingAttrs = append(indexMissingAttrs, selectedAttr)
-				}
-			}
+			// filters are evaluated on the index, so filter attributes are needed as well
+			neededAttrs := uniqueStrings(expr.attributes, expr.filterAttributes())
+			indexMissingAttrs := index.missingAttributes(neededAttrs)
 			if len(indexMissingAttrs) > 0 {
 				reason := fmt.Sprintf("index does not include attributes: %s",
 					strings.Join(indexMissingAttrs, ", "))
@@ -341,6 +675,17 @@ func (client *Client) listIndexViabilityInfractions(
 		}
 	}
 
+	// count queries do not return attributes and back-fetched attributes are fetched from the
+	// table, but filters are evaluated on the index, so the index must include every filter
+	// attribute
+	if !index.IncludesAllAttributes && (options.countOnly || options.allowBackFetch) {
+		if missingAttrs := index.missingAttributes(expr.filterAttributes()); len(missingAttrs) > 0 {
+			reason := fmt.Sprintf("index does not include filter attributes: %s",
+				strings.Join(missingAttrs, ", "))
+			notViableReasons = append(notViableReasons, reason)
+		}
+	}
+
 	// if index is sparse, then both partition and sort attributes must appear in expression
 	if index.IsSparse {
 		// equals condition on partition key takes precedence, so only need to check sort key
@@ -0,0 +1,108 @@
+package autoquery
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// newTestIndex creates a secondary index on the partition key "status" which projects the table
+// keys and the included attributes.
+func newTestIndex(included ...string) *tableIndex {
+	index := &tableIndex{Name: "byStatus", PartitionKey: "status"}
+	projection := &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")}
+	if len(included) > 0 {
+		projection.ProjectionType = aws.String("INCLUDE")
+		projection.NonKeyAttributes = aws.StringSlice(included)
+	}
+	index.loadAttributesFromProjection(projection, []string{"pk", "sk"})
+	return index
+}
+
+func TestIndexViabilityRequiresFilterAttributes(t *testing.T) {
+	client := &Client{}
+
+	tests := []struct {
+		name    string
+		index   *tableIndex
+		expr    *Expression
+		options queryOptions
+		viable  bool
+	}{
+		{
+			name:    "count with key conditions only",
+			index:   newTestIndex(),
+			expr:    NewExpression().Equal("status", "open"),
+			options: queryOptions{countOnly: true},
+			viable:  true,
+		},
+		{
+			name:    "count filtered on unprojected attribute",
+			index:   newTestIndex(),
+			expr:    NewExpression().Equal("status", "open").GreaterThan("age", 3),
+			options: queryOptions{countOnly: true},
+			viable:  false,
+		},
+		{
+			name:    "count filtered on included attribute",
+			index:   newTestIndex("age"),
+			expr:    NewExpression().Equal("status", "open").GreaterThan("age", 3),
+			options: queryOptions{countOnly: true},
+			viable:  true,
+		},
+		{
+			name:  "count with custom filter on unprojected attribute",
+			index: newTestIndex("age"),
+			expr: NewExpression().Equal("status", "open").Filter(
+				expression.Name("age").Equal(expression.Value(3)).
+					Or(expression.Name("color").Equal(expression.Value("red")))),
+			options: queryOptions{countOnly: true},
+			viable:  false,
+		},
+		{
+			name:  "count with custom filter on nested attribute of included attribute",
+			index: newTestIndex("info"),
+			expr: NewExpression().Equal("status", "open").Filter(
+				expression.Name("info.color").Equal(expression.Value("red"))),
+			options: queryOptions{countOnly: true},
+			viable:  true,
+		},
+		{
+			name:   "selected attributes with filter on unprojected attribute",
+			index:  newTestIndex("amount"),
+			expr:   NewExpression().Equal("status", "open").Equal("color", "red").Select("amount"),
+			viable: false,
+		},
+		{
+			name:   "selected attributes with filter on included attribute",
+			index:  newTestIndex("amount", "color"),
+			expr:   NewExpression().Equal("status", "open").Equal("color", "red").Select("amount"),
+			viable: true,
+		},
+		{
+			name:    "back-fetch with filter on unprojected attribute",
+			index:   newTestIndex(),
+			expr:    NewExpression().Equal("status", "open").Equal("color", "red"),
+			options: queryOptions{allowBackFetch: true},
+			viable:  false,
+		},
+		{
+			name:    "back-fetch with filter on included attribute",
+			index:   newTestIndex("color"),
+			expr:    NewExpression().Equal("status", "open").Equal("color", "red"),
+			options: queryOptions{allowBackFetch: true},
+			viable:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reasons := client.listIndexViabilityInfractions(test.index, test.expr, test.options)
+			if viable := len(reasons) == 0; viable != test.viable {
+				t.Errorf("expected viable to be %v, found reasons: %v", test.viable, reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// token is a lexical token of a condition expression.
+type token struct {
+	text   string
+	quoted bool
+	pos    int
+}
+
+// tokenize splits a condition expression into names, values, operators and keywords. Quoted
+// strings may use single or double quotes, and quotes within them may be escaped with backslash.
+func tokenize(input string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			text := strings.Builder{}
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			tokens = append(tokens, token{text: text.String(), quoted: true, pos: start + 1})
+		case isOperatorRune(r):
+			start := i
+			for i < len(runes) && isOperatorRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{text: string(runes[start:i]), pos: start + 1})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !isOperatorRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{text: string(runes[start:i]), pos: start + 1})
+		}
+	}
+	return tokens, nil
+}
+
+func isOperatorRune(r rune) bool {
+	return r == '=' || r == '<' || r == '>'
+}
+
+// isKeyword returns true if tok is the unquoted keyword, which is matched case-insensitively.
+func (tok token) isKeyword(keyword string) bool {
+	return !tok.quoted && strings.EqualFold(tok.text, keyword)
+}
+
+// numberPattern matches decimal numbers with an optional exponent, as accepted by DynamoDB.
+var numberPattern = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+
+// value returns the value of a token. Quoted tokens are always strings. Unquoted tokens are
+// numbers or booleans if they parse as such, and strings otherwise. Numbers keep their text so
+// that they are sent to DynamoDB without loss of precision.
+func (tok token) value() interface{} {
+	if tok.quoted {
+		return tok.text
+	}
+	if numberPattern.MatchString(tok.text) {
+		return dynamodbattribute.Number(tok.text)
+	}
+	switch strings.ToLower(tok.text) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return tok.text
+}
+
+// conditionParser parses condition expressions of the form
+//
+//	condition [and condition ...]
+//
+// where each condition is one of
+//
+//	name = value
+//	name < value
+//	name <= value
+//	name > value
+//	name >= value
+//	name between value and value
+//	name begins_with value
+type conditionParser struct {
+	tokens []token
+	next   int
+}
+
+// parseConditions adds the conditions of input to expr.
+func parseConditions(input string, expr *autoquery.Expression) error {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	parser := &conditionParser{tokens: tokens}
+	for {
+		if err := parser.parseCondition(expr); err != nil {
+			return err
+		}
+		if parser.done() {
+			return nil
+		}
+		if tok := parser.take(); !tok.isKeyword("and") {
+			return fmt.Errorf("expected \"and\" at position %d, found %q", tok.pos, tok.text)
+		}
+	}
+}
+
+func (parser *conditionParser) parseCondition(expr *autoquery.Expression) error {
+	if parser.done() {
+		return parser.unexpectedEnd("attribute name")
+	}
+	name := parser.take().text
+
+	if parser.done() {
+		return parser.unexpectedEnd("operator")
+	}
+	operator := parser.take()
+
+	valueToken, err := parser.takeValue()
+	if err != nil {
+		return err
+	}
+	value := valueToken.value()
+
+	switch {
+	case operator.isKeyword("between"):
+		if parser.done() {
+			return parser.unexpectedEnd("\"and\"")
+		}
+		if tok := parser.take(); !tok.isKeyword("and") {
+			return fmt.Errorf("expected \"and\" at position %d, found %q", tok.pos, tok.text)
+		}
+		highValueToken, err := parser.takeValue()
+		if err != nil {
+			return err
+		}
+		expr.Between(name, value, highValueToken.value())
+	case operator.isKeyword("begins_with"):
+		// prefixes are always strings
+		expr.BeginsWith(name, valueToken.text)
+	case operator.quoted:
+		return fmt.Errorf("expected operator at position %d, found %q",
+			operator.pos, operator.text)
+	case operator.text == "=":
+		expr.Equal(name, value)
+	case operator.text == "<":
+		expr.LessThan(name, value)
+	case operator.text == "<=":
+		expr.LessThanEqual(name, value)
+	case operator.text == ">":
+		expr.GreaterThan(name, value)
+	case operator.text == ">=":
+		expr.GreaterThanEqual(name, value)
+	default:
+		return fmt.Errorf("unknown operator at position %d: %q", operator.pos, operator.text)
+	}
+	return nil
+}
+
+func (parser *conditionParser) takeValue() (token, error) {
+	if parser.done() {
+		return token{}, parser.unexpectedEnd("value")
+	}
+	return parser.take(), nil
+}
+
+func (parser *conditionParser) take() token {
+	tok := parser.tokens[parser.next]
+	parser.next++
+	return tok
+}
+
+func (parser *conditionParser) done() bool {
+	return parser.next >= len(parser.tokens)
+}
+
+func (parser *conditionParser) unexpectedEnd(expected string) error {
+	return fmt.Errorf("unexpected end of expression, expected %s", expected)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize(`name="a \"quoted\" value" and  other>='it\'s' and x<1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []token{
+		{text: "name", pos: 1},
+		{text: "=", pos: 5},
+		{text: `a "quoted" value`, quoted: true, pos: 6},
+		{text: "and", pos: 27},
+		{text: "other", pos: 32},
+		{text: ">=", pos: 37},
+		{text: "it's", quoted: true, pos: 39},
+		{text: "and", pos: 47},
+		{text: "x", pos: 51},
+		{text: "<", pos: 52},
+		{text: "1", pos: 53},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected tokens %+v, found %+v", expected, tokens)
+	}
+}
+
+func TestTokenizeQuotes(t *testing.T) {
+	testCases := map[string]string{
+		`"it's"`:            "it's",
+		`'say "hi"'`:        `say "hi"`,
+		`"back\\slash"`:     `back\slash`,
+		`"and = between"`:   "and = between",
+		`""`:                "",
+		`"ünïcode \"ü\""`:   `ünïcode "ü"`,
+		`'trailing\'quote'`: "trailing'quote",
+	}
+	for input, text := range testCases {
+		tokens, err := tokenize(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		if len(tokens) != 1 || !tokens[0].quoted || tokens[0].text != text {
+			t.Errorf("%s: expected quoted token %q, found %+v", input, text, tokens)
+		}
+	}
+}
+
+func TestTokenizeUnterminatedString(t *testing.T) {
+	testCases := map[string]string{
+		`name = "value`:    "unterminated string at position 8",
+		`ü = 'value\'`:     "unterminated string at position 5",
+		`name = "a" and '`: "unterminated string at position 16",
+	}
+	for input, message := range testCases {
+		if _, err := tokenize(input); err == nil || err.Error() != message {
+			t.Errorf("%s: expected error %q, found %v", input, message, err)
+		}
+	}
+}
+
+func TestTokenValue(t *testing.T) {
+	testCases := []struct {
+		tok   token
+		value interface{}
+	}{
+		{token{text: "1"}, dynamodbattribute.Number("1")},
+		{token{text: "-1.50"}, dynamodbattribute.Number("-1.50")},
+		{token{text: "+.5"}, dynamodbattribute.Number("+.5")},
+		{token{text: "1e3"}, dynamodbattribute.Number("1e3")},
+		{token{text: "12345678901234567890.123456789"},
+			dynamodbattribute.Number("12345678901234567890.123456789")},
+		{token{text: "true"}, true},
+		{token{text: "FALSE"}, false},
+		{token{text: "inf"}, "inf"},
+		{token{text: "NaN"}, "NaN"},
+		{token{text: "0x10"}, "0x10"},
+		{token{text: "1_000"}, "1_000"},
+		{token{text: "1.2.3"}, "1.2.3"},
+		{token{text: "text"}, "text"},
+		{token{text: "1", quoted: true}, "1"},
+		{token{text: "true", quoted: true}, "true"},
+	}
+	for _, testCase := range testCases {
+		if value := testCase.tok.value(); value != testCase.value {
+			t.Errorf("%+v: expected %#v, found %#v", testCase.tok, testCase.value, value)
+		}
+	}
+}
+
+func TestParseConditions(t *testing.T) {
+	testCases := map[string]*autoquery.Expression{
+		"": autoquery.NewExpression(),
+		`director = "Clint Eastwood" and year between 1990 and 2000`: autoquery.NewExpression().
+			Equal("director", "Clint Eastwood").
+			Between("year", dynamodbattribute.Number("1990"), dynamodbattribute.Number("2000")),
+		`a < 1 AND b <= 2.5 And c > x and d >= true`: autoquery.NewExpression().
+			LessThan("a", dynamodbattribute.Number("1")).
+			LessThanEqual("b", dynamodbattribute.Number("2.5")).
+			GreaterThan("c", "x").
+			GreaterThanEqual("d", true),
+		`title BEGINS_WITH 12 and rating BETWEEN 'A' AND 'C'`: autoquery.NewExpression().
+			BeginsWith("title", "12").
+			Between("rating", "A", "C"),
+		`"quoted name"='and'`: autoquery.NewExpression().Equal("quoted name", "and"),
+	}
+	for input, expected := range testCases {
+		expr := autoquery.NewExpression()
+		if err := parseConditions(input, expr); err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		if !reflect.DeepEqual(expr, expected) {
+			t.Errorf("%s: expected %+v, found %+v", input, expected, expr)
+		}
+	}
+}
+
+func TestParseConditionsErrors(t *testing.T) {
+	testCases := map[string]string{
+		`pk`:                        "unexpected end of expression, expected operator",
+		`pk =`:                      "unexpected end of expression, expected value",
+		`pk = 1 and`:                "unexpected end of expression, expected attribute name",
+		`pk = 1 or sk = 2`:          `expected "and" at position 8, found "or"`,
+		`pk = 1 "and" sk = 2`:       `expected "and" at position 8, found "and"`,
+		`sk between 1`:              `unexpected end of expression, expected "and"`,
+		`sk between 1 or 2`:         `expected "and" at position 14, found "or"`,
+		`sk between 1 and`:          "unexpected end of expression, expected value",
+		`pk "=" 1`:                  `expected operator at position 4, found "="`,
+		`pk => 1`:                   `unknown operator at position 4: "=>"`,
+		`pk contains 1`:             `unknown operator at position 4: "contains"`,
+		`pk = "unterminated`:        "unterminated string at position 6",
+		`pk = 1 and sk between 1 2`: `expected "and" at position 25, found "2"`,
+	}
+	for input, message := range testCases {
+		err := parseConditions(input, autoquery.NewExpression())
+		if err == nil || err.Error() != message {
+			t.Errorf("%s: expected error %q, found %v", input, message, err)
+		}
+	}
+}
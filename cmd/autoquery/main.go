@@ -0,0 +1,276 @@
+// Command autoquery runs and explains autoquery queries from the command line.
+//
+// Usage:
+//
+//	autoquery [flags] <table> [condition [and condition ...]]
+//
+// Conditions are written as "name operator value", where the operator is one of =, <, <=, >, >=,
+// begins_with or "between value and value":
+//
+//	autoquery Movies 'director = "Clint Eastwood" and year between 1990 and 2000'
+//
+// Quoted values are strings. Unquoted values are numbers or booleans if they parse as such, and
+// strings otherwise. The index to query is selected in the same way as by autoquery.Client.
+//
+// Flags must precede the table name. Items are printed as JSON lines by default, or as CSV or an
+// aligned table with -format. With -explain, the viability and score of each index of the table
+// is printed as a table, or as JSON with -format json, instead of running the query.
+//
+// Table metadata may be read from a file with -metadata-file, which allows queries to be explained
+// without calls to DynamoDB. The file may contain the output of "aws dynamodb describe-table" or
+// an autoquery.TableSchema encoded as JSON.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type options struct {
+	format       string
+	explain      bool
+	endpoint     string
+	region       string
+	profile      string
+	limit        int
+	maxPages     int
+	maxItems     int
+	metadataFile string
+	selectAttrs  string
+	orderBy      string
+	consistent   bool
+	backFetch    bool
+	clientOrder  int
+	stats        bool
+}
+
+func main() {
+	opts := &options{}
+	flags := flag.NewFlagSet("autoquery", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(),
+			"Usage: autoquery [flags] <table> [condition [and condition ...]]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Flags:")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.format, "format", "",
+		"output format: json, csv or table (default json lines, or table with -explain)")
+	flags.BoolVar(&opts.explain, "explain", false,
+		"print the viability and score of each index instead of running the query")
+	flags.StringVar(&opts.endpoint, "endpoint", "",
+		"DynamoDB endpoint URL, such as http://localhost:8000 for DynamoDB Local")
+	flags.StringVar(&opts.region, "region", "", "AWS region")
+	flags.StringVar(&opts.profile, "profile", "", "AWS shared config profile")
+	flags.IntVar(&opts.limit, "limit", 0, "maximum number of items evaluated per page")
+	flags.IntVar(&opts.maxPages, "max-pages", 0, "maximum number of pages to query")
+	flags.IntVar(&opts.maxItems, "max-items", 0, "maximum number of items to print")
+	flags.StringVar(&opts.metadataFile, "metadata-file", "",
+		"JSON file with a DescribeTable output or table schema to use as table metadata")
+	flags.StringVar(&opts.selectAttrs, "select", "",
+		"comma-separated attributes to return, which are also the csv and table columns")
+	flags.StringVar(&opts.orderBy, "order-by", "",
+		"comma-separated order attributes, each optionally followed by :desc")
+	flags.BoolVar(&opts.consistent, "consistent", false, "use strongly consistent reads")
+	flags.BoolVar(&opts.backFetch, "back-fetch", false,
+		"allow indexes which do not project all attributes, fetching items from the table")
+	flags.IntVar(&opts.clientOrder, "client-side-order", 0,
+		"order up to this many items client-side when no index sorts on the order attribute")
+	flags.BoolVar(&opts.stats, "stats", false, "print query statistics to stderr")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	tableName := flags.Arg(0)
+	conditions := strings.Join(flags.Args()[1:], " ")
+
+	stdout := bufio.NewWriter(os.Stdout)
+	err := run(context.Background(), stdout, os.Stderr, tableName, conditions, opts)
+	if flushErr := stdout.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "autoquery:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs or explains the query, writing results to w. With -stats, query statistics are written
+// to stderr after the results, including when the query fails.
+func run(ctx context.Context, w, stderr io.Writer, tableName, conditions string,
+	opts *options) error {
+
+	expr, err := buildExpression(conditions, opts)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(opts)
+	if err != nil {
+		return err
+	}
+
+	parser := client.Query(tableName, expr)
+	if opts.limit > 0 {
+		parser.SetLimitPerPage(opts.limit)
+	}
+	if opts.maxPages > 0 {
+		parser.SetMaxPagination(opts.maxPages)
+	}
+	if opts.backFetch {
+		parser.SetAllowBackFetch(true)
+	}
+	if opts.clientOrder > 0 {
+		parser.SetClientSideOrdering(opts.clientOrder)
+	}
+
+	if opts.explain {
+		if opts.format == "" {
+			opts.format = formatTable
+		}
+		explanation, err := parser.Explain(ctx)
+		if err != nil {
+			return err
+		}
+		return writeExplanation(w, explanation, opts.format)
+	}
+
+	if opts.format == "" {
+		opts.format = formatJSON
+	}
+	writer, err := newItemWriter(w, opts.format, splitList(opts.selectAttrs))
+	if err != nil {
+		return err
+	}
+
+	err = writeItems(ctx, writer, parser, opts.maxItems)
+	if opts.stats {
+		if statsErr := writeStats(stderr, parser.Stats()); err == nil {
+			err = statsErr
+		}
+	}
+	return err
+}
+
+// writeItems writes up to maxItems items parsed by parser, or all items if maxItems is not
+// positive.
+func writeItems(ctx context.Context, writer itemWriter, parser *autoquery.Parser,
+	maxItems int) error {
+
+	for count := 0; maxItems <= 0 || count < maxItems; count++ {
+		item, err := parser.NextRaw(ctx)
+		if _, complete := err.(*autoquery.ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err := writer.Write(item); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// writeStats writes query statistics as indented JSON.
+func writeStats(w io.Writer, stats autoquery.ParserStats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
+
+// buildExpression builds the query expression from the conditions and the expression flags.
+func buildExpression(conditions string, opts *options) (*autoquery.Expression, error) {
+	expr := autoquery.NewExpression()
+	if err := parseConditions(conditions, expr); err != nil {
+		return nil, err
+	}
+
+	if attributes := splitList(opts.selectAttrs); len(attributes) > 0 {
+		expr.Select(attributes...)
+	}
+
+	for i, order := range splitList(opts.orderBy) {
+		attribute, ascending := order, true
+		if separator := strings.LastIndex(order, ":"); separator >= 0 {
+			attribute = order[:separator]
+			switch strings.ToLower(order[separator+1:]) {
+			case "asc":
+			case "desc":
+				ascending = false
+			default:
+				return nil, fmt.Errorf(
+					"invalid order direction in %q, expected asc or desc", order)
+			}
+		}
+		if i == 0 {
+			expr.OrderBy(attribute, ascending)
+		} else {
+			expr.ThenBy(attribute, ascending)
+		}
+	}
+
+	if opts.consistent {
+		expr.ConsistentRead(true)
+	}
+
+	return expr, nil
+}
+
+// newClient creates a client from the AWS shared configuration and the connection flags.
+func newClient(opts *options) (*autoquery.Client, error) {
+	config := aws.NewConfig()
+	if opts.region != "" {
+		config.Region = aws.String(opts.region)
+	}
+	if opts.endpoint != "" {
+		config.Endpoint = aws.String(opts.endpoint)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		Profile:           opts.profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(sess.Config.Region) == "" && opts.endpoint != "" {
+		// DynamoDB Local accepts any region, but requests must still be signed with one
+		sess.Config.Region = aws.String("us-east-1")
+	}
+	svc := dynamodb.New(sess)
+
+	if opts.metadataFile == "" {
+		return autoquery.NewClient(svc), nil
+	}
+	provider, err := loadMetadataFile(opts.metadataFile)
+	if err != nil {
+		return nil, err
+	}
+	return autoquery.NewClientWithMetadataProvider(svc, provider), nil
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(list string) []string {
+	elements := []string{}
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
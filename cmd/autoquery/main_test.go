@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+)
+
+func TestRunExplain(t *testing.T) {
+	ctx := context.Background()
+	opts := &options{
+		explain:      true,
+		region:       "us-east-1",
+		metadataFile: writeMetadataFile(t, describeTableOutput),
+	}
+
+	// the metadata file allows queries to be explained without calls to DynamoDB
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err := run(ctx, stdout, stderr, "Movies", `director = "Clint Eastwood" and year > 1990`, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "by-director  director       year      yes") ||
+		!strings.Contains(stdout.String(), `Key condition: (director = "Clint Eastwood")`) {
+		t.Errorf("unexpected explanation:\n%s", stdout.String())
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("expected no stderr output, found %s", stderr.String())
+	}
+}
+
+func TestRunWritesStatsOnError(t *testing.T) {
+	ctx := context.Background()
+	opts := &options{
+		stats:        true,
+		region:       "us-east-1",
+		metadataFile: writeMetadataFile(t, describeTableOutput),
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err := run(ctx, stdout, stderr, "Movies", "title = x", opts)
+	if _, noViableIndexes := err.(*autoquery.ErrNoViableIndexes); !noViableIndexes {
+		t.Fatalf("expected ErrNoViableIndexes, found %v", err)
+	}
+
+	stats := autoquery.ParserStats{}
+	if err := json.Unmarshal(stderr.Bytes(), &stats); err != nil {
+		t.Errorf("expected stats on stderr, found %q: %v", stderr.String(), err)
+	}
+}
+
+func TestBuildExpressionErrors(t *testing.T) {
+	if _, err := buildExpression("pk = 1", &options{orderBy: "year:up"}); err == nil {
+		t.Error("expected an error for an invalid order direction")
+	}
+	if _, err := buildExpression("pk =", &options{}); err == nil {
+		t.Error("expected an error for an invalid condition")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// descriptionProvider is a static TableDescriptionProvider for a single table description.
+type descriptionProvider struct {
+	description *dynamodb.TableDescription
+}
+
+func (p *descriptionProvider) Get(
+	ctx context.Context, tableName string) (*dynamodb.TableDescription, error) {
+
+	if tableName != aws.StringValue(p.description.TableName) {
+		return nil, fmt.Errorf("metadata file does not describe table %s", tableName)
+	}
+	return p.description, nil
+}
+
+// loadMetadataFile reads a static table description provider from a JSON file. The file may
+// contain either the output of DescribeTable, as printed by "aws dynamodb describe-table", or an
+// autoquery.TableSchema.
+func loadMetadataFile(filename string) (autoquery.TableDescriptionProvider, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(contents, &fields); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %v", filename, err)
+	}
+
+	if _, found := fields["Table"]; found {
+		output := &dynamodb.DescribeTableOutput{}
+		if err := json.Unmarshal(contents, output); err != nil {
+			return nil, fmt.Errorf("invalid table description in %s: %v", filename, err)
+		}
+		if output.Table == nil || output.Table.TableName == nil {
+			return nil, fmt.Errorf("table description in %s does not include TableName", filename)
+		}
+		return &descriptionProvider{description: output.Table}, nil
+	}
+
+	schema := &autoquery.TableSchema{}
+	if err := json.Unmarshal(contents, schema); err != nil {
+		return nil, fmt.Errorf("invalid table schema in %s: %v", filename, err)
+	}
+	if schema.TableName == "" || schema.PartitionKey.Name == "" {
+		return nil, fmt.Errorf(
+			"metadata file %s must contain a table description or a table schema", filename)
+	}
+	return schema, nil
+}
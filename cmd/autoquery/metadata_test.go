@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// describeTableOutput is the output of "aws dynamodb describe-table" for the Movies table.
+const describeTableOutput = `{
+	"Table": {
+		"TableName": "Movies",
+		"ItemCount": 10,
+		"AttributeDefinitions": [
+			{"AttributeName": "id", "AttributeType": "S"},
+			{"AttributeName": "director", "AttributeType": "S"},
+			{"AttributeName": "year", "AttributeType": "N"}
+		],
+		"KeySchema": [{"AttributeName": "id", "KeyType": "HASH"}],
+		"GlobalSecondaryIndexes": [{
+			"IndexName": "by-director",
+			"KeySchema": [
+				{"AttributeName": "director", "KeyType": "HASH"},
+				{"AttributeName": "year", "KeyType": "RANGE"}
+			],
+			"Projection": {"ProjectionType": "ALL"},
+			"ItemCount": 10
+		}]
+	}
+}`
+
+// tableSchema is an autoquery.TableSchema for the Movies table.
+const tableSchema = `{
+	"TableName": "Movies",
+	"PartitionKey": {"Name": "id", "Type": "S"},
+	"GlobalSecondaryIndexes": [{
+		"Name": "by-director",
+		"PartitionKey": {"Name": "director", "Type": "S"},
+		"SortKey": {"Name": "year", "Type": "N"}
+	}]
+}`
+
+// writeMetadataFile writes contents to a temporary file, which is removed when the test
+// completes.
+func writeMetadataFile(t *testing.T, contents string) string {
+	t.Helper()
+	file, err := ioutil.TempFile("", "autoquery-metadata-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(file.Name()) })
+
+	if _, err := file.WriteString(contents); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func TestLoadMetadataFile(t *testing.T) {
+	ctx := context.Background()
+	for name, contents := range map[string]string{
+		"description": describeTableOutput,
+		"schema":      tableSchema,
+	} {
+		provider, err := loadMetadataFile(writeMetadataFile(t, contents))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		description, err := provider.Get(ctx, "Movies")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if aws.StringValue(description.TableName) != "Movies" ||
+			len(description.KeySchema) != 1 || len(description.GlobalSecondaryIndexes) != 1 ||
+			aws.StringValue(description.GlobalSecondaryIndexes[0].IndexName) != "by-director" {
+			t.Errorf("%s: unexpected table description: %v", name, description)
+		}
+
+		if _, err := provider.Get(ctx, "Shows"); err == nil {
+			t.Errorf("%s: expected an error for a table which is not described", name)
+		}
+	}
+}
+
+func TestLoadMetadataFileErrors(t *testing.T) {
+	testCases := map[string]string{
+		"not json":                "invalid metadata file",
+		`["Movies"]`:              "invalid metadata file",
+		`{"Table": 1}`:            "invalid table description",
+		`{"Table": {}}`:           "does not include TableName",
+		`{"TableName": 1}`:        "invalid table schema",
+		`{"Name": "Movies"}`:      "must contain a table description or a table schema",
+		`{"TableName": "Movies"}`: "must contain a table description or a table schema",
+	}
+	for contents, message := range testCases {
+		_, err := loadMetadataFile(writeMetadataFile(t, contents))
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: expected error containing %q, found %v", contents, message, err)
+		}
+	}
+
+	if _, err := loadMetadataFile("does-not-exist.json"); !os.IsNotExist(err) {
+		t.Errorf("expected a file not found error, found %v", err)
+	}
+}
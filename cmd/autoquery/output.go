@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Output formats.
+const (
+	formatJSON  = "json"
+	formatCSV   = "csv"
+	formatTable = "table"
+)
+
+// itemWriter writes query result items in an output format.
+type itemWriter interface {
+	Write(item map[string]*dynamodb.AttributeValue) error
+	Flush() error
+}
+
+func newItemWriter(w io.Writer, format string, columns []string) (itemWriter, error) {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return &jsonLinesWriter{encoder: encoder}, nil
+	case formatCSV:
+		return &tabularWriter{csvWriter: csv.NewWriter(w), columns: columns}, nil
+	case formatTable:
+		return &tabularWriter{
+			tabWriter: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0),
+			columns:   columns,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown output format: %s", format)
+}
+
+// jsonLinesWriter writes each item as a JSON object on its own line.
+type jsonLinesWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonLinesWriter) Write(item map[string]*dynamodb.AttributeValue) error {
+	return w.encoder.Encode(itemValues(item))
+}
+
+func (w *jsonLinesWriter) Flush() error {
+	return nil
+}
+
+// tabularWriter writes items as CSV or as an aligned table. When columns are specified, each
+// item is written as it is received, although a table is only aligned and written on Flush.
+// Otherwise, items are buffered until Flush so that the columns include every attribute of every
+// item.
+type tabularWriter struct {
+	csvWriter     *csv.Writer
+	tabWriter     *tabwriter.Writer
+	columns       []string
+	headerWritten bool
+	items         []map[string]interface{}
+}
+
+func (w *tabularWriter) Write(item map[string]*dynamodb.AttributeValue) error {
+	if len(w.columns) == 0 {
+		w.items = append(w.items, itemValues(item))
+		return nil
+	}
+	return w.writeItem(w.columns, itemValues(item))
+}
+
+func (w *tabularWriter) Flush() error {
+	columns := w.columns
+	if len(columns) == 0 {
+		columnSet := map[string]struct{}{}
+		for _, item := range w.items {
+			for attribute := range item {
+				columnSet[attribute] = struct{}{}
+			}
+		}
+		for attribute := range columnSet {
+			columns = append(columns, attribute)
+		}
+		sort.Strings(columns)
+
+		for _, item := range w.items {
+			if err := w.writeItem(columns, item); err != nil {
+				return err
+			}
+		}
+		w.items = nil
+	}
+
+	// the header is written even if there are no items
+	if err := w.writeHeader(columns); err != nil {
+		return err
+	}
+	if w.csvWriter != nil {
+		w.csvWriter.Flush()
+		return w.csvWriter.Error()
+	}
+	return w.tabWriter.Flush()
+}
+
+func (w *tabularWriter) writeHeader(columns []string) error {
+	if w.headerWritten {
+		return nil
+	}
+	w.headerWritten = true
+	return w.writeRow(append([]string{}, columns...))
+}
+
+func (w *tabularWriter) writeItem(columns []string, item map[string]interface{}) error {
+	if err := w.writeHeader(columns); err != nil {
+		return err
+	}
+	row := make([]string, 0, len(columns))
+	for _, column := range columns {
+		row = append(row, formatCell(item[column]))
+	}
+	return w.writeRow(row)
+}
+
+// tableEscaper escapes tabs and newlines, which would break a table's alignment.
+var tableEscaper = strings.NewReplacer("\t", `\t`, "\n", `\n`)
+
+func (w *tabularWriter) writeRow(row []string) error {
+	if w.csvWriter != nil {
+		if err := w.csvWriter.Write(row); err != nil {
+			return err
+		}
+		w.csvWriter.Flush()
+		return w.csvWriter.Error()
+	}
+
+	for i := range row {
+		row[i] = tableEscaper.Replace(row[i])
+	}
+	_, err := fmt.Fprintln(w.tabWriter, strings.Join(row, "\t"))
+	return err
+}
+
+// itemValues converts an item into plain values. Numbers are kept as json.Number so that they
+// are printed without loss of precision.
+func itemValues(item map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	values := make(map[string]interface{}, len(item))
+	for attribute, av := range item {
+		values[attribute] = plainValue(av)
+	}
+	return values
+}
+
+func plainValue(av *dynamodb.AttributeValue) interface{} {
+	switch {
+	case av == nil:
+		return nil
+	case av.S != nil:
+		return *av.S
+	case av.N != nil:
+		return json.Number(*av.N)
+	case av.B != nil:
+		return av.B
+	case av.BOOL != nil:
+		return *av.BOOL
+	case av.SS != nil:
+		return aws.StringValueSlice(av.SS)
+	case av.NS != nil:
+		numbers := make([]json.Number, 0, len(av.NS))
+		for _, n := range av.NS {
+			numbers = append(numbers, json.Number(aws.StringValue(n)))
+		}
+		return numbers
+	case av.BS != nil:
+		return av.BS
+	case av.M != nil:
+		return itemValues(av.M)
+	case av.L != nil:
+		list := make([]interface{}, 0, len(av.L))
+		for _, element := range av.L {
+			list = append(list, plainValue(element))
+		}
+		return list
+	}
+	// NULL
+	return nil
+}
+
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// explainOutput is the JSON output of an explanation, with the query's expressions expanded in
+// place of the full query input.
+type explainOutput struct {
+	TableName              string
+	Indexes                []autoquery.IndexExplanation
+	KeyConditionExpression string `json:",omitempty"`
+	FilterExpression       string `json:",omitempty"`
+	ProjectionExpression   string `json:",omitempty"`
+	RequiresBackFetch      bool
+}
+
+// writeExplanation writes a query explanation as a single JSON object, or as a table of indexes
+// followed by the query to be made for other formats.
+func writeExplanation(
+	w io.Writer, explanation *autoquery.QueryExplanation, format string) error {
+
+	input := explanation.QueryInput
+	if format == formatJSON {
+		output := explainOutput{
+			TableName:         explanation.TableName,
+			Indexes:           explanation.Indexes,
+			RequiresBackFetch: explanation.RequiresBackFetch,
+		}
+		if input != nil {
+			output.KeyConditionExpression = expandExpression(
+				aws.StringValue(input.KeyConditionExpression), input)
+			output.FilterExpression = expandExpression(
+				aws.StringValue(input.FilterExpression), input)
+			output.ProjectionExpression = expandExpression(
+				aws.StringValue(input.ProjectionExpression), input)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(&output)
+	}
+
+	fmt.Fprintf(w, "Table: %s\n\n", explanation.TableName)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tPARTITION KEY\tSORT KEY\tVIABLE\tSCORE\tSELECTED\tREASONS")
+	for _, index := range explanation.Indexes {
+		name := index.IndexName
+		if name == "" {
+			name = "(table)"
+		}
+		viable, score, selected := "no", "-", ""
+		if index.Viable {
+			viable, score = "yes", formatScore(index.Score)
+		}
+		if index.Selected {
+			selected = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, index.PartitionKey,
+			index.SortKey, viable, score, selected, strings.Join(index.NotViableReasons, "; "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if input == nil {
+		_, err := fmt.Fprintln(w, "\nNo viable indexes.")
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Key condition: %s\n", expandExpression(
+		aws.StringValue(input.KeyConditionExpression), input))
+	if input.FilterExpression != nil {
+		fmt.Fprintf(w, "Filter: %s\n", expandExpression(*input.FilterExpression, input))
+	}
+	if input.ProjectionExpression != nil {
+		fmt.Fprintf(w, "Projection: %s\n", expandExpression(*input.ProjectionExpression, input))
+	}
+	if explanation.RequiresBackFetch {
+		fmt.Fprintln(w, "Back fetch: items are fetched from the table")
+	}
+	return nil
+}
+
+func formatScore(score float64) string {
+	if score == math.MaxFloat64 {
+		// viable indexes without any items are always preferred
+		return "max"
+	}
+	return strconv.FormatFloat(score, 'g', 4, 64)
+}
+
+var placeholderPattern = regexp.MustCompile(`[#:][0-9A-Za-z_]+`)
+
+// expandExpression replaces the attribute name and value placeholders of a query expression with
+// the names and values they stand for.
+func expandExpression(expr string, input *dynamodb.QueryInput) string {
+	return placeholderPattern.ReplaceAllStringFunc(expr, func(placeholder string) string {
+		if name, found := input.ExpressionAttributeNames[placeholder]; found {
+			return aws.StringValue(name)
+		}
+		if value, found := input.ExpressionAttributeValues[placeholder]; found {
+			if encoded, err := json.Marshal(plainValue(value)); err == nil {
+				return string(encoded)
+			}
+		}
+		return placeholder
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// testItems returns items with a variety of attribute types, and an attribute only in the second
+// item.
+func testItems() []map[string]*dynamodb.AttributeValue {
+	return []map[string]*dynamodb.AttributeValue{
+		{
+			"pk":    {S: aws.String("p")},
+			"sk":    {N: aws.String("12345678901234567890.5")},
+			"tags":  {SS: aws.StringSlice([]string{"a", "b"})},
+			"nums":  {NS: aws.StringSlice([]string{"1", "2.50"})},
+			"data":  {B: []byte("hi")},
+			"meta":  {M: map[string]*dynamodb.AttributeValue{"ok": {BOOL: aws.Bool(true)}}},
+			"list":  {L: []*dynamodb.AttributeValue{{NULL: aws.Bool(true)}, {N: aws.String("1")}}},
+			"empty": {NULL: aws.Bool(true)},
+		},
+		{
+			"pk":   {S: aws.String("tab\there")},
+			"sk":   {N: aws.String("2")},
+			"note": {S: aws.String("line\nbreak, \"quoted\"")},
+		},
+	}
+}
+
+func writeTestItems(t *testing.T, format string, columns []string) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	writer, err := newItemWriter(buf, format, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range testItems() {
+		if err := writer.Write(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func expectOutput(t *testing.T, output, expected string) {
+	t.Helper()
+	if output != expected {
+		t.Errorf("expected output:\n%s\nfound:\n%s", expected, output)
+	}
+}
+
+func TestJSONLinesWriter(t *testing.T) {
+	expectOutput(t, writeTestItems(t, formatJSON, nil),
+		`{"data":"aGk=","empty":null,"list":[null,1],"meta":{"ok":true},"nums":[1,2.50],`+
+			`"pk":"p","sk":12345678901234567890.5,"tags":["a","b"]}`+"\n"+
+			`{"note":"line\nbreak, \"quoted\"","pk":"tab\there","sk":2}`+"\n")
+}
+
+func TestCSVWriter(t *testing.T) {
+	// without columns, every attribute of every item is a column
+	expectOutput(t, writeTestItems(t, formatCSV, nil),
+		"data,empty,list,meta,note,nums,pk,sk,tags\n"+
+			`"""aGk=""",,"[null,1]","{""ok"":true}",,"[1,2.50]",p,12345678901234567890.5,`+
+			`"[""a"",""b""]"`+"\n"+
+			`,,,,"line`+"\n"+`break, ""quoted""",,`+"tab\there,2,\n")
+
+	expectOutput(t, writeTestItems(t, formatCSV, []string{"sk", "pk", "missing"}),
+		"sk,pk,missing\n"+
+			"12345678901234567890.5,p,\n"+
+			"2,tab\there,\n")
+}
+
+func TestTableWriter(t *testing.T) {
+	// tabs and newlines are escaped so that columns stay aligned
+	expectOutput(t, writeTestItems(t, formatTable, []string{"pk", "sk", "note"}),
+		"pk         sk                      note\n"+
+			"p          12345678901234567890.5  \n"+
+			`tab\there  2                       line\nbreak, "quoted"`+"\n")
+}
+
+func TestTabularWriterHeaderWithoutItems(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer, err := newItemWriter(buf, formatCSV, []string{"pk", "sk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expectOutput(t, buf.String(), "pk,sk\n")
+}
+
+func TestNewItemWriterUnknownFormat(t *testing.T) {
+	if _, err := newItemWriter(&bytes.Buffer{}, "xml", nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+// testExplanation returns an explanation of a query on by-director, which is selected over the
+// table and a second viable index without items.
+func testExplanation() *autoquery.QueryExplanation {
+	return &autoquery.QueryExplanation{
+		TableName: "Movies",
+		Indexes: []autoquery.IndexExplanation{
+			{
+				PartitionKey:     "id",
+				NotViableReasons: []string{"missing partition key condition", "other reason"},
+			},
+			{
+				IndexName:    "by-director",
+				PartitionKey: "director",
+				SortKey:      "year",
+				Viable:       true,
+				Score:        2.5,
+				Selected:     true,
+			},
+			{
+				IndexName:    "empty-index",
+				PartitionKey: "director",
+				Viable:       true,
+				Score:        math.MaxFloat64,
+			},
+		},
+		QueryInput: &dynamodb.QueryInput{
+			TableName:              aws.String("Movies"),
+			IndexName:              aws.String("by-director"),
+			KeyConditionExpression: aws.String("#0 = :0"),
+			FilterExpression:       aws.String("#1 > :1"),
+			ProjectionExpression:   aws.String("#0, #1"),
+			ExpressionAttributeNames: map[string]*string{
+				"#0": aws.String("director"),
+				"#1": aws.String("rating"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":0": {S: aws.String("Clint Eastwood")},
+				":1": {N: aws.String("7.50")},
+			},
+		},
+		RequiresBackFetch: true,
+	}
+}
+
+func TestWriteExplanationTable(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := writeExplanation(buf, testExplanation(), formatTable); err != nil {
+		t.Fatal(err)
+	}
+	expectOutput(t, buf.String(), strings.Join([]string{
+		"Table: Movies",
+		"",
+		"INDEX        PARTITION KEY  SORT KEY  VIABLE  SCORE  SELECTED  REASONS",
+		"(table)      id                       no      -                " +
+			"missing partition key condition; other reason",
+		"by-director  director       year      yes     2.5    *         ",
+		"empty-index  director                 yes     max              ",
+		"",
+		`Key condition: director = "Clint Eastwood"`,
+		"Filter: rating > 7.50",
+		"Projection: director, rating",
+		"Back fetch: items are fetched from the table",
+		"",
+	}, "\n"))
+}
+
+func TestWriteExplanationJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := writeExplanation(buf, testExplanation(), formatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	output := explainOutput{}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatal(err)
+	}
+	if output.TableName != "Movies" || len(output.Indexes) != 3 ||
+		!output.Indexes[1].Selected || !output.RequiresBackFetch {
+		t.Errorf("unexpected explanation: %+v", output)
+	}
+	if output.KeyConditionExpression != `director = "Clint Eastwood"` ||
+		output.FilterExpression != "rating > 7.50" ||
+		output.ProjectionExpression != "director, rating" {
+		t.Errorf("unexpected expressions: %+v", output)
+	}
+}
+
+func TestWriteExplanationNoViableIndexes(t *testing.T) {
+	explanation := testExplanation()
+	explanation.Indexes = explanation.Indexes[:1]
+	explanation.QueryInput = nil
+	explanation.RequiresBackFetch = false
+
+	buf := &bytes.Buffer{}
+	if err := writeExplanation(buf, explanation, formatTable); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(buf.String(), "\nNo viable indexes.\n") {
+		t.Errorf("expected no viable indexes, found:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeExplanation(buf, explanation, formatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "KeyConditionExpression") {
+		t.Errorf("expected no key condition, found %s", buf.String())
+	}
+}
+
+func TestExpandExpression(t *testing.T) {
+	input := &dynamodb.QueryInput{
+		ExpressionAttributeNames: map[string]*string{"#a": aws.String("name")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":b": {SS: aws.StringSlice([]string{"x"})},
+		},
+	}
+	expanded := expandExpression("#a = :b and #unknown = :unknown", input)
+	if expanded != `name = ["x"] and #unknown = :unknown` {
+		t.Errorf("unexpected expansion: %s", expanded)
+	}
+}
@@ -0,0 +1,187 @@
+package autoquery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// flightGroup coalesces concurrent calls with the same key into a single call, whose result is
+// shared by all callers.
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	value   interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// do calls fn once for concurrent calls with the same key and returns its result to each caller,
+// along with whether the caller issued the shared call.
+//
+// The shared call is made with a context which carries the values of the first caller's context,
+// but which is only canceled once every caller waiting on the call has returned. A caller whose
+// context is done returns the context's error without waiting for the shared call.
+func (group *flightGroup) do(ctx context.Context, key string,
+	fn func(ctx context.Context) (interface{}, error)) (value interface{}, issued bool, err error) {
+
+	group.mutex.Lock()
+	if group.calls == nil {
+		group.calls = map[string]*flightCall{}
+	}
+	call, found := group.calls[key]
+	if !found {
+		callCtx, cancel := context.WithCancel(detachedContext{parent: ctx})
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		group.calls[key] = call
+
+		go func() {
+			call.value, call.err = fn(callCtx)
+			group.mutex.Lock()
+			group.remove(key, call)
+			group.mutex.Unlock()
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	group.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, !found, call.err
+	case <-ctx.Done():
+		group.mutex.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// no callers remain, so the shared call is abandoned
+			group.remove(key, call)
+			call.cancel()
+		}
+		group.mutex.Unlock()
+		return nil, !found, ctx.Err()
+	}
+}
+
+// remove removes call from the group if it is the current call for key. The group's mutex must
+// be held.
+func (group *flightGroup) remove(key string, call *flightCall) {
+	if group.calls[key] == call {
+		delete(group.calls, key)
+	}
+}
+
+// detachedContext carries the values of its parent context without its deadline or cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// copyItem returns a deep copy of an item's attribute map, so that items shared between
+// coalesced callers may be modified independently.
+func copyItem(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	copied := make(map[string]*dynamodb.AttributeValue, len(item))
+	for attribute, value := range item {
+		copied[attribute] = copyAttributeValue(value)
+	}
+	return copied
+}
+
+// copyAttributeValue returns a deep copy of an attribute value, including its nested maps, lists
+// and sets.
+func copyAttributeValue(value *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if value == nil {
+		return nil
+	}
+	copied := &dynamodb.AttributeValue{
+		B:  copyBytes(value.B),
+		BS: copyByteSlices(value.BS),
+		M:  copyItem(value.M),
+		NS: copyStrings(value.NS),
+		SS: copyStrings(value.SS),
+	}
+	if value.BOOL != nil {
+		copied.BOOL = aws.Bool(*value.BOOL)
+	}
+	if value.N != nil {
+		copied.N = aws.String(*value.N)
+	}
+	if value.NULL != nil {
+		copied.NULL = aws.Bool(*value.NULL)
+	}
+	if value.S != nil {
+		copied.S = aws.String(*value.S)
+	}
+	if value.L != nil {
+		copied.L = make([]*dynamodb.AttributeValue, 0, len(value.L))
+		for _, element := range value.L {
+			copied.L = append(copied.L, copyAttributeValue(element))
+		}
+	}
+	return copied
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func copyByteSlices(bs [][]byte) [][]byte {
+	if bs == nil {
+		return nil
+	}
+	copied := make([][]byte, 0, len(bs))
+	for _, b := range bs {
+		copied = append(copied, copyBytes(b))
+	}
+	return copied
+}
+
+func copyStrings(ss []*string) []*string {
+	if ss == nil {
+		return nil
+	}
+	return aws.StringSlice(aws.StringValueSlice(ss))
+}
+
+// copyQueryOutput returns a deep copy of a query page's items and last evaluated key. The
+// consumed capacity is not copied, so that it is reported only by the caller which issued the
+// query.
+func copyQueryOutput(output *dynamodb.QueryOutput) *dynamodb.QueryOutput {
+	copied := *output
+	copied.ConsumedCapacity = nil
+	copied.Items = make([]map[string]*dynamodb.AttributeValue, 0, len(output.Items))
+	for _, item := range output.Items {
+		copied.Items = append(copied.Items, copyItem(item))
+	}
+	copied.LastEvaluatedKey = copyItem(output.LastEvaluatedKey)
+	return &copied
+}
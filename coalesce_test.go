@@ -0,0 +1,138 @@
+package autoquery
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// waitForWaiters waits until n callers are waiting on the group's calls.
+func waitForWaiters(t *testing.T, group *flightGroup, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		group.mutex.Lock()
+		waiters := 0
+		for _, call := range group.calls {
+			waiters += call.waiters
+		}
+		group.mutex.Unlock()
+
+		if waiters == n {
+			return
+		} else if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiting callers, found %d", n, waiters)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCoalesceConcurrentGets(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(5, 5)
+	svc.block = make(chan struct{})
+	client := NewClient(svc)
+	client.CoalesceRequests = true
+
+	const callers = 8
+	items := make([]testItem, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := testKey{PK: "p", SK: 3}
+			if err := client.Get(ctx, fakeTableName, key, &items[i]); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	waitForWaiters(t, &client.flights, callers)
+	close(svc.block)
+	wg.Wait()
+
+	if _, getCalls, _ := svc.calls(); getCalls != 1 {
+		t.Errorf("expected 1 get call, found %d", getCalls)
+	}
+	for _, item := range items {
+		if item.SK != 3 || item.Value != "v3" {
+			t.Errorf("unexpected item: %+v", item)
+		}
+	}
+}
+
+func TestCoalesceConcurrentFirstPages(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(4, 4)
+	svc.block = make(chan struct{})
+	client := NewClient(svc)
+	client.CoalesceRequests = true
+
+	const callers = 8
+	parsers := make([]*Parser, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		parsers[i] = client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+			SetReturnConsumedCapacity(dynamodb.ReturnConsumedCapacityTotal)
+		wg.Add(1)
+		go func(parser *Parser) {
+			defer wg.Done()
+			item, err := parser.NextRaw(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			// modifying one parser's item does not affect the items of other parsers
+			*item["value"].S = "modified"
+		}(parsers[i])
+	}
+	waitForWaiters(t, &client.flights, callers)
+	close(svc.block)
+	wg.Wait()
+
+	if queryCalls, _, _ := svc.calls(); queryCalls != 1 {
+		t.Errorf("expected 1 query call, found %d", queryCalls)
+	}
+
+	capacityUnits := 0.0
+	for _, parser := range parsers {
+		sortKeys := parseAllRaw(ctx, t, parser)
+		if len(sortKeys) != 3 {
+			t.Errorf("expected 3 remaining items, found %d", len(sortKeys))
+		}
+		capacityUnits += parser.Stats().ConsumedCapacity.CapacityUnits
+	}
+	// the 4 items of the shared page consume 2 capacity units
+	if capacityUnits != 2 {
+		t.Errorf("expected 2 capacity units to be reported, found %v", capacityUnits)
+	}
+}
+
+func TestCopyItemIsDeep(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"info": {M: map[string]*dynamodb.AttributeValue{
+			"tags": {L: []*dynamodb.AttributeValue{{S: aws.String("a")}}},
+		}},
+		"ids":  {NS: aws.StringSlice([]string{"1", "2"})},
+		"data": {B: []byte("data")},
+	}
+
+	copied := copyItem(item)
+	*copied["info"].M["tags"].L[0].S = "b"
+	*copied["ids"].NS[0] = "3"
+	copied["data"].B[0] = 'D'
+
+	if value := *item["info"].M["tags"].L[0].S; value != "a" {
+		t.Errorf("expected nested list value a, found %s", value)
+	}
+	if value := *item["ids"].NS[0]; value != "1" {
+		t.Errorf("expected number set value 1, found %s", value)
+	}
+	if value := string(item["data"].B); value != "data" {
+		t.Errorf("expected binary value data, found %s", value)
+	}
+}
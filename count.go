@@ -0,0 +1,38 @@
+package autoquery
+
+import "context"
+
+// CountResult contains the result of a count query made with Client.Count.
+type CountResult struct {
+	// Count is the number of items matching the expression.
+	Count int
+
+	// ScannedCount is the number of items evaluated by DynamoDB before filters were applied.
+	ScannedCount int
+}
+
+// Count returns the number of items matching the expression on a table. The index is selected
+// in the same way as Query, except that index projections are not considered since no
+// attributes are returned. Count queries all pages of the expression using Select=COUNT.
+func (client *Client) Count(
+	ctx context.Context, tableName string, expr *Expression) (*CountResult, error) {
+
+	parser := client.Query(tableName, expr)
+	parser.options.countOnly = true
+
+	for {
+		err := parser.fillBuffer(ctx)
+		if isParsingComplete(err) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		// count query pages should not return items, but discard any that are returned
+		parser.currentBufferIndex = len(parser.bufferedItems)
+	}
+
+	return &CountResult{
+		Count:        parser.stats.ItemsReturned,
+		ScannedCount: parser.stats.ItemsScanned,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package autoquery
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Decoder unmarshals DynamoDB items returned by queries and gets. Attribute values grouped by an
+// Aggregation are not unmarshaled with a Decoder.
+type Decoder interface {
+	Decode(item map[string]*dynamodb.AttributeValue, out interface{}) error
+}
+
+// DecoderFunc is a function which implements Decoder.
+//
+// A DecoderFunc may be used for polymorphic decoding, such as switching on a "type" attribute to
+// decode items from a single-table design into different Go types.
+type DecoderFunc func(item map[string]*dynamodb.AttributeValue, out interface{}) error
+
+// Decode calls f(item, out).
+func (f DecoderFunc) Decode(item map[string]*dynamodb.AttributeValue, out interface{}) error {
+	return f(item, out)
+}
+
+// NewAttributeDecoder creates a Decoder from a dynamodbattribute.Decoder. This may be used to
+// customize decoding options, such as decoding with "json" struct tags or with UseNumber.
+//
+//	decoder := autoquery.NewAttributeDecoder(dynamodbattribute.NewDecoder(
+//		func(d *dynamodbattribute.Decoder) {
+//			d.TagKey = "json"
+//		}))
+func NewAttributeDecoder(decoder *dynamodbattribute.Decoder) Decoder {
+	return DecoderFunc(func(item map[string]*dynamodb.AttributeValue, out interface{}) error {
+		return decoder.Decode(&dynamodb.AttributeValue{M: item}, out)
+	})
+}
+
+// decodeItem unmarshals an item using decoder, or with "dynamodbav" struct tags if decoder is nil.
+func decodeItem(
+	decoder Decoder, item map[string]*dynamodb.AttributeValue, out interface{}) error {
+
+	if decoder == nil {
+		return dynamodbattribute.UnmarshalMap(item, out)
+	}
+	return decoder.Decode(item, out)
+}
+
+// decodeItems unmarshals a list of items into out, which must be a pointer to a slice.
+func decodeItems(
+	decoder Decoder, items []map[string]*dynamodb.AttributeValue, out interface{}) error {
+
+	if decoder == nil {
+		return dynamodbattribute.UnmarshalListOfMaps(items, out)
+	}
+
+	outValue := reflect.ValueOf(out)
+	if outValue.Kind() != reflect.Ptr || outValue.IsNil() || outValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("cannot decode items into %T, must be a pointer to a slice", out)
+	}
+
+	slice := reflect.MakeSlice(outValue.Elem().Type(), len(items), len(items))
+	for i, item := range items {
+		if err := decoder.Decode(item, slice.Index(i).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	outValue.Elem().Set(slice)
+
+	return nil
+}
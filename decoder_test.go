@@ -0,0 +1,154 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+type jsonItem struct {
+	PK    string `json:"pk"`
+	SK    int    `json:"sk"`
+	Value string `json:"value"`
+}
+
+func newJSONDecoder() Decoder {
+	return NewAttributeDecoder(dynamodbattribute.NewDecoder(func(d *dynamodbattribute.Decoder) {
+		d.TagKey = "json"
+	}))
+}
+
+func newJSONEncoder() Encoder {
+	return NewAttributeEncoder(dynamodbattribute.NewEncoder(func(e *dynamodbattribute.Encoder) {
+		e.TagKey = "json"
+	}))
+}
+
+func TestNextRaw(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(3, 2))
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+
+	item, err := parser.NextRaw(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := aws.StringValue(item["value"].S); value != "v0" {
+		t.Errorf("expected value v0, found %s", value)
+	}
+
+	// raw and decoded items share the parser's position
+	decoded := testItem{}
+	if err := parser.Next(ctx, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SK != 1 {
+		t.Errorf("expected item 1, found %d", decoded.SK)
+	}
+	if item, err = parser.NextRaw(ctx); err != nil || aws.StringValue(item["sk"].N) != "2" {
+		t.Errorf("expected item 2, found %v: %v", item, err)
+	}
+	if _, err := parser.NextRaw(ctx); !isParsingComplete(err) {
+		t.Errorf("expected ErrParsingComplete, found %v", err)
+	}
+}
+
+func TestClientDecoder(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(3, 2))
+	client.Decoder = newJSONDecoder()
+
+	item := jsonItem{}
+	if err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 1}, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.SK != 1 || item.Value != "v1" {
+		t.Errorf("unexpected item from Get: %+v", item)
+	}
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	items := []jsonItem{}
+	if _, err := parser.NextPage(ctx, &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1].Value != "v1" {
+		t.Errorf("unexpected items from NextPage: %+v", items)
+	}
+}
+
+func TestParserDecoderOverridesClientDecoder(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(3, 3))
+	client.Decoder = DecoderFunc(func(item map[string]*dynamodb.AttributeValue,
+		out interface{}) error {
+		return fmt.Errorf("client decoder used")
+	})
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+		SetDecoder(newJSONDecoder())
+	item := jsonItem{}
+	if err := parser.Next(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Value != "v0" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+
+	parser.UnsetDecoder()
+	if err := parser.Next(ctx, &item); err == nil || err.Error() != "client decoder used" {
+		t.Errorf("expected the client decoder to be used, found %v", err)
+	}
+}
+
+func TestDecoderFuncPolymorphicDecoding(t *testing.T) {
+	type even struct {
+		SK int `dynamodbav:"sk"`
+	}
+	type odd struct {
+		SK int `dynamodbav:"sk"`
+	}
+
+	// items are decoded into a type chosen from their attributes
+	decoder := DecoderFunc(func(item map[string]*dynamodb.AttributeValue, out interface{}) error {
+		var sk int
+		if err := dynamodbattribute.Unmarshal(item["sk"], &sk); err != nil {
+			return err
+		}
+		var decoded interface{} = &odd{SK: sk}
+		if sk%2 == 0 {
+			decoded = &even{SK: sk}
+		}
+		*out.(*interface{}) = decoded
+		return nil
+	})
+
+	ctx := context.Background()
+	client := NewClient(newFakeService(4, 4))
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).SetDecoder(decoder)
+
+	items := []interface{}{}
+	if _, err := parser.NextPage(ctx, &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items, found %d", len(items))
+	}
+	for i, item := range items {
+		switch item.(type) {
+		case *even:
+			if i%2 != 0 {
+				t.Errorf("item %d: expected odd, found even", i)
+			}
+		case *odd:
+			if i%2 == 0 {
+				t.Errorf("item %d: expected even, found odd", i)
+			}
+		default:
+			t.Errorf("item %d: unexpected type %T", i, item)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package autoquery
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Encoder marshals items and keys written to or requested from DynamoDB. Values in query
+// conditions and Update actions are marshaled with an Encoder as the attribute of an item.
+type Encoder interface {
+	Encode(in interface{}) (map[string]*dynamodb.AttributeValue, error)
+}
+
+// EncoderFunc is a function which implements Encoder.
+type EncoderFunc func(in interface{}) (map[string]*dynamodb.AttributeValue, error)
+
+// Encode calls f(in).
+func (f EncoderFunc) Encode(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	return f(in)
+}
+
+// NewAttributeEncoder creates an Encoder from a dynamodbattribute.Encoder. This may be used to
+// customize encoding options, such as encoding with "json" struct tags.
+func NewAttributeEncoder(encoder *dynamodbattribute.Encoder) Encoder {
+	return EncoderFunc(func(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+		av, err := encoder.Encode(in)
+		if err != nil {
+			return nil, err
+		}
+		return av.M, nil
+	})
+}
+
+// encodeValue marshals a single value in an expression with encoder, so that values are marshaled
+// in the same way as the attributes of items. The value is encoded as the only attribute of a map.
+// If encoder is nil or v is already an attribute value, v is returned unchanged to be marshaled by
+// the expression package.
+func encodeValue(encoder Encoder, v interface{}) (interface{}, error) {
+	if _, isAttributeValue := v.(*dynamodb.AttributeValue); isAttributeValue || encoder == nil {
+		return v, nil
+	}
+
+	item, err := encoder.Encode(map[string]interface{}{"value": v})
+	if err != nil {
+		return nil, err
+	}
+	av, found := item["value"]
+	if !found {
+		return nil, fmt.Errorf("encoder did not encode expression value of type %T", v)
+	}
+	return av, nil
+}
+
+// encodeItem marshals an item or key using encoder, or with "dynamodbav" struct tags if encoder
+// is nil.
+func encodeItem(encoder Encoder, in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	if encoder == nil {
+		return dynamodbattribute.MarshalMap(in)
+	}
+	return encoder.Encode(in)
+}
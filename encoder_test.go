@@ -0,0 +1,160 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func TestClientEncoder(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(0, 2)
+	client := NewClient(svc)
+	client.Encoder = newJSONEncoder()
+	client.Decoder = newJSONDecoder()
+
+	if err := client.Put(ctx, fakeTableName, jsonItem{PK: "p", SK: 4, Value: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.items) != 1 || aws.StringValue(svc.items[0]["value"].S) != "json" {
+		t.Fatalf("expected the item to be encoded with json tags, found %v", svc.items)
+	}
+
+	item := jsonItem{}
+	key := struct {
+		PK string `json:"pk"`
+		SK int    `json:"sk"`
+	}{"p", 4}
+	if err := client.Get(ctx, fakeTableName, key, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Value != "json" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+}
+
+func TestEncoderFunc(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(0, 2)
+	client := NewClient(svc)
+
+	// the encoder adds an attribute to every encoded item
+	encoded := 0
+	client.Encoder = EncoderFunc(func(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+		encoded++
+		item, err := dynamodbattribute.MarshalMap(in)
+		if err != nil {
+			return nil, err
+		}
+		item["encoded"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+		return item, nil
+	})
+
+	if err := client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 1, Value: "v"}); err != nil {
+		t.Fatal(err)
+	}
+	if encoded != 1 || len(svc.items) != 1 || !aws.BoolValue(svc.items[0]["encoded"].BOOL) {
+		t.Errorf("expected the item to be encoded by the encoder, found %v", svc.items)
+	}
+}
+
+func TestEncoderMarshalsQueryValues(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 5))
+
+	// the encoder stores sort keys offset by one
+	client.Encoder = EncoderFunc(func(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+		item, err := dynamodbattribute.MarshalMap(in)
+		if err != nil {
+			return nil, err
+		}
+		if value, found := item["value"]; found && value.N != nil {
+			var n int
+			if err := dynamodbattribute.Unmarshal(value, &n); err != nil {
+				return nil, err
+			}
+			item["value"], err = dynamodbattribute.Marshal(n + 1)
+		}
+		return item, err
+	})
+
+	values := []map[string]*dynamodb.AttributeValue{}
+	client = client.Use(func(ctx context.Context, operation string, input interface{},
+		next Handler) (interface{}, error) {
+
+		if queryInput, isQuery := input.(*dynamodb.QueryInput); isQuery {
+			values = append(values, queryInput.ExpressionAttributeValues)
+		}
+		return next(ctx, input)
+	})
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p").Between("sk", 1, 3))
+	items := []testItem{}
+	if _, err := parser.NextPage(ctx, &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 {
+		t.Fatalf("expected 1 query, found %d", len(values))
+	}
+	numbers := map[string]bool{}
+	for _, value := range values[0] {
+		if value.N != nil {
+			numbers[aws.StringValue(value.N)] = true
+		}
+	}
+	if len(numbers) != 2 || !numbers["2"] || !numbers["4"] {
+		t.Errorf("expected encoded values 2 and 4, found %v", values[0])
+	}
+}
+
+func TestEncoderMarshalsUpdateValues(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(1, 1)
+	client := NewClient(svc)
+	client.Encoder = newJSONEncoder()
+
+	type owner struct {
+		Name string `json:"name"`
+	}
+	update := NewUpdate().Set("owner", owner{Name: "o"})
+	if err := client.Update(ctx, fakeTableName, testKey{PK: "p", SK: 0}, update); err != nil {
+		t.Fatal(err)
+	}
+	stored := svc.items[0]["owner"]
+	if stored == nil || stored.M["name"] == nil || aws.StringValue(stored.M["name"].S) != "o" {
+		t.Errorf("expected the value to be encoded with json tags, found %v", stored)
+	}
+}
+
+func TestEncoderValueErrors(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(1, 1))
+	encodeErr := errors.New("encode failed")
+	client.Encoder = EncoderFunc(func(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+		if _, isValue := in.(map[string]interface{}); isValue {
+			return nil, encodeErr
+		}
+		return dynamodbattribute.MarshalMap(in)
+	})
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	if err := parser.Next(ctx, &testItem{}); err != encodeErr {
+		t.Errorf("expected the query to fail with the encoder error, found %v", err)
+	}
+	update := NewUpdate().Set("value", "v")
+	if err := client.Update(ctx, fakeTableName, testKey{PK: "p", SK: 0}, update); err != encodeErr {
+		t.Errorf("expected the update to fail with the encoder error, found %v", err)
+	}
+
+	// an encoder which drops the value is reported
+	client.Encoder = EncoderFunc(func(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+		return map[string]*dynamodb.AttributeValue{}, nil
+	})
+	if _, err := encodeValue(client.Encoder, "v"); err == nil {
+		t.Error("expected an error when the encoder does not encode the value")
+	}
+}
@@ -0,0 +1,481 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// KeyTemplate declares a composite key attribute of an entity, such as a single-table design
+// sort key "ORDER#{Date}#{OrderID}". Placeholders in braces are the names of struct fields of the
+// entity, and the text between placeholders is kept literally.
+//
+// Field values are formatted as strings: integers and floats in decimal notation, booleans as
+// "true" or "false", and time.Time values in UTC as fixed-width RFC 3339 timestamps with
+// nanoseconds, such as "2006-01-02T15:04:05.000000000Z", so that keys sort in time order.
+// Adjacent placeholders must be separated by literal text, which should not occur within field
+// values so that keys can be decoded back into fields.
+type KeyTemplate struct {
+	Attribute string
+	Template  string
+}
+
+// Entity is a type of item in a single-table design, whose composite key attributes are built
+// from its struct fields with key templates.
+//
+// Entities are registered on a table with Table.RegisterEntity. Items of registered entity types
+// have their composite keys encoded when written with Table.Put and used as keys with Table.Get,
+// Table.Delete and Table.Update, and have their fields decoded from their keys whenever items of
+// the table are decoded, such as by Get, BatchGet, TransactGet, Parser.Next and Parser.NextPage.
+// Expression.EntityType restricts a query to items of an entity type.
+type Entity struct {
+	Name     string
+	itemType reflect.Type
+	keys     []*compositeKey
+}
+
+// compositeKey is a parsed key template. A template with n fields has n+1 literals, where
+// literals[i] precedes fields[i].
+type compositeKey struct {
+	attribute string
+	literals  []string
+	fields    []string
+}
+
+// NewEntity creates an entity type named name for the struct type of prototype, with composite
+// key attributes declared by keys. An error is returned if a template is malformed or refers to
+// a field which does not exist, is unexported or has an unsupported type.
+func NewEntity(name string, prototype interface{}, keys ...KeyTemplate) (*Entity, error) {
+	itemType := structType(prototype)
+	if itemType == nil {
+		return nil, fmt.Errorf("entity %s must be a struct, got %T", name, prototype)
+	}
+
+	entity := &Entity{Name: name, itemType: itemType}
+	for _, template := range keys {
+		key, err := parseKeyTemplate(template)
+		if err != nil {
+			return nil, err
+		}
+		for _, fieldName := range key.fields {
+			field, found := itemType.FieldByName(fieldName)
+			if !found {
+				return nil, fmt.Errorf("key template %q refers to unknown field %s of %s",
+					template.Template, fieldName, itemType)
+			} else if field.PkgPath != "" {
+				return nil, fmt.Errorf("key template %q refers to unexported field %s of %s",
+					template.Template, fieldName, itemType)
+			} else if !isKeyFieldType(field.Type) {
+				return nil, fmt.Errorf("key template field %s has unsupported type %s",
+					fieldName, field.Type)
+			}
+		}
+		entity.keys = append(entity.keys, key)
+	}
+
+	return entity, nil
+}
+
+func parseKeyTemplate(template KeyTemplate) (*compositeKey, error) {
+	key := &compositeKey{attribute: template.Attribute}
+	rest := template.Template
+	for {
+		open := strings.Index(rest, "{")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(rest[open:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("key template %q has an unclosed placeholder", template.Template)
+		}
+		literal := rest[:open]
+		if len(key.fields) > 0 && literal == "" {
+			return nil, fmt.Errorf("key template %q has adjacent placeholders", template.Template)
+		}
+		key.literals = append(key.literals, literal)
+		key.fields = append(key.fields, rest[open+1:open+end])
+		rest = rest[open+end+1:]
+	}
+	key.literals = append(key.literals, rest)
+
+	if template.Attribute == "" {
+		return nil, fmt.Errorf("key template %q does not specify an attribute", template.Template)
+	}
+	return key, nil
+}
+
+// prefix returns the literal text preceding the first field of the key.
+func (key *compositeKey) prefix() string {
+	return key.literals[0]
+}
+
+func (key *compositeKey) encode(itemValue reflect.Value) string {
+	var builder strings.Builder
+	for i, fieldName := range key.fields {
+		builder.WriteString(key.literals[i])
+		builder.WriteString(formatKeyField(itemValue.FieldByName(fieldName)))
+	}
+	builder.WriteString(key.literals[len(key.fields)])
+	return builder.String()
+}
+
+// split returns the field values of a key value, or false if the value does not match the key.
+func (key *compositeKey) split(value string) ([]string, bool) {
+	if !strings.HasPrefix(value, key.literals[0]) {
+		return nil, false
+	}
+	rest := value[len(key.literals[0]):]
+
+	if len(key.fields) == 0 {
+		return nil, rest == ""
+	}
+
+	values := []string{}
+	for i := range key.fields {
+		literal := key.literals[i+1]
+		if i == len(key.fields)-1 {
+			if !strings.HasSuffix(rest, literal) {
+				return nil, false
+			}
+			values = append(values, rest[:len(rest)-len(literal)])
+		} else {
+			end := strings.Index(rest, literal)
+			if end < 0 {
+				return nil, false
+			}
+			values = append(values, rest[:end])
+			rest = rest[end+len(literal):]
+		}
+	}
+	return values, true
+}
+
+func (key *compositeKey) decode(value string, itemValue reflect.Value) error {
+	values, ok := key.split(value)
+	if !ok {
+		return fmt.Errorf("value %q of attribute %s does not match its key template",
+			value, key.attribute)
+	}
+	for i, fieldName := range key.fields {
+		if err := parseKeyField(values[i], itemValue.FieldByName(fieldName)); err != nil {
+			return fmt.Errorf("cannot decode field %s from attribute %s: %v",
+				fieldName, key.attribute, err)
+		}
+	}
+	return nil
+}
+
+// EncodeKeys returns the composite key attributes of item, which must be of the entity's type.
+func (entity *Entity) EncodeKeys(item interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	itemValue, err := entity.structValue(item)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*dynamodb.AttributeValue{}
+	for _, key := range entity.keys {
+		keys[key.attribute] = &dynamodb.AttributeValue{S: aws.String(key.encode(itemValue))}
+	}
+	return keys, nil
+}
+
+// DecodeKeys sets the fields of out from the composite key attributes of item. The out argument
+// must be a pointer to the entity's type. Key attributes missing from item are ignored.
+func (entity *Entity) DecodeKeys(item map[string]*dynamodb.AttributeValue, out interface{}) error {
+	outValue := reflect.ValueOf(out)
+	if outValue.Kind() != reflect.Ptr || outValue.IsNil() ||
+		outValue.Elem().Type() != entity.itemType {
+		return fmt.Errorf("cannot decode keys of entity %s into %T", entity.Name, out)
+	}
+
+	for _, key := range entity.keys {
+		value, found := item[key.attribute]
+		if !found || value.S == nil {
+			continue
+		}
+		if err := key.decode(*value.S, outValue.Elem()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches returns true if all composite key attributes of item match the entity's templates.
+func (entity *Entity) matches(item map[string]*dynamodb.AttributeValue) bool {
+	for _, key := range entity.keys {
+		value, found := item[key.attribute]
+		if !found || value.S == nil {
+			return false
+		} else if _, ok := key.split(*value.S); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (entity *Entity) key(attribute string) *compositeKey {
+	for _, key := range entity.keys {
+		if key.attribute == attribute {
+			return key
+		}
+	}
+	return nil
+}
+
+func (entity *Entity) structValue(item interface{}) (reflect.Value, error) {
+	itemValue := reflect.ValueOf(item)
+	for itemValue.Kind() == reflect.Ptr && !itemValue.IsNil() {
+		itemValue = itemValue.Elem()
+	}
+	if itemValue.Kind() != reflect.Struct || itemValue.Type() != entity.itemType {
+		return itemValue, fmt.Errorf("item of type %T is not entity %s", item, entity.Name)
+	}
+	return itemValue, nil
+}
+
+// prefixCondition returns the key attribute and prefix which restrict a query on expr to items of
+// the entity. Keys which are the sort key of an index whose partition key has an Equal condition
+// are preferred, so that the condition is applied as a key condition. An empty attribute is
+// returned if all key attributes of the entity already have conditions.
+func (entity *Entity) prefixCondition(
+	expr *Expression, indexMetadata *tableIndexMetadata) (string, string) {
+
+	unconstrained := func(attribute string) *compositeKey {
+		key := entity.key(attribute)
+		if key == nil || key.prefix() == "" {
+			return nil
+		} else if _, found := expr.filters[attribute]; found {
+			return nil
+		}
+		return key
+	}
+
+	for _, index := range indexMetadata.Indexes {
+		if _, isEqual := expr.filters[index.PartitionKey].(*equalsFilter); !isEqual {
+			continue
+		} else if !index.IsComposite {
+			continue
+		}
+		if key := unconstrained(index.SortKey); key != nil {
+			return key.attribute, key.prefix()
+		}
+	}
+
+	for _, key := range entity.keys {
+		if unconstrained(key.attribute) != nil {
+			return key.attribute, key.prefix()
+		}
+	}
+	return "", ""
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// keyTimeLayout is the layout of time.Time key fields. Times are formatted in UTC with a fixed
+// number of fractional digits so that their keys sort in time order.
+const keyTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func isKeyFieldType(fieldType reflect.Type) bool {
+	if fieldType == timeType {
+		return true
+	}
+	switch fieldType.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func formatKeyField(value reflect.Value) string {
+	if value.Type() == timeType {
+		return value.Interface().(time.Time).UTC().Format(keyTimeLayout)
+	}
+	switch value.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits())
+	}
+	return value.String()
+}
+
+func parseKeyField(s string, value reflect.Value) error {
+	if value.Type() == timeType {
+		t, err := time.Parse(keyTimeLayout, s)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	}
+	return nil
+}
+
+// RegisterEntity registers an entity type with the table. Entities are matched to items in the
+// order they are registered, so more specific entities should be registered first.
+func (table Table) RegisterEntity(entity *Entity) {
+	client := table.autoqueryClient
+	client.entitiesMutex.Lock()
+	defer client.entitiesMutex.Unlock()
+	client.entities[table.name] = append(client.entities[table.name], entity)
+}
+
+func (client *Client) tableEntities(tableName string) []*Entity {
+	client.entitiesMutex.RLock()
+	defer client.entitiesMutex.RUnlock()
+	return client.entities[tableName]
+}
+
+// entityForType returns the entity registered on the table for the struct type of item, or nil.
+func (client *Client) entityForType(tableName string, item interface{}) *Entity {
+	itemType := structType(item)
+	if itemType == nil {
+		return nil
+	}
+	for _, entity := range client.tableEntities(tableName) {
+		if entity.itemType == itemType {
+			return entity
+		}
+	}
+	return nil
+}
+
+// resolveEntityType adds a begins-with condition to restrict expr to items of its entity type, if
+// one is specified.
+func (client *Client) resolveEntityType(
+	ctx context.Context, tableName string, expr *Expression) (*Expression, error) {
+
+	if expr.entityType == "" {
+		return expr, nil
+	}
+
+	var entity *Entity
+	for _, tableEntity := range client.tableEntities(tableName) {
+		if tableEntity.Name == expr.entityType {
+			entity = tableEntity
+			break
+		}
+	}
+	if entity == nil {
+		return nil, fmt.Errorf("entity type %s is not registered on table %s",
+			expr.entityType, tableName)
+	}
+
+	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	attribute, prefix := entity.prefixCondition(expr, indexMetadata)
+	if attribute == "" {
+		return expr, nil
+	}
+	resolved := expr.clone()
+	resolved.filters[attribute] = &beginsWithFilter{prefix: prefix}
+	return resolved, nil
+}
+
+// decodeTableItem decodes an item of a table into out. If out is a pointer to an empty interface,
+// the item is decoded into a new instance of the first registered entity which matches the item,
+// or into a map if none match. The fields of registered entities are decoded from their keys.
+func (client *Client) decodeTableItem(tableName string, decoder Decoder,
+	item map[string]*dynamodb.AttributeValue, out interface{}) error {
+
+	entities := client.tableEntities(tableName)
+	if len(entities) == 0 {
+		return decodeItem(decoder, item, out)
+	}
+
+	if outInterface, ok := out.(*interface{}); ok {
+		for _, entity := range entities {
+			if !entity.matches(item) {
+				continue
+			}
+			entityItem := reflect.New(entity.itemType).Interface()
+			if err := decodeItem(decoder, item, entityItem); err != nil {
+				return err
+			} else if err := entity.DecodeKeys(item, entityItem); err != nil {
+				return err
+			}
+			*outInterface = entityItem
+			return nil
+		}
+	}
+
+	if err := decodeItem(decoder, item, out); err != nil {
+		return err
+	}
+	if entity := client.entityForType(tableName, out); entity != nil {
+		return entity.DecodeKeys(item, out)
+	}
+	return nil
+}
+
+// decodeTableItems decodes items of a table into out, which must be a pointer to a slice. Each
+// item is decoded in the same way as decodeTableItem.
+func (client *Client) decodeTableItems(tableName string, decoder Decoder,
+	items []map[string]*dynamodb.AttributeValue, out interface{}) error {
+
+	if len(client.tableEntities(tableName)) == 0 {
+		return decodeItems(decoder, items, out)
+	}
+
+	outValue := reflect.ValueOf(out)
+	if outValue.Kind() != reflect.Ptr || outValue.IsNil() ||
+		outValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("cannot decode items into %T, must be a pointer to a slice", out)
+	}
+
+	slice := reflect.MakeSlice(outValue.Elem().Type(), len(items), len(items))
+	for i, item := range items {
+		err := client.decodeTableItem(tableName, decoder, item, slice.Index(i).Addr().Interface())
+		if err != nil {
+			return err
+		}
+	}
+	outValue.Elem().Set(slice)
+
+	return nil
+}
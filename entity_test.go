@@ -0,0 +1,185 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type testOrder struct {
+	CustomerID string
+	Date       time.Time
+	status     string
+}
+
+func TestEntityTimeKeysAreFixedWidthUTC(t *testing.T) {
+	entity, err := NewEntity("Order", testOrder{},
+		KeyTemplate{Attribute: "sk", Template: "ORDER#{Date}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zone := time.FixedZone("EST", -5*60*60)
+	date := time.Date(2021, 3, 4, 7, 8, 9, 100000000, zone)
+	keys, err := entity.EncodeKeys(testOrder{Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk := aws.StringValue(keys["sk"].S); sk != "ORDER#2021-03-04T12:08:09.100000000Z" {
+		t.Errorf("unexpected sort key: %s", sk)
+	}
+
+	decoded := testOrder{}
+	if err := entity.DecodeKeys(keys, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Date.Equal(date) {
+		t.Errorf("expected decoded date %v, found %v", date, decoded.Date)
+	}
+}
+
+func TestNewEntityRejectsUnexportedFields(t *testing.T) {
+	_, err := NewEntity("Order", testOrder{},
+		KeyTemplate{Attribute: "sk", Template: "ORDER#{status}"})
+	if err == nil {
+		t.Error("expected an error for a template with an unexported field")
+	}
+}
+
+type testUser struct {
+	UserID string `dynamodbav:"-"`
+	SK     int    `dynamodbav:"sk"`
+	Value  string `dynamodbav:"value"`
+}
+
+type testGroup struct {
+	GroupID string `dynamodbav:"-"`
+	SK      int    `dynamodbav:"sk"`
+}
+
+// newEntityTable creates a fake table of users and groups with the given partition keys, and
+// registers both entities on it.
+func newEntityTable(t *testing.T, partitionKeys ...string) (*Client, *Table) {
+	t.Helper()
+	user, err := NewEntity("User", testUser{},
+		KeyTemplate{Attribute: "pk", Template: "USER#{UserID}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := NewEntity("Group", testGroup{},
+		KeyTemplate{Attribute: "pk", Template: "GROUP#{GroupID}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := newFakeService(len(partitionKeys), 10)
+	for i, pk := range partitionKeys {
+		svc.items[i]["pk"] = &dynamodb.AttributeValue{S: aws.String(pk)}
+	}
+	client := NewClient(svc)
+	table := client.Table(fakeTableName)
+	table.RegisterEntity(user)
+	table.RegisterEntity(group)
+	return client, table
+}
+
+func TestEntityNextPage(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newEntityTable(t, "USER#a", "USER#b")
+
+	users := []testUser{}
+	_, err := client.Query(fakeTableName, NewExpression().Equal("pk", "USER#a")).
+		NextPage(ctx, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].UserID != "a" || users[1].UserID != "b" ||
+		users[1].Value != "v1" {
+		t.Errorf("expected users decoded from their keys, found %+v", users)
+	}
+}
+
+func TestEntityNextPagePolymorphic(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newEntityTable(t, "USER#a", "GROUP#b", "OTHER")
+
+	items := []interface{}{}
+	_, err := client.Query(fakeTableName, NewExpression().Equal("pk", "USER#a")).
+		NextPage(ctx, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, found %d", len(items))
+	}
+	if user, ok := items[0].(*testUser); !ok || user.UserID != "a" {
+		t.Errorf("expected user a, found %#v", items[0])
+	}
+	if group, ok := items[1].(*testGroup); !ok || group.GroupID != "b" || group.SK != 1 {
+		t.Errorf("expected group b, found %#v", items[1])
+	}
+	if _, ok := items[2].(map[string]interface{}); !ok {
+		t.Errorf("expected a map for an item without an entity, found %#v", items[2])
+	}
+}
+
+func TestEntityBatchGet(t *testing.T) {
+	ctx := context.Background()
+	_, table := newEntityTable(t, "USER#a", "GROUP#b", "USER#c")
+
+	users := []testUser{}
+	keys := []testKey{{PK: "USER#a", SK: 0}, {PK: "USER#c", SK: 2}}
+	if _, err := table.BatchGet(ctx, keys, &users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].UserID != "a" || users[1].UserID != "c" {
+		t.Errorf("expected users decoded from their keys, found %+v", users)
+	}
+
+	items := []interface{}{}
+	keys = []testKey{{PK: "GROUP#b", SK: 1}}
+	if _, err := table.BatchGet(ctx, keys, &items); err != nil {
+		t.Fatal(err)
+	}
+	if group, ok := items[0].(*testGroup); !ok || group.GroupID != "b" {
+		t.Errorf("expected group b, found %#v", items[0])
+	}
+}
+
+func TestEntityTransactGet(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newEntityTable(t, "USER#a", "GROUP#b")
+
+	user := testUser{}
+	var group interface{}
+	_, err := client.TransactGet().
+		Get(fakeTableName, testKey{PK: "USER#a", SK: 0}, &user).
+		Get(fakeTableName, testKey{PK: "GROUP#b", SK: 1}, &group).
+		Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.UserID != "a" || user.Value != "v0" {
+		t.Errorf("expected user a, found %+v", user)
+	}
+	if decoded, ok := group.(*testGroup); !ok || decoded.GroupID != "b" {
+		t.Errorf("expected group b, found %#v", group)
+	}
+}
+
+func TestEntityReturnValues(t *testing.T) {
+	ctx := context.Background()
+	_, table := newEntityTable(t, "USER#a")
+
+	old := testUser{}
+	err := table.Delete(ctx, testUser{UserID: "a", SK: 0}, WithReturnValues("ALL_OLD", &old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.UserID != "a" || old.Value != "v0" {
+		t.Errorf("expected the deleted user decoded from its keys, found %+v", old)
+	}
+}
@@ -3,6 +3,7 @@ package autoquery
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // ErrParsingComplete is returned by Parser.Next when all query items have been returned or when
@@ -51,3 +52,114 @@ type ErrItemNotFound struct{}
 func (ErrItemNotFound) Error() string {
 	return "item not found"
 }
+
+// ErrClientSideOrderLimitExceeded is returned by Parser.Next when items are ordered client-side
+// and the query returns more items than the maximum set with Parser.SetClientSideOrdering.
+type ErrClientSideOrderLimitExceeded struct {
+	MaxItems int
+}
+
+func (e ErrClientSideOrderLimitExceeded) Error() string {
+	return fmt.Sprintf("client-side ordering exceeded max items: %d", e.MaxItems)
+}
+
+// ErrUnexpectedOutput is returned when an interceptor returns without an error, but with an
+// output which is nil or not of the operation's output type.
+type ErrUnexpectedOutput struct {
+	Operation string
+	Output    interface{}
+}
+
+func (e ErrUnexpectedOutput) Error() string {
+	return fmt.Sprintf("interceptor returned unexpected output for %s: %T", e.Operation, e.Output)
+}
+
+// ErrConditionFailed is returned by Put, Update and Delete when a write condition is not met.
+type ErrConditionFailed struct {
+	Message string
+}
+
+func (e ErrConditionFailed) Error() string {
+	if e.Message == "" {
+		return "write condition failed"
+	}
+	return fmt.Sprintf("write condition failed: %s", e.Message)
+}
+
+// ErrBatchUnprocessed is returned by batch operations when keys or items remain unprocessed by
+// DynamoDB after the max number of attempts.
+type ErrBatchUnprocessed struct {
+	Attempts         int
+	UnprocessedCount int
+}
+
+func (e ErrBatchUnprocessed) Error() string {
+	return fmt.Sprintf("batch request has %d unprocessed keys or items after %d attempts",
+		e.UnprocessedCount, e.Attempts)
+}
+
+// ErrTransactionCanceled is returned by TransactWrite.Commit and TransactGet.Execute when a
+// transaction is canceled. Reasons contains the cancellation reason for each operation in the
+// order in which operations were added to the transaction.
+type ErrTransactionCanceled struct {
+	Message string
+	Reasons []TransactionCancellationReason
+}
+
+// TransactionCancellationReason describes why a transaction operation caused a transaction to be
+// canceled. Operations which did not cause the cancellation have the code "None".
+type TransactionCancellationReason struct {
+	OperationIndex int
+	Code           string
+	Message        string
+}
+
+func (e ErrTransactionCanceled) Error() string {
+	failed := e.FailedOperations()
+	if len(failed) == 0 {
+		return fmt.Sprintf("transaction canceled: %s", e.Message)
+	}
+	reasons := []string{}
+	for _, reason := range failed {
+		reasons = append(reasons, fmt.Sprintf("operation %d: %s (%s)",
+			reason.OperationIndex, reason.Code, reason.Message))
+	}
+	return fmt.Sprintf("transaction canceled: %s", strings.Join(reasons, "; "))
+}
+
+// FailedOperations returns the reasons for operations which caused the transaction to be
+// canceled, excluding operations with the code "None".
+func (e ErrTransactionCanceled) FailedOperations() []TransactionCancellationReason {
+	failed := []TransactionCancellationReason{}
+	for _, reason := range e.Reasons {
+		if reason.Code != "" && reason.Code != "None" {
+			failed = append(failed, reason)
+		}
+	}
+	return failed
+}
+
+// ErrVersionConflict is returned by Table.Put and Table.Update on versioned items when the
+// version of the stored item does not match the expected version.
+type ErrVersionConflict struct {
+	TableName       string
+	ExpectedVersion int64
+}
+
+func (e ErrVersionConflict) Error() string {
+	return fmt.Sprintf("version conflict on table %s: expected version %d",
+		e.TableName, e.ExpectedVersion)
+}
+
+// ErrMissingKeyAttributes is returned by Table.RegisterType when a struct type does not have
+// fields for all key attributes of the table and its secondary indexes.
+type ErrMissingKeyAttributes struct {
+	TableName  string
+	TypeName   string
+	Attributes []string
+}
+
+func (e ErrMissingKeyAttributes) Error() string {
+	return fmt.Sprintf("type %s is missing key attributes for table %s: %s",
+		e.TypeName, e.TableName, strings.Join(e.Attributes, ", "))
+}
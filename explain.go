@@ -0,0 +1,115 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// QueryExplanation describes how an index is selected for a parser's query.
+type QueryExplanation struct {
+	// TableName is the name of the queried table.
+	TableName string
+
+	// Indexes contains an explanation for each index of the table, starting with the table's
+	// primary index.
+	Indexes []IndexExplanation
+
+	// QueryInput is the input of the first page query made on the selected index, or nil if no
+	// index is viable.
+	QueryInput *dynamodb.QueryInput
+
+	// RequiresBackFetch is true if the selected index does not project all needed attributes, in
+	// which case items are fetched from the table. See Parser.SetAllowBackFetch.
+	RequiresBackFetch bool
+}
+
+// IndexExplanation describes how an index is scored against a query expression.
+type IndexExplanation struct {
+	// IndexName is the name of the index. For the table's primary index, IndexName is empty.
+	IndexName    string
+	PartitionKey string
+	SortKey      string
+
+	// Viable is true if the index may be used to query the expression. If the index is not
+	// viable, NotViableReasons contains the reasons why.
+	Viable           bool
+	NotViableReasons []string
+
+	// Score is the score of a viable index against the expression. Higher scores are preferred.
+	Score float64
+
+	// Selected is true if the index is selected for the query.
+	Selected bool
+}
+
+// SelectedIndex returns the explanation of the selected index, or nil if no index is viable.
+func (explanation *QueryExplanation) SelectedIndex() *IndexExplanation {
+	for i := range explanation.Indexes {
+		if explanation.Indexes[i].Selected {
+			return &explanation.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// Explain explains how an index is selected for the parser's query, including the viability and
+// score of each index of the table, without querying the table. The explanation applies the
+// parser's options, such as Parser.SetAllowBackFetch and Parser.SetClientSideOrdering.
+//
+// Index selection is made through the client's interceptors in the same way as for Next. If no
+// index is viable, the explanation is returned without a selected index rather than as an
+// ErrNoViableIndexes error.
+func (parser *Parser) Explain(ctx context.Context) (*QueryExplanation, error) {
+	expr, _, err := parser.resolveExpression(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	indexMetadata, err := parser.client.pullIndexMetadata(ctx, parser.tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	selectedIndex, err := parser.client.chooseIndex(ctx, parser.tableName, expr, parser.options)
+	if _, noViableIndexes := err.(*ErrNoViableIndexes); err != nil && !noViableIndexes {
+		return nil, err
+	}
+
+	explanation := &QueryExplanation{TableName: parser.tableName}
+	for _, index := range indexMetadata.Indexes {
+		score, inviableErr := parser.client.scoreIndexOnExpr(index, expr, parser.options)
+		indexExplanation := IndexExplanation{
+			IndexName:    index.queryIndexName(),
+			PartitionKey: index.PartitionKey,
+			SortKey:      index.SortKey,
+			Viable:       inviableErr == nil,
+			Score:        score,
+			Selected: selectedIndex != nil &&
+				index.queryIndexName() == selectedIndex.queryIndexName(),
+		}
+		if inviableErr != nil {
+			indexExplanation.NotViableReasons = inviableErr.NotViableReasons
+		}
+		explanation.Indexes = append(explanation.Indexes, indexExplanation)
+	}
+
+	if selectedIndex == nil {
+		return explanation, nil
+	}
+
+	queryExpr := expr
+	explanation.RequiresBackFetch = parser.requiresBackFetch(selectedIndex, expr)
+	if explanation.RequiresBackFetch {
+		queryExpr = backFetchQueryExpression(expr, indexMetadata.primaryIndex())
+	}
+
+	explanation.QueryInput, err = queryExpr.constructQueryInputGivenIndex(
+		selectedIndex, parser.options)
+	if err != nil {
+		return nil, err
+	}
+	parser.setQueryInputOptions(explanation.QueryInput, explanation.RequiresBackFetch)
+
+	return explanation, nil
+}
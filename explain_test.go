@@ -0,0 +1,110 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// newExplainService creates a fake table with a global secondary index which sorts on value and
+// projects the given type.
+func newExplainService(projectionType string) *fakeService {
+	svc := newFakeService(5, 2)
+	svc.indexes = []*dynamodb.GlobalSecondaryIndexDescription{{
+		IndexName: aws.String("value-index"),
+		ItemCount: aws.Int64(5),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("value"), KeyType: aws.String("RANGE")},
+		},
+		Projection: &dynamodb.Projection{ProjectionType: aws.String(projectionType)},
+	}}
+	return svc
+}
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	svc := newExplainService(dynamodb.ProjectionTypeAll)
+	client := NewClient(svc)
+
+	expr := NewExpression().Equal("pk", "p").OrderBy("value", true)
+	explanation, err := client.Query(fakeTableName, expr).Explain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.TableName != fakeTableName || len(explanation.Indexes) != 2 {
+		t.Fatalf("unexpected explanation: %+v", explanation)
+	}
+
+	table, index := explanation.Indexes[0], explanation.Indexes[1]
+	if table.IndexName != "" || table.Viable || table.Selected || len(table.NotViableReasons) == 0 {
+		t.Errorf("expected the table index not to be viable, found %+v", table)
+	}
+	if index.IndexName != "value-index" || index.PartitionKey != "pk" || index.SortKey != "value" ||
+		!index.Viable || !index.Selected || index.Score <= 0 {
+		t.Errorf("expected value-index to be selected, found %+v", index)
+	}
+	selected := explanation.SelectedIndex()
+	if selected == nil || selected.IndexName != "value-index" {
+		t.Errorf("unexpected selected index: %+v", selected)
+	}
+
+	input := explanation.QueryInput
+	if input == nil || aws.StringValue(input.IndexName) != "value-index" ||
+		aws.StringValue(input.KeyConditionExpression) == "" ||
+		!aws.BoolValue(input.ScanIndexForward) {
+		t.Errorf("unexpected query input: %v", input)
+	}
+	if explanation.RequiresBackFetch {
+		t.Error("expected no back fetch for an index projecting all attributes")
+	}
+
+	// explaining does not query the table
+	if svc.queryCalls != 0 {
+		t.Errorf("expected no queries, found %d", svc.queryCalls)
+	}
+}
+
+func TestExplainBackFetch(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newExplainService(dynamodb.ProjectionTypeKeysOnly))
+	expr := NewExpression().Equal("pk", "p").OrderBy("value", true)
+
+	explanation, err := client.Query(fakeTableName, expr).Explain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.SelectedIndex() != nil || explanation.QueryInput != nil {
+		t.Errorf("expected no viable indexes without back fetch, found %+v", explanation)
+	}
+
+	explanation, err = client.Query(fakeTableName, expr).SetAllowBackFetch(true).Explain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected := explanation.SelectedIndex()
+	if selected == nil || selected.IndexName != "value-index" || !explanation.RequiresBackFetch {
+		t.Errorf("expected value-index to be selected with back fetch, found %+v", explanation)
+	}
+}
+
+func TestExplainNoViableIndexes(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newExplainService(dynamodb.ProjectionTypeAll))
+
+	explanation, err := client.Query(fakeTableName, NewExpression().Equal("value", "v1")).
+		Explain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range explanation.Indexes {
+		if index.Viable || index.Selected || len(index.NotViableReasons) == 0 {
+			t.Errorf("expected %q not to be viable, found %+v", index.IndexName, index)
+		}
+	}
+	if explanation.SelectedIndex() != nil || explanation.QueryInput != nil {
+		t.Errorf("expected no selected index, found %+v", explanation)
+	}
+}
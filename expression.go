@@ -1,6 +1,9 @@
 package autoquery
 
 import (
+	"regexp"
+	"sort"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
@@ -16,9 +19,12 @@ type Expression struct {
 	orderSpecified bool
 	orderAttribute string
 	orderAscending bool
+	thenByOrders   []orderSpec
 
 	consistentRead bool
 
+	entityType string
+
 	additionalConditions []expression.ConditionBuilder
 }
 
@@ -102,6 +108,21 @@ func (expr *Expression) BeginsWith(attr string, prefix string) *Expression {
 	return expr
 }
 
+// KeyPrefix adds a new begins-with condition on a composite key attribute, such as
+// KeyPrefix("SK", "ORDER#") in a single-table design. KeyPrefix is equivalent to BeginsWith.
+func (expr *Expression) KeyPrefix(attr string, prefix string) *Expression {
+	return expr.BeginsWith(attr, prefix)
+}
+
+// EntityType restricts the expression to items of the entity type registered on the queried
+// table with Table.RegisterEntity. When the query is made, the entity type compiles into a
+// begins-with condition on the literal prefix of one of the entity's key templates, preferring
+// the sort key of an index whose partition key has an Equal condition in the expression.
+func (expr *Expression) EntityType(name string) *Expression {
+	expr.entityType = name
+	return expr
+}
+
 // OrderBy sets attr as the sort attribute. If ascending is true, items will be returned starting
 // with the lowest value for the attribute. If ascending is false, the highest value will be
 // returned first. OrderBy may only be used on sort key attributes of indexes which satisfy all
@@ -113,6 +134,20 @@ func (expr *Expression) OrderBy(attr string, ascending bool) *Expression {
 	return expr
 }
 
+// ThenBy adds attr as a secondary sort attribute, which orders items that have equal values for
+// all previous sort attributes. If OrderBy has not been specified, ThenBy behaves as OrderBy.
+// Subsequent calls to ThenBy append additional sort attributes.
+//
+// Indexes can only order items by a single sort key, so expressions with secondary sort
+// attributes require client-side ordering to be enabled with Parser.SetClientSideOrdering.
+func (expr *Expression) ThenBy(attr string, ascending bool) *Expression {
+	if !expr.orderSpecified {
+		return expr.OrderBy(attr, ascending)
+	}
+	expr.thenByOrders = append(expr.thenByOrders, orderSpec{attribute: attr, ascending: ascending})
+	return expr
+}
+
 // Select specifies attributes that should be returned in queried items. Subsequent calls to
 // Select will append to the existing selected attributes for the expression.
 //
@@ -161,8 +196,119 @@ func (expr *Expression) Filter(filterCondition expression.ConditionBuilder) *Exp
 	return expr
 }
 
+// clone returns a copy of the expression which may be modified without affecting the original.
+func (expr *Expression) clone() *Expression {
+	cloned := *expr
+
+	cloned.filters = map[string]conditionFilter{}
+	for k, v := range expr.filters {
+		cloned.filters[k] = v
+	}
+	cloned.attributes = append([]string{}, expr.attributes...)
+	cloned.thenByOrders = append([]orderSpec{}, expr.thenByOrders...)
+	cloned.additionalConditions = append(
+		[]expression.ConditionBuilder{}, expr.additionalConditions...)
+
+	return &cloned
+}
+
+// encodeValues returns a copy of the expression whose condition values are marshaled with
+// encoder. Conditions added with Filter are built by the expression package and are unchanged.
+func (expr *Expression) encodeValues(encoder Encoder) (*Expression, error) {
+	var err error
+	encode := func(v interface{}) interface{} {
+		encoded, encodeErr := encodeValue(encoder, v)
+		if encodeErr != nil && err == nil {
+			err = encodeErr
+		}
+		return encoded
+	}
+
+	encoded := expr.clone()
+	for attribute, filter := range expr.filters {
+		switch f := filter.(type) {
+		case *equalsFilter:
+			encoded.filters[attribute] = &equalsFilter{value: encode(f.value)}
+		case *lessThanFilter:
+			encoded.filters[attribute] = &lessThanFilter{value: encode(f.value)}
+		case *greaterThanFilter:
+			encoded.filters[attribute] = &greaterThanFilter{value: encode(f.value)}
+		case *lessThanEqualFilter:
+			encoded.filters[attribute] = &lessThanEqualFilter{value: encode(f.value)}
+		case *greaterThanEqualFilter:
+			encoded.filters[attribute] = &greaterThanEqualFilter{value: encode(f.value)}
+		case *betweenFilter:
+			encoded.filters[attribute] = &betweenFilter{
+				lowval:  encode(f.lowval),
+				highval: encode(f.highval),
+			}
+		}
+	}
+	return encoded, err
+}
+
+// topLevelNamePattern matches attribute name placeholders in a condition expression which are not
+// nested within a document path.
+var topLevelNamePattern = regexp.MustCompile(`(?:^|[^.\w#])(#\w+)`)
+
+// filterAttributes returns the top-level attributes referenced by the expression's conditions,
+// including conditions added with Filter, in order of attribute name.
+func (expr *Expression) filterAttributes() []string {
+	attributes := make([]string, 0, len(expr.filters))
+	for attribute := range expr.filters {
+		attributes = append(attributes, attribute)
+	}
+
+	for _, condition := range expr.additionalConditions {
+		built, err := expression.NewBuilder().WithCondition(condition).Build()
+		if err != nil {
+			// invalid conditions fail when the query input is constructed
+			continue
+		}
+		names := built.Names()
+		matches := topLevelNamePattern.FindAllStringSubmatch(aws.StringValue(built.Condition()), -1)
+		for _, match := range matches {
+			if name, found := names[match[1]]; found {
+				attributes = append(attributes, aws.StringValue(name))
+			}
+		}
+	}
+
+	attributes = uniqueStrings(attributes)
+	sort.Strings(attributes)
+	return attributes
+}
+
+// queryOptions contains query options which affect index selection and query construction.
+type queryOptions struct {
+	// countOnly specifies that the query only counts items, so no attributes are returned.
+	countOnly bool
+
+	// allowBackFetch specifies that attributes missing from the query index may be fetched from
+	// the table.
+	allowBackFetch bool
+
+	// clientSideOrdering specifies that items may be ordered after they are queried, so the query
+	// index does not need to sort on the order attribute.
+	clientSideOrdering bool
+}
+
+type orderSpec struct {
+	attribute string
+	ascending bool
+}
+
+// orders returns all sort attributes of the expression, starting with the OrderBy attribute.
+func (expr *Expression) orders() []orderSpec {
+	if !expr.orderSpecified {
+		return nil
+	}
+	primaryOrder := orderSpec{attribute: expr.orderAttribute, ascending: expr.orderAscending}
+	return append([]orderSpec{primaryOrder}, expr.thenByOrders...)
+}
+
 func (expr *Expression) constructQueryInputGivenIndex(
-	index *tableIndex) (*dynamodb.QueryInput, error) {
+	index *tableIndex, options queryOptions) (*dynamodb.QueryInput, error) {
 
 	dynamodbExprBuilder := expression.NewBuilder()
 
@@ -206,8 +352,16 @@ func (expr *Expression) constructQueryInputGivenIndex(
 	dynamodbExprBuilder = dynamodbExprBuilder.WithKeyCondition(kce)
 
 	// apply remaining filters as filter conditions
+	// filters are applied in order of attribute name so that equal expressions build equal inputs
+	filterAttributes := make([]string, 0, len(filters))
+	for key := range filters {
+		filterAttributes = append(filterAttributes, key)
+	}
+	sort.Strings(filterAttributes)
+
 	filterConditions := []expression.ConditionBuilder{}
-	for key, filter := range filters {
+	for _, key := range filterAttributes {
+		filter := filters[key]
 		var fc expression.ConditionBuilder
 		switch f := filter.(type) {
 		case *equalsFilter:
@@ -242,7 +396,7 @@ func (expr *Expression) constructQueryInputGivenIndex(
 	}
 
 	// set projection if specified
-	if expr.attributesSpecified {
+	if expr.attributesSpecified && !options.countOnly {
 		names := []expression.NameBuilder{}
 		for _, attribute := range expr.attributes {
 			names = append(names, expression.Name(attribute))
@@ -276,5 +430,9 @@ func (expr *Expression) constructQueryInputGivenIndex(
 		queryInput.ScanIndexForward = aws.Bool(expr.orderAscending)
 	}
 
+	if options.countOnly {
+		queryInput.Select = aws.String(dynamodb.SelectCount)
+	}
+
 	return queryInput, nil
 }
@@ -0,0 +1,553 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+const fakeTableName = "Items"
+
+// fakeService is an in-memory DynamoDB table with a string partition key "pk" and a number sort
+// key "sk". Queries ignore their conditions and return the table's items in sort key order,
+// pageSize items at a time or fewer when a limit is set. Consumed capacity is half a unit per
+// returned item. Writes support the simple condition and update expressions described by
+// checkCondition and applyUpdate.
+type fakeService struct {
+	dynamodbiface.DynamoDBAPI
+
+	mutex    sync.Mutex
+	items    []map[string]*dynamodb.AttributeValue
+	pageSize int
+
+	// indexes are described as the table's global secondary indexes, but queries on them return
+	// the table's items
+	indexes []*dynamodb.GlobalSecondaryIndexDescription
+
+	// block, if set, is received from before each call returns
+	block chan struct{}
+
+	// queryErrs are returned by the next query calls, in order, where nil errors do not fail
+	queryErrs []error
+
+	// writeErrs are returned by the next put, update or delete calls, in order, where nil errors
+	// do not fail
+	writeErrs []error
+
+	queryCalls    int
+	getCalls      int
+	batchGetCalls int
+	batchGetKeys  []int
+}
+
+// newFakeService creates a fake table with n items whose sort keys are 0 to n-1.
+func newFakeService(n, pageSize int) *fakeService {
+	svc := &fakeService{pageSize: pageSize}
+	for i := 0; i < n; i++ {
+		svc.items = append(svc.items, fakeItem(i, "v"+strconv.Itoa(i)))
+	}
+	return svc
+}
+
+func fakeKey(sk int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"pk": {S: aws.String("p")},
+		"sk": {N: aws.String(strconv.Itoa(sk))},
+	}
+}
+
+func fakeItem(sk int, value string) map[string]*dynamodb.AttributeValue {
+	item := fakeKey(sk)
+	item["value"] = &dynamodb.AttributeValue{S: aws.String(value)}
+	return item
+}
+
+func (svc *fakeService) wait(ctx context.Context) error {
+	if svc.block == nil {
+		return nil
+	}
+	select {
+	case <-svc.block:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// find returns the index of the item with the key's sort key, or -1 if it is not found.
+func (svc *fakeService) find(key map[string]*dynamodb.AttributeValue) int {
+	return findItem(svc.items, key)
+}
+
+func findItem(items []map[string]*dynamodb.AttributeValue,
+	key map[string]*dynamodb.AttributeValue) int {
+
+	sk, err := strconv.ParseFloat(aws.StringValue(key["sk"].N), 64)
+	if err != nil {
+		return -1
+	}
+	for i, item := range items {
+		if itemSK, _ := strconv.ParseFloat(*item["sk"].N, 64); itemSK == sk {
+			return i
+		}
+	}
+	return -1
+}
+
+func (svc *fakeService) DescribeTableWithContext(ctx aws.Context,
+	input *dynamodb.DescribeTableInput,
+	opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableName: aws.String(fakeTableName),
+		ItemCount: aws.Int64(int64(len(svc.items))),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("sk"), AttributeType: aws.String("N")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("sk"), KeyType: aws.String("RANGE")},
+		},
+		GlobalSecondaryIndexes: svc.indexes,
+	}}, nil
+}
+
+func (svc *fakeService) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput,
+	opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+	svc.mutex.Lock()
+	svc.queryCalls++
+	if len(svc.queryErrs) > 0 {
+		err := svc.queryErrs[0]
+		svc.queryErrs = svc.queryErrs[1:]
+		if err != nil {
+			svc.mutex.Unlock()
+			return nil, err
+		}
+	}
+	items := svc.items
+	if input.ScanIndexForward != nil && !*input.ScanIndexForward {
+		items = make([]map[string]*dynamodb.AttributeValue, 0, len(svc.items))
+		for i := len(svc.items) - 1; i >= 0; i-- {
+			items = append(items, svc.items[i])
+		}
+	}
+	start := 0
+	if input.ExclusiveStartKey != nil {
+		start = findItem(items, input.ExclusiveStartKey) + 1
+	}
+	pageSize := svc.pageSize
+	if input.Limit != nil && int(*input.Limit) < pageSize {
+		pageSize = int(*input.Limit)
+	}
+	end := start + pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	output := &dynamodb.QueryOutput{
+		Count:        aws.Int64(int64(end - start)),
+		ScannedCount: aws.Int64(int64(end - start)),
+	}
+	if capacity := aws.StringValue(input.ReturnConsumedCapacity); capacity != "" &&
+		capacity != dynamodb.ReturnConsumedCapacityNone {
+		output.ConsumedCapacity = &dynamodb.ConsumedCapacity{
+			TableName:     input.TableName,
+			CapacityUnits: aws.Float64(float64(end-start) / 2),
+		}
+	}
+	for _, item := range items[start:end] {
+		output.Items = append(output.Items, copyItem(item))
+	}
+	if end < len(items) {
+		output.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{
+			"pk": items[end-1]["pk"],
+			"sk": items[end-1]["sk"],
+		}
+	}
+	svc.mutex.Unlock()
+
+	if err := svc.wait(ctx); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (svc *fakeService) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput,
+	opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+
+	svc.mutex.Lock()
+	svc.getCalls++
+	if err := validateKey(input.Key); err != nil {
+		svc.mutex.Unlock()
+		return nil, err
+	}
+	output := &dynamodb.GetItemOutput{}
+	if i := svc.find(input.Key); i >= 0 {
+		output.Item = copyItem(svc.items[i])
+	}
+	svc.mutex.Unlock()
+
+	if err := svc.wait(ctx); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (svc *fakeService) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput,
+	opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	old, err := svc.checkWrite(input.Item, input.ConditionExpression,
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.store(input.Item)
+	return &dynamodb.PutItemOutput{
+		Attributes: returnedAttributes(input.ReturnValues, old, nil),
+	}, nil
+}
+
+func (svc *fakeService) UpdateItemWithContext(ctx aws.Context, input *dynamodb.UpdateItemInput,
+	opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	if err := validateKey(input.Key); err != nil {
+		return nil, err
+	}
+	old, err := svc.checkWrite(input.Key, input.ConditionExpression,
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := svc.update(input.Key, input.UpdateExpression,
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.UpdateItemOutput{
+		Attributes: returnedAttributes(input.ReturnValues, old, item),
+	}, nil
+}
+
+func (svc *fakeService) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput,
+	opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	if err := validateKey(input.Key); err != nil {
+		return nil, err
+	}
+	old, err := svc.checkWrite(input.Key, input.ConditionExpression,
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.remove(input.Key)
+	return &dynamodb.DeleteItemOutput{
+		Attributes: returnedAttributes(input.ReturnValues, old, nil),
+	}, nil
+}
+
+// checkWrite returns the item with the key, or nil if it is not found, and an error if the next
+// write error is set or the write's condition is not met. The mutex must be held.
+func (svc *fakeService) checkWrite(key map[string]*dynamodb.AttributeValue, condition *string,
+	names map[string]*string, values map[string]*dynamodb.AttributeValue) (
+	map[string]*dynamodb.AttributeValue, error) {
+
+	if len(svc.writeErrs) > 0 {
+		err := svc.writeErrs[0]
+		svc.writeErrs = svc.writeErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	item := svc.get(key)
+	return item, checkCondition(condition, names, values, item)
+}
+
+func (svc *fakeService) TransactWriteItemsWithContext(ctx aws.Context,
+	input *dynamodb.TransactWriteItemsInput,
+	opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	// every condition is checked before any item is written
+	canceled := false
+	reasons := []*dynamodb.CancellationReason{}
+	for _, item := range input.TransactItems {
+		key, condition, names, values := transactWriteCondition(item)
+		err := checkCondition(condition, names, values, svc.get(key))
+		reason := &dynamodb.CancellationReason{Code: aws.String("None")}
+		if err != nil {
+			canceled = true
+			reason = &dynamodb.CancellationReason{
+				Code:    aws.String("ConditionalCheckFailed"),
+				Message: aws.String(err.(awserr.Error).Message()),
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+	if canceled {
+		return nil, &dynamodb.TransactionCanceledException{
+			Message_:            aws.String("Transaction cancelled"),
+			CancellationReasons: reasons,
+		}
+	}
+
+	for _, item := range input.TransactItems {
+		switch {
+		case item.Put != nil:
+			svc.store(item.Put.Item)
+		case item.Update != nil:
+			_, err := svc.update(item.Update.Key, item.Update.UpdateExpression,
+				item.Update.ExpressionAttributeNames, item.Update.ExpressionAttributeValues)
+			if err != nil {
+				return nil, err
+			}
+		case item.Delete != nil:
+			svc.remove(item.Delete.Key)
+		}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// transactWriteCondition returns the key and condition expression of a transaction operation.
+func transactWriteCondition(item *dynamodb.TransactWriteItem) (
+	map[string]*dynamodb.AttributeValue, *string,
+	map[string]*string, map[string]*dynamodb.AttributeValue) {
+
+	switch {
+	case item.Put != nil:
+		return item.Put.Item, item.Put.ConditionExpression,
+			item.Put.ExpressionAttributeNames, item.Put.ExpressionAttributeValues
+	case item.Update != nil:
+		return item.Update.Key, item.Update.ConditionExpression,
+			item.Update.ExpressionAttributeNames, item.Update.ExpressionAttributeValues
+	case item.Delete != nil:
+		return item.Delete.Key, item.Delete.ConditionExpression,
+			item.Delete.ExpressionAttributeNames, item.Delete.ExpressionAttributeValues
+	}
+	check := item.ConditionCheck
+	return check.Key, check.ConditionExpression,
+		check.ExpressionAttributeNames, check.ExpressionAttributeValues
+}
+
+func (svc *fakeService) TransactGetItemsWithContext(ctx aws.Context,
+	input *dynamodb.TransactGetItemsInput,
+	opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	output := &dynamodb.TransactGetItemsOutput{}
+	for _, item := range input.TransactItems {
+		output.Responses = append(output.Responses,
+			&dynamodb.ItemResponse{Item: copyItem(svc.get(item.Get.Key))})
+	}
+	return output, nil
+}
+
+// get returns the item with the key, or nil if it is not found. The mutex must be held.
+func (svc *fakeService) get(
+	key map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+
+	if i := svc.find(key); i >= 0 {
+		return svc.items[i]
+	}
+	return nil
+}
+
+// store puts a copy of an item in the table. The mutex must be held.
+func (svc *fakeService) store(item map[string]*dynamodb.AttributeValue) {
+	if i := svc.find(item); i >= 0 {
+		svc.items[i] = copyItem(item)
+	} else {
+		svc.items = append(svc.items, copyItem(item))
+	}
+}
+
+// update applies an update expression to the item with the key, which is created if it does not
+// exist, and returns the updated item. The mutex must be held.
+func (svc *fakeService) update(key map[string]*dynamodb.AttributeValue, update *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+
+	item := copyItem(key)
+	if existing := svc.get(key); existing != nil {
+		item = copyItem(existing)
+	}
+	if err := applyUpdate(aws.StringValue(update), names, values, item); err != nil {
+		return nil, err
+	}
+	svc.store(item)
+	return item, nil
+}
+
+// remove deletes the item with the key from the table, if it exists. The mutex must be held.
+func (svc *fakeService) remove(key map[string]*dynamodb.AttributeValue) {
+	if i := svc.find(key); i >= 0 {
+		svc.items = append(svc.items[:i:i], svc.items[i+1:]...)
+	}
+}
+
+// validateKey returns a validation error if key has attributes other than the table's keys.
+func validateKey(key map[string]*dynamodb.AttributeValue) error {
+	if len(key) != 2 || key["pk"] == nil || key["sk"] == nil {
+		return awserr.New("ValidationException",
+			"The provided key element does not match the schema", nil)
+	}
+	return nil
+}
+
+func conditionalCheckFailed() error {
+	return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException,
+		"The conditional request failed", nil)
+}
+
+// checkCondition evaluates a condition expression on an existing item, which is nil if the item
+// does not exist. Only equality comparisons between an attribute and a value and the
+// attribute_exists and attribute_not_exists functions, combined with AND, are supported.
+func checkCondition(condition *string, names map[string]*string,
+	values map[string]*dynamodb.AttributeValue, item map[string]*dynamodb.AttributeValue) error {
+
+	if condition == nil {
+		return nil
+	}
+	for _, term := range strings.Split(*condition, " AND ") {
+		if strings.HasPrefix(term, "(") && strings.HasSuffix(term, ")") {
+			term = term[1 : len(term)-1]
+		}
+
+		var met bool
+		if name := strings.TrimPrefix(term, "attribute_exists ("); name != term {
+			_, met = item[aws.StringValue(names[strings.TrimSuffix(name, ")")])]
+		} else if name := strings.TrimPrefix(term, "attribute_not_exists ("); name != term {
+			_, exists := item[aws.StringValue(names[strings.TrimSuffix(name, ")")])]
+			met = !exists
+		} else if operands := strings.Split(term, " = "); len(operands) == 2 {
+			actual := item[aws.StringValue(names[operands[0]])]
+			expected := values[operands[1]]
+			met = actual != nil &&
+				attributeValueTypeRank(actual) == attributeValueTypeRank(expected) &&
+				compareAttributeValues(actual, expected) == 0
+		} else {
+			return fmt.Errorf("unsupported condition: %s", term)
+		}
+
+		if !met {
+			return conditionalCheckFailed()
+		}
+	}
+	return nil
+}
+
+// applyUpdate applies an update expression to an item. Only SET actions which assign values,
+// REMOVE actions and ADD actions on numbers are supported.
+func applyUpdate(update string, names map[string]*string,
+	values map[string]*dynamodb.AttributeValue, item map[string]*dynamodb.AttributeValue) error {
+
+	for _, clause := range strings.Split(strings.TrimSpace(update), "\n") {
+		fields := strings.SplitN(clause, " ", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("unsupported update: %s", clause)
+		}
+		for _, action := range strings.Split(fields[1], ", ") {
+			switch fields[0] {
+			case "SET":
+				operands := strings.Split(action, " = ")
+				if len(operands) != 2 || values[operands[1]] == nil {
+					return fmt.Errorf("unsupported set action: %s", action)
+				}
+				item[aws.StringValue(names[operands[0]])] = values[operands[1]]
+			case "REMOVE":
+				delete(item, aws.StringValue(names[action]))
+			case "ADD":
+				operands := strings.Split(action, " ")
+				if len(operands) != 2 || values[operands[1]] == nil ||
+					values[operands[1]].N == nil {
+					return fmt.Errorf("unsupported add action: %s", action)
+				}
+				name := aws.StringValue(names[operands[0]])
+				sum, _ := strconv.ParseFloat(*values[operands[1]].N, 64)
+				if current := item[name]; current != nil {
+					n, _ := strconv.ParseFloat(aws.StringValue(current.N), 64)
+					sum += n
+				}
+				item[name] = &dynamodb.AttributeValue{
+					N: aws.String(strconv.FormatFloat(sum, 'f', -1, 64)),
+				}
+			default:
+				return fmt.Errorf("unsupported update: %s", clause)
+			}
+		}
+	}
+	return nil
+}
+
+// returnedAttributes returns the attributes of a write for its ReturnValues parameter.
+func returnedAttributes(returnValues *string,
+	old, new map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+
+	switch aws.StringValue(returnValues) {
+	case dynamodb.ReturnValueAllOld:
+		return copyItem(old)
+	case dynamodb.ReturnValueAllNew:
+		return copyItem(new)
+	}
+	return nil
+}
+
+func (svc *fakeService) BatchGetItemWithContext(ctx aws.Context,
+	input *dynamodb.BatchGetItemInput,
+	opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+
+	svc.mutex.Lock()
+	svc.batchGetCalls++
+	output := &dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]*dynamodb.AttributeValue{},
+	}
+	for tableName, keysAndAttributes := range input.RequestItems {
+		svc.batchGetKeys = append(svc.batchGetKeys, len(keysAndAttributes.Keys))
+		for _, key := range keysAndAttributes.Keys {
+			if i := svc.find(key); i >= 0 {
+				output.Responses[tableName] = append(output.Responses[tableName],
+					copyItem(svc.items[i]))
+			}
+		}
+	}
+	svc.mutex.Unlock()
+
+	if err := svc.wait(ctx); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (svc *fakeService) calls() (queryCalls, getCalls, batchGetCalls int) {
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+	return svc.queryCalls, svc.getCalls, svc.batchGetCalls
+}
@@ -0,0 +1,275 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Operation names passed to interceptors for each DynamoDB call made by a Client.
+// OperationSelectIndex is not a DynamoDB call; it wraps the index selection for a query.
+// OperationCompleteQuery is not a DynamoDB call either; it is invoked once when a Parser completes
+// its query.
+const (
+	OperationSelectIndex    = "SelectIndex"
+	OperationCompleteQuery  = "CompleteQuery"
+	OperationDescribeTable  = "DescribeTable"
+	OperationQuery          = "Query"
+	OperationGetItem        = "GetItem"
+	OperationPutItem        = "PutItem"
+	OperationUpdateItem     = "UpdateItem"
+	OperationDeleteItem     = "DeleteItem"
+	OperationBatchGetItem   = "BatchGetItem"
+	OperationBatchWriteItem = "BatchWriteItem"
+	OperationTransactWrite  = "TransactWriteItems"
+	OperationTransactGet    = "TransactGetItems"
+)
+
+// Handler executes a DynamoDB operation. The input is the operation's input type from the
+// dynamodb package, such as *dynamodb.QueryInput, and the output is the corresponding output
+// type, such as *dynamodb.QueryOutput. For OperationSelectIndex, the input is an
+// *IndexSelectionInput and the output is an *IndexSelectionOutput. For OperationCompleteQuery,
+// the input is a *QueryCompletionInput and the output is nil.
+type Handler func(ctx context.Context, input interface{}) (interface{}, error)
+
+// Interceptor wraps a DynamoDB call made by a Client. The operation is one of the Operation
+// constants and input is the operation's input. The interceptor should call next to continue the
+// call chain and may observe or modify the input, output and error. An interceptor may also
+// return without calling next in order to short-circuit the call.
+//
+// Inputs passed to next and outputs returned from the interceptor must retain the types of the
+// original operation. An interceptor which returns without an error must return a non-nil
+// output, otherwise the call fails with an ErrUnexpectedOutput error.
+type Interceptor func(
+	ctx context.Context, operation string, input interface{}, next Handler) (interface{}, error)
+
+// IndexSelectionInput is the input to OperationSelectIndex.
+type IndexSelectionInput struct {
+	TableName  string
+	Expression *Expression
+
+	// CountOnly is true if the index is selected for a count query, in which case the index
+	// only needs to project the expression's filter attributes.
+	CountOnly bool
+
+	// AllowBackFetch is true if indexes which do not project all selected attributes may be
+	// selected, in which case the missing attributes are fetched from the table. Filter
+	// attributes must still be projected.
+	AllowBackFetch bool
+
+	// ClientSideOrdering is true if items may be ordered after they are queried, in which case
+	// the selected index does not need to sort on the expression's order attribute.
+	ClientSideOrdering bool
+}
+
+func (input *IndexSelectionInput) queryOptions() queryOptions {
+	return queryOptions{
+		countOnly:          input.CountOnly,
+		allowBackFetch:     input.AllowBackFetch,
+		clientSideOrdering: input.ClientSideOrdering,
+	}
+}
+
+// IndexSelectionOutput is the output of OperationSelectIndex.
+type IndexSelectionOutput struct {
+	// IndexName is the name of the selected index. If the table's primary index is selected,
+	// IndexName is empty.
+	IndexName string
+
+	// Score is the score of the selected index against the expression. Higher scores are
+	// preferred.
+	Score float64
+}
+
+// QueryCompletionInput is the input to OperationCompleteQuery.
+type QueryCompletionInput struct {
+	TableName string
+
+	// Stats contains the cumulative statistics of the completed query.
+	Stats ParserStats
+}
+
+type pageContextKey struct{}
+
+// PageFromContext returns the page number of a Query call made by Parser.Next, starting from 1.
+// The page number is available in the context passed to interceptors for OperationQuery.
+func PageFromContext(ctx context.Context) (int, bool) {
+	page, ok := ctx.Value(pageContextKey{}).(int)
+	return page, ok
+}
+
+func contextWithPage(ctx context.Context, page int) context.Context {
+	return context.WithValue(ctx, pageContextKey{}, page)
+}
+
+// Use appends interceptors to the client's interceptor chain. Interceptors are invoked in the
+// order they are added, so the first interceptor added is the outermost in the chain.
+//
+// Interceptors apply to DescribeTable calls only when the client uses the default metadata
+// provider created by NewClient. Use should be called before the client is used to make any
+// calls to DynamoDB.
+func (client *Client) Use(interceptors ...Interceptor) *Client {
+	client.interceptors = append(client.interceptors, interceptors...)
+	return client
+}
+
+func (client *Client) invoke(ctx context.Context, operation string, input interface{},
+	handler Handler) (interface{}, error) {
+
+	// wrap handler from innermost to outermost interceptor
+	for i := len(client.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := client.interceptors[i], handler
+		handler = func(ctx context.Context, input interface{}) (interface{}, error) {
+			return interceptor(ctx, operation, input, next)
+		}
+	}
+
+	return handler(ctx, input)
+}
+
+// outputError returns err, or an ErrUnexpectedOutput error if the operation's interceptors
+// returned without an error and without a valid output.
+func outputError(operation string, output interface{}, valid bool, err error) error {
+	if err == nil && !valid {
+		return &ErrUnexpectedOutput{Operation: operation, Output: output}
+	}
+	return err
+}
+
+// interceptedService routes DynamoDB calls made by a Client through its interceptor chain.
+type interceptedService struct {
+	dynamodbiface.DynamoDBAPI
+	client *Client
+}
+
+func (s *interceptedService) DescribeTableWithContext(
+	ctx aws.Context, input *dynamodb.DescribeTableInput,
+	opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationDescribeTable, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.DescribeTableWithContext(
+				ctx, input.(*dynamodb.DescribeTableInput), opts...)
+		})
+	describeTableOutput, ok := output.(*dynamodb.DescribeTableOutput)
+	return describeTableOutput, outputError(OperationDescribeTable, output,
+		ok && describeTableOutput != nil, err)
+}
+
+func (s *interceptedService) QueryWithContext(ctx aws.Context,
+	input *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationQuery, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.QueryWithContext(ctx, input.(*dynamodb.QueryInput), opts...)
+		})
+	queryOutput, ok := output.(*dynamodb.QueryOutput)
+	return queryOutput, outputError(OperationQuery, output, ok && queryOutput != nil, err)
+}
+
+func (s *interceptedService) GetItemWithContext(ctx aws.Context,
+	input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationGetItem, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.GetItemWithContext(ctx, input.(*dynamodb.GetItemInput), opts...)
+		})
+	getItemOutput, ok := output.(*dynamodb.GetItemOutput)
+	return getItemOutput, outputError(OperationGetItem, output, ok && getItemOutput != nil, err)
+}
+
+func (s *interceptedService) PutItemWithContext(ctx aws.Context,
+	input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationPutItem, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.PutItemWithContext(ctx, input.(*dynamodb.PutItemInput), opts...)
+		})
+	putItemOutput, ok := output.(*dynamodb.PutItemOutput)
+	return putItemOutput, outputError(OperationPutItem, output, ok && putItemOutput != nil, err)
+}
+
+func (s *interceptedService) BatchGetItemWithContext(
+	ctx aws.Context, input *dynamodb.BatchGetItemInput,
+	opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationBatchGetItem, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.BatchGetItemWithContext(
+				ctx, input.(*dynamodb.BatchGetItemInput), opts...)
+		})
+	batchGetItemOutput, ok := output.(*dynamodb.BatchGetItemOutput)
+	return batchGetItemOutput, outputError(OperationBatchGetItem, output,
+		ok && batchGetItemOutput != nil, err)
+}
+
+func (s *interceptedService) UpdateItemWithContext(ctx aws.Context,
+	input *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationUpdateItem, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.UpdateItemWithContext(
+				ctx, input.(*dynamodb.UpdateItemInput), opts...)
+		})
+	updateItemOutput, ok := output.(*dynamodb.UpdateItemOutput)
+	return updateItemOutput, outputError(OperationUpdateItem, output,
+		ok && updateItemOutput != nil, err)
+}
+
+func (s *interceptedService) DeleteItemWithContext(ctx aws.Context,
+	input *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationDeleteItem, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.DeleteItemWithContext(
+				ctx, input.(*dynamodb.DeleteItemInput), opts...)
+		})
+	deleteItemOutput, ok := output.(*dynamodb.DeleteItemOutput)
+	return deleteItemOutput, outputError(OperationDeleteItem, output,
+		ok && deleteItemOutput != nil, err)
+}
+
+func (s *interceptedService) BatchWriteItemWithContext(
+	ctx aws.Context, input *dynamodb.BatchWriteItemInput,
+	opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationBatchWriteItem, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.BatchWriteItemWithContext(
+				ctx, input.(*dynamodb.BatchWriteItemInput), opts...)
+		})
+	batchWriteItemOutput, ok := output.(*dynamodb.BatchWriteItemOutput)
+	return batchWriteItemOutput, outputError(OperationBatchWriteItem, output,
+		ok && batchWriteItemOutput != nil, err)
+}
+
+func (s *interceptedService) TransactWriteItemsWithContext(
+	ctx aws.Context, input *dynamodb.TransactWriteItemsInput,
+	opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationTransactWrite, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.TransactWriteItemsWithContext(
+				ctx, input.(*dynamodb.TransactWriteItemsInput), opts...)
+		})
+	transactWriteItemsOutput, ok := output.(*dynamodb.TransactWriteItemsOutput)
+	return transactWriteItemsOutput, outputError(OperationTransactWrite, output,
+		ok && transactWriteItemsOutput != nil, err)
+}
+
+func (s *interceptedService) TransactGetItemsWithContext(
+	ctx aws.Context, input *dynamodb.TransactGetItemsInput,
+	opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+
+	output, err := s.client.invoke(ctx, OperationTransactGet, input,
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return s.DynamoDBAPI.TransactGetItemsWithContext(
+				ctx, input.(*dynamodb.TransactGetItemsInput), opts...)
+		})
+	transactGetItemsOutput, ok := output.(*dynamodb.TransactGetItemsOutput)
+	return transactGetItemsOutput, outputError(OperationTransactGet, output,
+		ok && transactGetItemsOutput != nil, err)
+}
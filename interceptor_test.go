@@ -0,0 +1,130 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// shortCircuit returns an interceptor which returns output for operation without calling next.
+func shortCircuit(operation string, output interface{}) Interceptor {
+	return func(ctx context.Context, op string, input interface{},
+		next Handler) (interface{}, error) {
+
+		if op == operation {
+			return output, nil
+		}
+		return next(ctx, input)
+	}
+}
+
+func expectUnexpectedOutput(t *testing.T, err error, operation string) {
+	t.Helper()
+	unexpectedErr, ok := err.(*ErrUnexpectedOutput)
+	if !ok {
+		t.Fatalf("expected ErrUnexpectedOutput, found %v", err)
+	}
+	if unexpectedErr.Operation != operation {
+		t.Errorf("expected operation %s, found %s", operation, unexpectedErr.Operation)
+	}
+}
+
+func TestInterceptorShortCircuitsQuery(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(5, 5)
+	client := NewClient(svc).Use(shortCircuit(OperationQuery, &dynamodb.QueryOutput{
+		Items: []map[string]*dynamodb.AttributeValue{fakeItem(7, "cached")},
+		Count: aws.Int64(1),
+	}))
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	item := testItem{}
+	if err := parser.Next(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.SK != 7 || item.Value != "cached" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+	if queryCalls, _, _ := svc.calls(); queryCalls != 0 {
+		t.Errorf("expected no query calls, found %d", queryCalls)
+	}
+}
+
+func TestInterceptorUnexpectedOutputs(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		operation string
+		output    interface{}
+		call      func(client *Client) error
+	}{
+		{
+			name:      "nil query output",
+			operation: OperationQuery,
+			call: func(client *Client) error {
+				_, err := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+					NextRaw(ctx)
+				return err
+			},
+		},
+		{
+			name:      "typed nil query output",
+			operation: OperationQuery,
+			output:    (*dynamodb.QueryOutput)(nil),
+			call: func(client *Client) error {
+				_, err := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+					NextRaw(ctx)
+				return err
+			},
+		},
+		{
+			name:      "prefetched query output of wrong type",
+			operation: OperationQuery,
+			output:    &dynamodb.GetItemOutput{},
+			call: func(client *Client) error {
+				parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+					SetPrefetchDepth(2)
+				defer parser.Close()
+				_, err := parser.NextRaw(ctx)
+				return err
+			},
+		},
+		{
+			name:      "get item output of wrong type",
+			operation: OperationGetItem,
+			output:    &dynamodb.QueryOutput{},
+			call: func(client *Client) error {
+				return client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 1}, &testItem{})
+			},
+		},
+		{
+			name:      "nil describe table output",
+			operation: OperationDescribeTable,
+			call: func(client *Client) error {
+				_, err := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+					NextRaw(ctx)
+				return err
+			},
+		},
+		{
+			name:      "nil index selection output",
+			operation: OperationSelectIndex,
+			call: func(client *Client) error {
+				_, err := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+					NextRaw(ctx)
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewClient(newFakeService(5, 2)).
+				Use(shortCircuit(test.operation, test.output))
+			expectUnexpectedOutput(t, test.call(client), test.operation)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// itemTypeKey identifies a struct type registered on a table.
+type itemTypeKey struct {
+	tableName string
+	itemType  reflect.Type
+}
+
+// RegisterType registers the struct type of item with the table, so that full items of the type
+// may be passed as keys to Get, Delete and Update. The table's key attributes are extracted from
+// such items using the table's metadata, and all other attributes are ignored.
+//
+// The struct type is validated against the key schema of the table and its secondary indexes. If
+// the struct does not have fields for all key attributes, an *ErrMissingKeyAttributes error is
+// returned and the type is not registered.
+//
+// Attribute names are read from the "dynamodbav" struct tags of the type. If the client has an
+// Encoder, the attribute names are instead those of the type's zero value encoded with the
+// Encoder, so key attributes must be encoded even when their fields are empty.
+func (table Table) RegisterType(ctx context.Context, item interface{}) error {
+	itemType := structType(item)
+	if itemType == nil {
+		return fmt.Errorf("registered item must be a struct, got %T", item)
+	}
+
+	indexMetadata, err := table.autoqueryClient.pullIndexMetadata(ctx, table.name)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := table.itemAttributeNames(itemType)
+	if err != nil {
+		return err
+	}
+	missing := []string{}
+	for _, index := range indexMetadata.Indexes {
+		for _, key := range index.getKeys() {
+			if !containsString(attributes, key) {
+				missing = append(missing, key)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return &ErrMissingKeyAttributes{
+			TableName:  table.name,
+			TypeName:   itemType.String(),
+			Attributes: uniqueStrings(missing),
+		}
+	}
+
+	client := table.autoqueryClient
+	client.itemTypesMutex.Lock()
+	client.itemTypes[itemTypeKey{tableName: table.name, itemType: itemType}] = struct{}{}
+	client.itemTypesMutex.Unlock()
+
+	return nil
+}
+
+// Load retrieves a single item using the key attributes of the item itself, which should be a
+// pointer to a struct. The table's key attributes are extracted from the item using the table's
+// metadata, and the retrieved item is unmarshaled into item.
+//
+// If the item is not found, ErrItemNotFound is returned.
+func (table Table) Load(ctx context.Context, item interface{}) error {
+	key, err := table.extractKey(ctx, item)
+	if err != nil {
+		return err
+	}
+
+	return table.autoqueryClient.getItem(ctx, table.name, key, item)
+}
+
+// marshalKey marshals itemKey into the key of an item. If the type of itemKey is registered with
+// the table, only the table's key attributes are kept.
+func (table Table) marshalKey(
+	ctx context.Context, itemKey interface{}) (map[string]*dynamodb.AttributeValue, error) {
+
+	if table.isRegisteredType(itemKey) ||
+		table.autoqueryClient.entityForType(table.name, itemKey) != nil {
+		return table.extractKey(ctx, itemKey)
+	}
+	return encodeItem(table.autoqueryClient.Encoder, itemKey)
+}
+
+// marshalItem marshals item, including its composite key attributes if its type is a registered
+// entity of the table.
+func (table Table) marshalItem(item interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	tableItem, err := encodeItem(table.autoqueryClient.Encoder, item)
+	if err != nil {
+		return nil, err
+	}
+
+	if entity := table.autoqueryClient.entityForType(table.name, item); entity != nil {
+		keys, err := entity.EncodeKeys(item)
+		if err != nil {
+			return nil, err
+		}
+		for attribute, value := range keys {
+			tableItem[attribute] = value
+		}
+	}
+
+	return tableItem, nil
+}
+
+// extractKey marshals item and returns only the attributes of the table's primary key.
+func (table Table) extractKey(
+	ctx context.Context, item interface{}) (map[string]*dynamodb.AttributeValue, error) {
+
+	indexMetadata, err := table.autoqueryClient.pullIndexMetadata(ctx, table.name)
+	if err != nil {
+		return nil, err
+	}
+	primaryIndex := indexMetadata.primaryIndex()
+	if primaryIndex == nil {
+		return nil, fmt.Errorf("no primary key found for table %s", table.name)
+	}
+
+	tableItem, err := table.marshalItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	key := map[string]*dynamodb.AttributeValue{}
+	for _, keyAttribute := range primaryIndex.getKeys() {
+		value, found := tableItem[keyAttribute]
+		if !found {
+			return nil, fmt.Errorf("item is missing key attribute %s for table %s",
+				keyAttribute, table.name)
+		}
+		key[keyAttribute] = value
+	}
+
+	return key, nil
+}
+
+func (table Table) isRegisteredType(item interface{}) bool {
+	itemType := structType(item)
+	if itemType == nil {
+		return false
+	}
+
+	client := table.autoqueryClient
+	client.itemTypesMutex.RLock()
+	defer client.itemTypesMutex.RUnlock()
+	_, found := client.itemTypes[itemTypeKey{tableName: table.name, itemType: itemType}]
+	return found
+}
+
+// structType returns the struct type of item, dereferencing pointers, or nil if item is not a
+// struct.
+func structType(item interface{}) reflect.Type {
+	if item == nil {
+		return nil
+	}
+	itemType := reflect.TypeOf(item)
+	for itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	if itemType.Kind() != reflect.Struct {
+		return nil
+	}
+	return itemType
+}
+
+// itemAttributeNames returns the attribute names of items of a struct type, as encoded by the
+// client's Encoder if it is set.
+func (table Table) itemAttributeNames(itemType reflect.Type) ([]string, error) {
+	encoder := table.autoqueryClient.Encoder
+	if encoder == nil {
+		return structAttributeNames(itemType), nil
+	}
+
+	item, err := encoder.Encode(reflect.New(itemType).Interface())
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range item {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// structAttributeNames returns the DynamoDB attribute names of the fields of a struct type.
+func structAttributeNames(itemType reflect.Type) []string {
+	names := []string{}
+	for _, field := range structFields(itemType) {
+		names = append(names, attributeName(field))
+	}
+	return names
+}
+
+// structFields returns the fields of a struct type which are marshaled as attributes. Fields of
+// embedded structs without a dynamodbav name are included, as they are when marshaled.
+func structFields(itemType reflect.Type) []reflect.StructField {
+	fields := []reflect.StructField{}
+	for i := 0; i < itemType.NumField(); i++ {
+		field := itemType.Field(i)
+		tagName := strings.Split(field.Tag.Get("dynamodbav"), ",")[0]
+		if tagName == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && tagName == "" && fieldType.Kind() == reflect.Struct {
+			fields = append(fields, structFields(fieldType)...)
+			continue
+		}
+
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -0,0 +1,138 @@
+package autoquery
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func TestRegisterType(t *testing.T) {
+	ctx := context.Background()
+	table := NewClient(newFakeService(3, 2)).Table(fakeTableName)
+
+	// full items may not be used as keys until their type is registered
+	item := testItem{PK: "p", SK: 1, Value: "ignored"}
+	if err := table.Get(ctx, item, &testItem{}); err == nil {
+		t.Error("expected an error for a full item of an unregistered type")
+	}
+
+	if err := table.RegisterType(ctx, &testItem{}); err != nil {
+		t.Fatal(err)
+	}
+	found := testItem{}
+	if err := table.Get(ctx, item, &found); err != nil {
+		t.Fatal(err)
+	}
+	if found.Value != "v1" {
+		t.Errorf("unexpected item: %+v", found)
+	}
+	if err := table.Delete(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	err := table.Get(ctx, testKey{PK: "p", SK: 1}, &testItem{})
+	if _, notFound := err.(*ErrItemNotFound); !notFound {
+		t.Errorf("expected ErrItemNotFound, found %v", err)
+	}
+}
+
+func TestRegisterTypeMissingKeyAttributes(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(0, 2)
+	svc.indexes = []*dynamodb.GlobalSecondaryIndexDescription{{
+		IndexName: aws.String("group-index"),
+		ItemCount: aws.Int64(0),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("group"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("value"), KeyType: aws.String("RANGE")},
+		},
+		Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+	}}
+	table := NewClient(svc).Table(fakeTableName)
+
+	err := table.RegisterType(ctx, testItem{})
+	missingErr, ok := err.(*ErrMissingKeyAttributes)
+	if !ok {
+		t.Fatalf("expected ErrMissingKeyAttributes, found %v", err)
+	}
+	if strings.Join(missingErr.Attributes, ",") != "group" {
+		t.Errorf("expected missing attribute group, found %v", missingErr.Attributes)
+	}
+	if table.isRegisteredType(testItem{}) {
+		t.Error("expected the type not to be registered")
+	}
+
+	if err := table.RegisterType(ctx, "item"); err == nil {
+		t.Error("expected an error for a type which is not a struct")
+	}
+}
+
+func TestRegisterTypeWithEncoder(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(3, 2))
+	client.Encoder = newJSONEncoder()
+	table := client.Table(fakeTableName)
+
+	// attribute names are those encoded by the client's encoder
+	if err := table.RegisterType(ctx, jsonItem{}); err != nil {
+		t.Fatal(err)
+	}
+	client.Decoder = newJSONDecoder()
+	found := jsonItem{}
+	if err := table.Get(ctx, jsonItem{PK: "p", SK: 2, Value: "ignored"}, &found); err != nil {
+		t.Fatal(err)
+	}
+	if found.Value != "v2" {
+		t.Errorf("unexpected item: %+v", found)
+	}
+
+	// struct tags are ignored when the encoder names attributes differently
+	client.Encoder = EncoderFunc(func(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+		item, err := dynamodbattribute.MarshalMap(in)
+		renamed := map[string]*dynamodb.AttributeValue{}
+		for name, value := range item {
+			renamed[strings.ToUpper(name)] = value
+		}
+		return renamed, err
+	})
+	err := table.RegisterType(ctx, testItem{})
+	if _, missing := err.(*ErrMissingKeyAttributes); !missing {
+		t.Errorf("expected ErrMissingKeyAttributes, found %v", err)
+	}
+}
+
+func TestExtractKey(t *testing.T) {
+	ctx := context.Background()
+	table := NewClient(newFakeService(0, 2)).Table(fakeTableName)
+
+	key, err := table.extractKey(ctx, &testItem{PK: "p", SK: 3, Value: "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 2 || aws.StringValue(key["pk"].S) != "p" || aws.StringValue(key["sk"].N) != "3" {
+		t.Errorf("expected only the table's key attributes, found %v", key)
+	}
+
+	partial := struct {
+		PK string `dynamodbav:"pk"`
+	}{"p"}
+	if _, err := table.extractKey(ctx, partial); err == nil {
+		t.Error("expected an error for an item without the sort key")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	table := NewClient(newFakeService(3, 2)).Table(fakeTableName)
+
+	item := testItem{PK: "p", SK: 2}
+	if err := table.Load(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Value != "v2" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+}
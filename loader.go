@@ -0,0 +1,205 @@
+package autoquery
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Loader batches Get calls on a table. Keys requested through a loader are collected for a short
+// window, or until the maximum batch size is reached, and are then retrieved together with a
+// single BatchGetItem call. Each caller receives its own item, or ErrItemNotFound if its item does
+// not exist.
+//
+// A Loader may be used concurrently, such as by GraphQL resolvers which would otherwise make a
+// separate Get call for each resolved item.
+type Loader struct {
+	table        Table
+	wait         time.Duration
+	maxBatchSize int
+
+	mutex   sync.Mutex
+	pending *loaderBatch
+}
+
+type loaderBatch struct {
+	ctx           context.Context
+	keys          []map[string]*dynamodb.AttributeValue
+	keyIDs        map[string]struct{}
+	keyAttributes []string
+	dispatched    bool
+
+	done  chan struct{}
+	items map[string]map[string]*dynamodb.AttributeValue
+	err   error
+}
+
+// NewLoader creates a loader which batches Get calls on a table. The first key of each batch
+// waits up to wait for other keys before the batch is requested. See Loader.
+func (client *Client) NewLoader(tableName string, wait time.Duration) *Loader {
+	return newLoader(Table{autoqueryClient: client, name: tableName}, wait)
+}
+
+// NewLoader creates a loader which batches Get calls on the table. Keys of types registered with
+// RegisterType or RegisterEntity may be full items, as with Table.Get. See Loader.
+func (table Table) NewLoader(wait time.Duration) *Loader {
+	return newLoader(table, wait)
+}
+
+func newLoader(table Table, wait time.Duration) *Loader {
+	return &Loader{
+		table:        table,
+		wait:         wait,
+		maxBatchSize: maxBatchGetKeys,
+	}
+}
+
+// SetMaxBatchSize sets the maximum number of keys in a batch. A batch is requested immediately
+// once it reaches the maximum size. The maximum batch size may not exceed 100, which is the
+// maximum number of keys in a BatchGetItem call, and is 100 by default.
+func (loader *Loader) SetMaxBatchSize(maxBatchSize int) *Loader {
+	if maxBatchSize <= 0 || maxBatchSize > maxBatchGetKeys {
+		maxBatchSize = maxBatchGetKeys
+	}
+	loader.maxBatchSize = maxBatchSize
+	return loader
+}
+
+// Get retrieves a single item by its key, in the same way as Client.Get, by adding the key to the
+// loader's next batch. Get blocks until the batch is retrieved or until ctx is done.
+//
+// If the item is not found, ErrItemNotFound is returned. If the batch fails, its error is returned
+// to every caller in the batch.
+func (loader *Loader) Get(ctx context.Context, itemKey, returnItem interface{}) error {
+	client := loader.table.autoqueryClient
+	tableName := loader.table.name
+
+	key, err := loader.table.marshalKey(ctx, itemKey)
+	if err != nil {
+		return err
+	}
+
+	if client.Cache != nil {
+		cacheKey, err := itemCacheKey(tableName, key)
+		if err != nil {
+			return err
+		}
+		if cached, found := client.getCachedItem(cacheKey); found {
+			if !cached.Found {
+				return &ErrItemNotFound{}
+			}
+			return client.decodeTableItem(tableName, client.Decoder, cached.Item, returnItem)
+		}
+	}
+
+	batch, id := loader.add(ctx, key)
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if batch.err != nil {
+		return batch.err
+	}
+	item, found := batch.items[id]
+	if !found {
+		return &ErrItemNotFound{}
+	}
+	return client.decodeTableItem(tableName, client.Decoder, copyItem(item), returnItem)
+}
+
+// add adds key to the pending batch, starting a new batch if needed, and returns the batch and
+// the key's ID within the batch.
+func (loader *Loader) add(
+	ctx context.Context, key map[string]*dynamodb.AttributeValue) (*loaderBatch, string) {
+
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	batch := loader.pending
+	if batch == nil {
+		keyAttributes := make([]string, 0, len(key))
+		for attribute := range key {
+			keyAttributes = append(keyAttributes, attribute)
+		}
+		sort.Strings(keyAttributes)
+
+		batch = &loaderBatch{
+			// the batch carries the values of the first caller's context, but is not canceled
+			// when any single caller's context is done
+			ctx:           detachedContext{parent: ctx},
+			keyIDs:        map[string]struct{}{},
+			keyAttributes: keyAttributes,
+			done:          make(chan struct{}),
+		}
+		loader.pending = batch
+		time.AfterFunc(loader.wait, func() { loader.dispatch(batch) })
+	}
+
+	// duplicate keys are not allowed in a single BatchGetItem call
+	id := keyID(key, batch.keyAttributes)
+	if _, found := batch.keyIDs[id]; !found {
+		batch.keyIDs[id] = struct{}{}
+		batch.keys = append(batch.keys, key)
+	}
+
+	if len(batch.keys) >= loader.maxBatchSize {
+		loader.pending = nil
+		batch.dispatched = true
+		go loader.fetch(batch)
+	}
+
+	return batch, id
+}
+
+// dispatch requests the batch when its wait window ends, unless it was already requested because
+// it reached the maximum batch size.
+func (loader *Loader) dispatch(batch *loaderBatch) {
+	loader.mutex.Lock()
+	if batch.dispatched {
+		loader.mutex.Unlock()
+		return
+	}
+	if loader.pending == batch {
+		loader.pending = nil
+	}
+	batch.dispatched = true
+	loader.mutex.Unlock()
+
+	loader.fetch(batch)
+}
+
+func (loader *Loader) fetch(batch *loaderBatch) {
+	client := loader.table.autoqueryClient
+	tableName := loader.table.name
+
+	// reads are registered before the call, so items written during the call are not cached
+	reads := make([]*itemRead, len(batch.keys))
+	if client.Cache != nil {
+		for i, key := range batch.keys {
+			if cacheKey, err := itemCacheKey(tableName, key); err == nil {
+				reads[i] = client.beginItemRead(cacheKey)
+			}
+		}
+	}
+
+	batch.items, _, batch.err = client.batchGetTableItems(batch.ctx, tableName, batch.keys,
+		batch.keyAttributes, &dynamodb.KeysAndAttributes{}, nil, client.RetryPolicy)
+
+	for i, read := range reads {
+		if read == nil {
+			continue
+		}
+		if batch.err == nil {
+			client.setCachedItem(read, batch.items[keyID(batch.keys[i], batch.keyAttributes)])
+		}
+		client.endItemRead(read)
+	}
+
+	close(batch.done)
+}
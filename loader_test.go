@@ -0,0 +1,90 @@
+package autoquery
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+type testNumberKey struct {
+	PK string                   `dynamodbav:"pk"`
+	SK dynamodbattribute.Number `dynamodbav:"sk"`
+}
+
+func TestLoaderBatchesConcurrentGets(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(5, 5)
+	client := NewClient(svc)
+	loader := client.NewLoader(fakeTableName, 50*time.Millisecond)
+
+	// each item is requested twice, once with a key in its normalized form
+	keys := []interface{}{}
+	for sk := 0; sk < 5; sk++ {
+		keys = append(keys,
+			testKey{PK: "p", SK: sk},
+			testNumberKey{PK: "p", SK: dynamodbattribute.Number(strconv.Itoa(sk) + ".0")})
+	}
+	// a key whose item does not exist
+	keys = append(keys, testKey{PK: "p", SK: 10})
+
+	items := make([]testItem, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key interface{}) {
+			defer wg.Done()
+			errs[i] = loader.Get(ctx, key, &items[i])
+		}(i, key)
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		if errs[i] != nil {
+			t.Errorf("key %d: %v", i, errs[i])
+		} else if sk := i / 2; items[i].SK != sk || items[i].Value != "v"+strconv.Itoa(sk) {
+			t.Errorf("key %d: unexpected item %+v", i, items[i])
+		}
+	}
+	if _, notFound := errs[10].(*ErrItemNotFound); !notFound {
+		t.Errorf("expected ErrItemNotFound, found %v", errs[10])
+	}
+
+	if _, _, batchGetCalls := svc.calls(); batchGetCalls != 1 {
+		t.Errorf("expected 1 batch get call, found %d", batchGetCalls)
+	}
+	if len(svc.batchGetKeys) != 1 || svc.batchGetKeys[0] != 6 {
+		t.Errorf("expected a single batch of 6 keys, found %v", svc.batchGetKeys)
+	}
+}
+
+func TestLoaderRequestsFullBatches(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(4, 4)
+	client := NewClient(svc)
+	loader := client.NewLoader(fakeTableName, time.Minute).SetMaxBatchSize(2)
+
+	var wg sync.WaitGroup
+	for sk := 0; sk < 4; sk++ {
+		wg.Add(1)
+		go func(sk int) {
+			defer wg.Done()
+			item := testItem{}
+			if err := loader.Get(ctx, testKey{PK: "p", SK: sk}, &item); err != nil {
+				t.Error(err)
+			} else if item.SK != sk {
+				t.Errorf("expected item %d, found %d", sk, item.SK)
+			}
+		}(sk)
+	}
+	wg.Wait()
+
+	// full batches are requested without waiting for more keys
+	if _, _, batchGetCalls := svc.calls(); batchGetCalls != 2 {
+		t.Errorf("expected 2 batch get calls, found %d", batchGetCalls)
+	}
+}
@@ -0,0 +1,143 @@
+package autoquery
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// clientSideOrderScoreMultiplier is applied to the score of indexes which would require ordering
+// items client-side. Client-side ordering queries every page before the first item is returned.
+const clientSideOrderScoreMultiplier = 0.5
+
+// SetClientSideOrdering enables client-side ordering of items, which allows expressions with
+// OrderBy or ThenBy to use indexes which do not sort on the order attribute. If the selected index
+// does not sort items in the expression's order, then all pages of the query are buffered and
+// ordered before the first item is returned. If the query returns more than maxItems items,
+// Next returns an ErrClientSideOrderLimitExceeded error on that call and every later call.
+// Indexes which sort on the order attribute are preferred over indexes which do not.
+//
+// Items are ordered by number, string, binary or boolean attribute values. Items without an
+// order attribute are ordered before items with the attribute when ascending.
+//
+// Whether items are ordered client-side is decided when the first query is made, so
+// SetClientSideOrdering and UnsetClientSideOrdering have no effect after the first call to Next.
+//
+// By default, client-side ordering is disabled and OrderBy requires an index which sorts on the
+// order attribute.
+func (parser *Parser) SetClientSideOrdering(maxItems int) *Parser {
+	parser.options.clientSideOrdering = true
+	parser.clientSideOrderMaxItems = maxItems
+	return parser
+}
+
+// UnsetClientSideOrdering disables client-side ordering of items.
+func (parser *Parser) UnsetClientSideOrdering() *Parser {
+	parser.options.clientSideOrdering = false
+	return parser
+}
+
+// fillOrderedBuffer queries all remaining pages and buffers the items in the expression's order.
+func (parser *Parser) fillOrderedBuffer(ctx context.Context) error {
+	items := []map[string]*dynamodb.AttributeValue{}
+	for !parser.allItemsParsed() && !parser.maxPaginationReached() {
+		// update query input with the last evaluated key
+		if err := parser.buildQueryInput(ctx); err != nil {
+			return err
+		}
+
+		page, err := parser.nextPage(ctx)
+		if err != nil {
+			return err
+		}
+		parser.applyPage(page)
+
+		items = append(items, page.output.Items...)
+		if len(items) > parser.clientSideOrderMaxItems {
+			parser.stopPrefetch()
+			parser.orderLimitErr = &ErrClientSideOrderLimitExceeded{
+				MaxItems: parser.clientSideOrderMaxItems,
+			}
+			return parser.orderLimitErr
+		}
+	}
+
+	orders := parser.expr.orders()
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, order := range orders {
+			cmp := compareAttributeValues(items[i][order.attribute], items[j][order.attribute])
+			if cmp != 0 {
+				return (cmp < 0) == order.ascending
+			}
+		}
+		return false
+	})
+
+	parser.bufferedItems = items
+	parser.currentBufferIndex = 0
+	parser.removeUnselectedAttributes()
+
+	return nil
+}
+
+// compareAttributeValues returns -1, 0 or 1 if a is less than, equal to or greater than b.
+// Missing values are less than present values, and values of different types are ordered by type.
+func compareAttributeValues(a, b *dynamodb.AttributeValue) int {
+	aRank, bRank := attributeValueTypeRank(a), attributeValueTypeRank(b)
+	if aRank != bRank {
+		if aRank < bRank {
+			return -1
+		}
+		return 1
+	}
+
+	switch {
+	case a == nil:
+		return 0
+	case a.N != nil:
+		aNum, _, errA := big.ParseFloat(*a.N, 10, 128, big.ToNearestEven)
+		bNum, _, errB := big.ParseFloat(*b.N, 10, 128, big.ToNearestEven)
+		if errA != nil || errB != nil {
+			return 0
+		}
+		return aNum.Cmp(bNum)
+	case a.S != nil:
+		if *a.S < *b.S {
+			return -1
+		} else if *a.S > *b.S {
+			return 1
+		}
+		return 0
+	case a.B != nil:
+		return bytes.Compare(a.B, b.B)
+	case a.BOOL != nil:
+		if *a.BOOL == *b.BOOL {
+			return 0
+		} else if !*a.BOOL {
+			return -1
+		}
+		return 1
+	}
+
+	return 0
+}
+
+func attributeValueTypeRank(av *dynamodb.AttributeValue) int {
+	switch {
+	case av == nil:
+		return 0
+	case av.N != nil:
+		return 1
+	case av.S != nil:
+		return 2
+	case av.B != nil:
+		return 3
+	case av.BOOL != nil:
+		return 4
+	}
+	// other types are not ordered
+	return 5
+}
@@ -0,0 +1,120 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func expectOrder(t *testing.T, sortKeys []int, expected []int) {
+	t.Helper()
+	if len(sortKeys) != len(expected) {
+		t.Fatalf("expected items %v, found %v", expected, sortKeys)
+	}
+	for i := range expected {
+		if sortKeys[i] != expected[i] {
+			t.Fatalf("expected items %v, found %v", expected, sortKeys)
+		}
+	}
+}
+
+func TestClientSideOrdering(t *testing.T) {
+	ctx := context.Background()
+	svc := newAggregateService(
+		[]*dynamodb.AttributeValue{nil, nil, nil, nil, nil},
+		[]*dynamodb.AttributeValue{number("3"), number("-1"), nil, number("10"), number("9")})
+	client := NewClient(svc)
+
+	ascending := client.Query(fakeTableName,
+		NewExpression().Equal("pk", "p").OrderBy("amount", true)).SetClientSideOrdering(10)
+	expectOrder(t, parseAllRaw(ctx, t, ascending), []int{2, 1, 0, 4, 3})
+
+	descending := client.Query(fakeTableName,
+		NewExpression().Equal("pk", "p").OrderBy("amount", false)).SetClientSideOrdering(10)
+	expectOrder(t, parseAllRaw(ctx, t, descending), []int{3, 4, 0, 1, 2})
+}
+
+func TestClientSideOrderingThenBy(t *testing.T) {
+	ctx := context.Background()
+	svc := newAggregateService(
+		[]*dynamodb.AttributeValue{number("2"), number("1"), number("2"), number("1"), number("2")},
+		[]*dynamodb.AttributeValue{number("5"), number("6"), number("7"), number("4"), number("7")})
+	client := NewClient(svc)
+
+	// ties on both attributes keep the order of the query
+	expr := NewExpression().Equal("pk", "p").OrderBy("group", true).ThenBy("amount", false)
+	parser := client.Query(fakeTableName, expr).SetClientSideOrdering(10)
+	expectOrder(t, parseAllRaw(ctx, t, parser), []int{1, 3, 2, 4, 0})
+
+	// secondary order attributes require client-side ordering
+	parser = client.Query(fakeTableName, expr)
+	if _, err := parser.NextRaw(ctx); err == nil {
+		t.Error("expected an error without client-side ordering")
+	}
+}
+
+func TestClientSideOrderingMixedTypes(t *testing.T) {
+	ctx := context.Background()
+	svc := newAggregateService(
+		[]*dynamodb.AttributeValue{nil, nil, nil, nil, nil},
+		[]*dynamodb.AttributeValue{
+			{S: aws.String("b")}, number("10"), {S: aws.String("a")}, number("9"), nil,
+		})
+	client := NewClient(svc)
+
+	// missing values are ordered before numbers, and numbers before strings
+	parser := client.Query(fakeTableName,
+		NewExpression().Equal("pk", "p").OrderBy("amount", true)).SetClientSideOrdering(10)
+	expectOrder(t, parseAllRaw(ctx, t, parser), []int{4, 3, 1, 2, 0})
+}
+
+func TestClientSideOrderingPrefersSortedIndex(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(5, 2)
+	svc.indexes = []*dynamodb.GlobalSecondaryIndexDescription{{
+		IndexName: aws.String("value-index"),
+		ItemCount: aws.Int64(5),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("value"), KeyType: aws.String("RANGE")},
+		},
+		Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+	}}
+
+	indexNames := []string{}
+	client := NewClient(svc).Use(func(ctx context.Context, operation string,
+		input interface{}, next Handler) (interface{}, error) {
+
+		if operation == OperationQuery {
+			indexNames = append(indexNames, aws.StringValue(input.(*dynamodb.QueryInput).IndexName))
+		}
+		return next(ctx, input)
+	})
+
+	// items are returned in the index's order without being buffered
+	parser := client.Query(fakeTableName,
+		NewExpression().Equal("pk", "p").OrderBy("value", false)).SetClientSideOrdering(1)
+	expectOrder(t, parseAllRaw(ctx, t, parser), []int{4, 3, 2, 1, 0})
+	for _, indexName := range indexNames {
+		if indexName != "value-index" {
+			t.Fatalf("expected queries on value-index, found %v", indexNames)
+		}
+	}
+}
+
+func TestClientSideOrderLimitErrorIsRepeated(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(10, 2))
+
+	expr := NewExpression().Equal("pk", "p").OrderBy("value", false)
+	parser := client.Query(fakeTableName, expr).SetClientSideOrdering(3)
+
+	for i := 0; i < 3; i++ {
+		_, err := parser.NextRaw(ctx)
+		if _, exceeded := err.(*ErrClientSideOrderLimitExceeded); !exceeded {
+			t.Fatalf("call %d: expected ErrClientSideOrderLimitExceeded, found %v", i+1, err)
+		}
+	}
+}
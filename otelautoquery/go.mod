@@ -0,0 +1,25 @@
+module github.com/dgravesa/dynamodb-autoquery/otelautoquery
+
+go 1.23.0
+
+require (
+	github.com/aws/aws-sdk-go v1.42.9
+	github.com/dgravesa/dynamodb-autoquery v0.0.0-20261018133749-10916f020727
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/metric v1.38.0
+	go.opentelemetry.io/otel/sdk v1.38.0
+	go.opentelemetry.io/otel/sdk/metric v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
+
+// the replace directive applies only when building within this repository
+replace github.com/dgravesa/dynamodb-autoquery => ../
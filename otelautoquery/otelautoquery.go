@@ -0,0 +1,211 @@
+// Package otelautoquery provides OpenTelemetry tracing and metrics for autoquery clients.
+//
+// The instrumentation is provided as an autoquery.Interceptor which may be added to a client with
+// Client.Use:
+//
+//	interceptor, err := otelautoquery.NewInterceptor()
+//	if err != nil {
+//		return err
+//	}
+//	client := autoquery.NewClient(svc).Use(interceptor)
+//
+// A span is emitted for each index selection and each DynamoDB call made by the client. Latency
+// of each call and the number of items returned by each completed query are recorded as
+// histograms.
+//
+// The autoquery.query.items histogram only records completed queries, which are queries whose
+// parser returned all items or reached max pagination. Queries which fail, or which the caller
+// stops reading before completion, are not recorded, so the histogram should not be used to
+// count queries or the items they return.
+package otelautoquery
+
+import (
+	"context"
+	"time"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/dgravesa/dynamodb-autoquery/otelautoquery"
+
+// Attribute keys set on spans and metrics.
+const (
+	OperationKey        = attribute.Key("autoquery.operation")
+	TableKey            = attribute.Key("autoquery.table")
+	IndexKey            = attribute.Key("autoquery.index")
+	IndexScoreKey       = attribute.Key("autoquery.index.score")
+	PageKey             = attribute.Key("autoquery.page")
+	ItemsKey            = attribute.Key("autoquery.items")
+	ScannedCountKey     = attribute.Key("autoquery.scanned_count")
+	ConsumedCapacityKey = attribute.Key("autoquery.consumed_capacity")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// Option configures the interceptor created by NewInterceptor.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. By default, the global
+// tracer provider is used.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used to create metric instruments. By default, the
+// global meter provider is used.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+type instrumentation struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	items    metric.Int64Histogram
+}
+
+// NewInterceptor creates an interceptor which emits OpenTelemetry spans and metrics for each
+// call made by an autoquery client.
+func NewInterceptor(opts ...Option) (autoquery.Interceptor, error) {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram("autoquery.operation.duration",
+		metric.WithDescription("Duration of autoquery operations."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	items, err := meter.Int64Histogram("autoquery.query.items",
+		metric.WithDescription(
+			"Number of items returned by each completed query, excluding failed or abandoned "+
+				"queries."),
+		metric.WithUnit("{item}"))
+	if err != nil {
+		return nil, err
+	}
+
+	inst := &instrumentation{
+		tracer:   c.tracerProvider.Tracer(instrumentationName),
+		duration: duration,
+		items:    items,
+	}
+
+	return inst.intercept, nil
+}
+
+func (inst *instrumentation) intercept(ctx context.Context, operation string,
+	input interface{}, next autoquery.Handler) (interface{}, error) {
+
+	if completion, ok := input.(*autoquery.QueryCompletionInput); ok {
+		// query completion is not a call, so only the query's item count is recorded
+		inst.items.Record(ctx, int64(completion.Stats.ItemsReturned), metric.WithAttributes(
+			TableKey.String(completion.TableName), IndexKey.String(completion.Stats.IndexName)))
+		return next(ctx, input)
+	}
+
+	attrs := append([]attribute.KeyValue{OperationKey.String(operation)}, inputAttributes(input)...)
+	if page, ok := autoquery.PageFromContext(ctx); ok && operation == autoquery.OperationQuery {
+		attrs = append(attrs, PageKey.Int(page))
+	}
+
+	ctx, span := inst.tracer.Start(ctx, "autoquery."+operation,
+		trace.WithSpanKind(spanKind(operation)),
+		trace.WithAttributes(attrs...))
+	defer span.End()
+
+	start := time.Now()
+	output, err := next(ctx, input)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(outputAttributes(output)...)
+
+	inst.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(attrs[:2]...))
+
+	return output, err
+}
+
+func spanKind(operation string) trace.SpanKind {
+	if operation == autoquery.OperationSelectIndex {
+		return trace.SpanKindInternal
+	}
+	return trace.SpanKindClient
+}
+
+// inputAttributes returns the table attribute of an input, followed by any other attributes.
+func inputAttributes(input interface{}) []attribute.KeyValue {
+	switch in := input.(type) {
+	case *autoquery.IndexSelectionInput:
+		return []attribute.KeyValue{TableKey.String(in.TableName)}
+	case *dynamodb.DescribeTableInput:
+		return []attribute.KeyValue{TableKey.String(aws.StringValue(in.TableName))}
+	case *dynamodb.QueryInput:
+		return []attribute.KeyValue{
+			TableKey.String(aws.StringValue(in.TableName)),
+			IndexKey.String(aws.StringValue(in.IndexName)),
+		}
+	case *dynamodb.GetItemInput:
+		return []attribute.KeyValue{TableKey.String(aws.StringValue(in.TableName))}
+	case *dynamodb.PutItemInput:
+		return []attribute.KeyValue{TableKey.String(aws.StringValue(in.TableName))}
+	}
+	return []attribute.KeyValue{TableKey.String("")}
+}
+
+func outputAttributes(output interface{}) []attribute.KeyValue {
+	switch out := output.(type) {
+	case *autoquery.IndexSelectionOutput:
+		if out == nil {
+			break
+		}
+		return []attribute.KeyValue{
+			IndexKey.String(out.IndexName),
+			IndexScoreKey.Float64(out.Score),
+		}
+	case *dynamodb.QueryOutput:
+		if out == nil {
+			break
+		}
+		attrs := []attribute.KeyValue{
+			ItemsKey.Int64(aws.Int64Value(out.Count)),
+			ScannedCountKey.Int64(aws.Int64Value(out.ScannedCount)),
+		}
+		if out.ConsumedCapacity != nil {
+			attrs = append(attrs,
+				ConsumedCapacityKey.Float64(aws.Float64Value(out.ConsumedCapacity.CapacityUnits)))
+		}
+		return attrs
+	case *dynamodb.GetItemOutput:
+		if out == nil || out.ConsumedCapacity == nil {
+			break
+		}
+		return []attribute.KeyValue{
+			ConsumedCapacityKey.Float64(aws.Float64Value(out.ConsumedCapacity.CapacityUnits)),
+		}
+	}
+	return nil
+}
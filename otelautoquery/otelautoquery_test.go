@@ -0,0 +1,180 @@
+package otelautoquery_test
+
+import (
+	"context"
+	"testing"
+
+	autoquery "github.com/dgravesa/dynamodb-autoquery"
+	"github.com/dgravesa/dynamodb-autoquery/otelautoquery"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// pagedService serves a table with a partition key "id" and returns one item per query page.
+type pagedService struct {
+	dynamodbiface.DynamoDBAPI
+	pages int
+}
+
+func (svc *pagedService) DescribeTableWithContext(ctx aws.Context,
+	input *dynamodb.DescribeTableInput,
+	opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableName: input.TableName,
+		ItemCount: aws.Int64(int64(svc.pages)),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: aws.String("HASH")},
+		},
+	}}, nil
+}
+
+func (svc *pagedService) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput,
+	opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+	page, _ := autoquery.PageFromContext(ctx)
+	output := &dynamodb.QueryOutput{
+		Items: []map[string]*dynamodb.AttributeValue{
+			{"id": {S: aws.String("a")}},
+		},
+		Count:        aws.Int64(1),
+		ScannedCount: aws.Int64(1),
+	}
+	if page < svc.pages {
+		output.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String("a")},
+		}
+	}
+	return output, nil
+}
+
+// newTestInterceptor creates an interceptor which records spans and metrics in memory.
+func newTestInterceptor(t *testing.T) (autoquery.Interceptor, *tracetest.SpanRecorder,
+	*sdkmetric.ManualReader) {
+
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	interceptor, err := otelautoquery.NewInterceptor(
+		otelautoquery.WithTracerProvider(tracerProvider),
+		otelautoquery.WithMeterProvider(meterProvider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return interceptor, spanRecorder, reader
+}
+
+// itemsHistogram returns the autoquery.query.items histogram, or nil if it was not recorded.
+func itemsHistogram(ctx context.Context, t *testing.T,
+	reader *sdkmetric.ManualReader) *metricdata.Histogram[int64] {
+
+	metrics := metricdata.ResourceMetrics{}
+	if err := reader.Collect(ctx, &metrics); err != nil {
+		t.Fatal(err)
+	}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == "autoquery.query.items" {
+				histogram := m.Data.(metricdata.Histogram[int64])
+				return &histogram
+			}
+		}
+	}
+	return nil
+}
+
+func TestInterceptor(t *testing.T) {
+	ctx := context.Background()
+
+	interceptor, spanRecorder, reader := newTestInterceptor(t)
+	client := autoquery.NewClient(&pagedService{pages: 3}).Use(interceptor)
+
+	parser := client.Query("Items", autoquery.NewExpression().Equal("id", "a"))
+	items := 0
+	for {
+		_, err := parser.NextRaw(ctx)
+		if _, complete := err.(*autoquery.ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		items++
+	}
+	if items != 3 {
+		t.Fatalf("expected 3 items, found %d", items)
+	}
+
+	spanCounts := map[string]int{}
+	for _, span := range spanRecorder.Ended() {
+		spanCounts[span.Name()]++
+	}
+	expectedSpans := map[string]int{
+		"autoquery.DescribeTable": 1,
+		"autoquery.SelectIndex":   1,
+		"autoquery.Query":         3,
+	}
+	for name, count := range expectedSpans {
+		if spanCounts[name] != count {
+			t.Errorf("expected %d %s spans, found %d", count, name, spanCounts[name])
+		}
+	}
+	if len(spanCounts) != len(expectedSpans) {
+		t.Errorf("unexpected spans: %v", spanCounts)
+	}
+
+	histogram := itemsHistogram(ctx, t, reader)
+	if histogram == nil {
+		t.Fatal("autoquery.query.items was not recorded")
+	}
+	if len(histogram.DataPoints) != 1 {
+		t.Fatalf("expected 1 data point, found %d", len(histogram.DataPoints))
+	}
+	point := histogram.DataPoints[0]
+	if point.Count != 1 || point.Sum != 3 {
+		t.Errorf("expected a single query of 3 items, found %d queries totaling %d items",
+			point.Count, point.Sum)
+	}
+}
+
+func TestInterceptorSkipsAbandonedQueries(t *testing.T) {
+	ctx := context.Background()
+
+	interceptor, spanRecorder, reader := newTestInterceptor(t)
+	client := autoquery.NewClient(&pagedService{pages: 3}).Use(interceptor)
+
+	// the caller stops after the first item, so the query never completes
+	parser := client.Query("Items", autoquery.NewExpression().Equal("id", "a"))
+	if _, err := parser.NextRaw(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	queries := 0
+	for _, span := range spanRecorder.Ended() {
+		if span.Name() == "autoquery.Query" {
+			queries++
+		}
+	}
+	if queries != 1 {
+		t.Errorf("expected 1 query span, found %d", queries)
+	}
+
+	if histogram := itemsHistogram(ctx, t, reader); histogram != nil {
+		for _, point := range histogram.DataPoints {
+			if point.Count != 0 {
+				t.Errorf("expected no recorded queries, found %d", point.Count)
+			}
+		}
+	}
+}
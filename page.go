@@ -0,0 +1,63 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// PageMetadata contains metadata for a page of items returned by Parser.NextPage.
+type PageMetadata struct {
+	// PageNumber is the number of the page in the query, starting from 1.
+	PageNumber int
+
+	// Count is the number of items unmarshaled from the page.
+	Count int
+
+	// ScannedCount is the number of items evaluated by DynamoDB for the page before filters were
+	// applied.
+	ScannedCount int
+
+	// LastEvaluatedKey is the last evaluated key of the page. It may be passed to
+	// Parser.SetExclusiveStartKey on a new parser to continue the query after this page. If
+	// LastEvaluatedKey is empty, the page is the last page of the query.
+	LastEvaluatedKey map[string]*dynamodb.AttributeValue
+
+	// IndexName is the name of the index used for the query. If the table's primary index is
+	// used, IndexName is empty.
+	IndexName string
+}
+
+// NextPage retrieves the remaining items in the current page of the query. The items are
+// unmarshaled into out, which should be a pointer to a slice of items with "dynamodbav" struct
+// tags, unless a decoder has been set on the parser or client.
+//
+// If items from the current page have already been returned by Next, only the remaining items in
+// the page are returned and Count reflects the number of remaining items. Otherwise, NextPage
+// queries the next page in the same way as Next. Pages without any items after filters have been
+// applied are skipped.
+//
+// Once all items have been returned or max pagination has been reached, NextPage returns
+// ErrParsingComplete.
+func (parser *Parser) NextPage(ctx context.Context, out interface{}) (*PageMetadata, error) {
+	if err := parser.fillBuffer(ctx); err != nil {
+		return nil, err
+	}
+
+	pageItems := parser.bufferedItems[parser.currentBufferIndex:]
+	parser.currentBufferIndex = len(parser.bufferedItems)
+
+	err := parser.client.decodeTableItems(parser.tableName, parser.getDecoder(), pageItems, out)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PageMetadata{
+		PageNumber:       parser.currentPage,
+		Count:            len(pageItems),
+		ScannedCount:     int(aws.Int64Value(parser.currentPageOutput.ScannedCount)),
+		LastEvaluatedKey: parser.currentPageOutput.LastEvaluatedKey,
+		IndexName:        parser.stats.IndexName,
+	}, nil
+}
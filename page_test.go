@@ -0,0 +1,144 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func itemSortKeys(items []testItem) []int {
+	sortKeys := []int{}
+	for _, item := range items {
+		sortKeys = append(sortKeys, item.SK)
+	}
+	return sortKeys
+}
+
+func expectPage(t *testing.T, page *PageMetadata, items []testItem, pageNumber int,
+	sortKeys []int, last bool) {
+
+	t.Helper()
+	if page.PageNumber != pageNumber {
+		t.Errorf("expected page %d, found page %d", pageNumber, page.PageNumber)
+	}
+	found := itemSortKeys(items)
+	if page.Count != len(sortKeys) || len(found) != len(sortKeys) {
+		t.Fatalf("expected items %v, found %v with count %d", sortKeys, found, page.Count)
+	}
+	for i := range sortKeys {
+		if found[i] != sortKeys[i] {
+			t.Fatalf("expected items %v, found %v", sortKeys, found)
+		}
+	}
+	if isLast := len(page.LastEvaluatedKey) == 0; isLast != last {
+		t.Errorf("expected last page to be %v, found last evaluated key %v",
+			last, page.LastEvaluatedKey)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 2))
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+
+	expected := [][]int{{0, 1}, {2, 3}, {4}}
+	for i, sortKeys := range expected {
+		items := []testItem{}
+		page, err := parser.NextPage(ctx, &items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectPage(t, page, items, i+1, sortKeys, i == len(expected)-1)
+		if page.ScannedCount != len(sortKeys) || page.IndexName != "" {
+			t.Errorf("unexpected page metadata: %+v", page)
+		}
+	}
+
+	items := []testItem{}
+	if _, err := parser.NextPage(ctx, &items); !isParsingComplete(err) {
+		t.Errorf("expected ErrParsingComplete, found %v", err)
+	}
+}
+
+func TestNextPageAfterNext(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 3))
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+
+	item := testItem{}
+	if err := parser.Next(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the remaining items of the current page are returned
+	items := []testItem{}
+	page, err := parser.NextPage(ctx, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPage(t, page, items, 1, []int{1, 2}, false)
+
+	page, err = parser.NextPage(ctx, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPage(t, page, items, 2, []int{3, 4}, true)
+
+	// the page's last evaluated key continues the query on a new parser
+	continued := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+		SetExclusiveStartKey(map[string]*dynamodb.AttributeValue{
+			"pk": {S: aws.String("p")},
+			"sk": {N: aws.String("2")},
+		})
+	if _, err := continued.NextPage(ctx, &items); err != nil {
+		t.Fatal(err)
+	}
+	if found := itemSortKeys(items); len(found) != 2 || found[0] != 3 {
+		t.Errorf("expected items [3 4], found %v", found)
+	}
+}
+
+func TestNextPageSkipsEmptyPages(t *testing.T) {
+	ctx := context.Background()
+
+	// the second page has no items after filters are applied
+	emptySecondPage := func(ctx context.Context, operation string, input interface{},
+		next Handler) (interface{}, error) {
+
+		output, err := next(ctx, input)
+		if page, _ := PageFromContext(ctx); operation == OperationQuery && page == 2 {
+			queryOutput := output.(*dynamodb.QueryOutput)
+			queryOutput.Items = nil
+			queryOutput.Count = aws.Int64(0)
+		}
+		return output, err
+	}
+	client := NewClient(newFakeService(6, 2)).Use(emptySecondPage)
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+
+	items := []testItem{}
+	if _, err := parser.NextPage(ctx, &items); err != nil {
+		t.Fatal(err)
+	}
+	page, err := parser.NextPage(ctx, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPage(t, page, items, 3, []int{4, 5}, true)
+}
+
+func TestNextPageRequiresSlicePointer(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(2, 2))
+	client.Decoder = DecoderFunc(func(item map[string]*dynamodb.AttributeValue,
+		out interface{}) error {
+		return nil
+	})
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+
+	if _, err := parser.NextPage(ctx, &testItem{}); err == nil {
+		t.Error("expected an error when decoding a page into a non-slice")
+	}
+}
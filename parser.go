@@ -5,7 +5,6 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
 // Parser is used for parsing query results.
@@ -14,6 +13,7 @@ type Parser struct {
 
 	tableName string
 	expr      *Expression
+	options   queryOptions
 
 	maxPagesSpecified bool
 	maxPages          int
@@ -24,14 +24,37 @@ type Parser struct {
 
 	exclusiveStartkey map[string]*dynamodb.AttributeValue
 
+	returnConsumedCapacitySpecified bool
+	returnConsumedCapacity          string
+
+	retryPolicySpecified bool
+	retryPolicy          *RetryPolicy
+
+	decoderSpecified bool
+	decoder          Decoder
+
+	prefetchDepth int
+	prefetcher    *prefetcher
+	streamer      *streamer
+
 	queryInput *dynamodb.QueryInput
+	backFetch  *backFetchSpec
+
+	clientSideOrderMaxItems int
+	orderClientSide         bool
+	orderLimitErr           error
+	unselectedAttributes    []string
 
+	stats           ParserStats
+	completeInvoked bool
+
+	currentPageOutput  *dynamodb.QueryOutput
 	bufferedItems      []map[string]*dynamodb.AttributeValue
 	currentBufferIndex int
 }
 
 // Next retrieves the next item in the query. The returnItem is unmarshaled with "dynamodbav"
-// struct tags.
+// struct tags, unless a decoder has been set on the parser or client.
 //
 // On the first call to Next with a new table, the table's index metadata will be retrieved using
 // the underlying metadata provider. For the default client created by NewClient, this requires
@@ -49,12 +72,45 @@ type Parser struct {
 // Once all items have been returned or max pagination has been reached, the query will return
 // ErrParsingComplete.
 func (parser *Parser) Next(ctx context.Context, returnItem interface{}) error {
-	// refill buffer if necessary, including first call
+	if err := parser.fillBuffer(ctx); err != nil {
+		return err
+	}
+
+	currentItem := parser.bufferedItems[parser.currentBufferIndex]
+	parser.currentBufferIndex++
+
+	return parser.client.decodeTableItem(
+		parser.tableName, parser.getDecoder(), currentItem, returnItem)
+}
+
+// NextRaw retrieves the next item in the query without unmarshaling it. NextRaw otherwise
+// behaves the same as Next.
+func (parser *Parser) NextRaw(ctx context.Context) (map[string]*dynamodb.AttributeValue, error) {
+	if err := parser.fillBuffer(ctx); err != nil {
+		return nil, err
+	}
+
+	currentItem := parser.bufferedItems[parser.currentBufferIndex]
+	parser.currentBufferIndex++
+
+	return currentItem, nil
+}
+
+// fillBuffer refills the buffer with the next page of items if all buffered items have been
+// returned, including on the first call.
+func (parser *Parser) fillBuffer(ctx context.Context) error {
+	// items are not returned once the client-side order limit has been exceeded
+	if parser.orderLimitErr != nil {
+		return parser.orderLimitErr
+	}
+
 	for parser.currentBufferIndex == len(parser.bufferedItems) {
 		// check for parsing complete conditions
 		if parser.allItemsParsed() {
+			parser.invokeComplete(ctx)
 			return &ErrParsingComplete{reason: "all items have been parsed"}
 		} else if parser.maxPaginationReached() {
+			parser.invokeComplete(ctx)
 			return &ErrParsingComplete{reason: "max pagination has been reached"}
 		}
 
@@ -63,27 +119,82 @@ func (parser *Parser) Next(ctx context.Context, returnItem interface{}) error {
 			return err
 		}
 
+		// items ordered client-side are buffered all at once
+		if parser.orderClientSide {
+			if err := parser.fillOrderedBuffer(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
 		// execute new query to refill buffer
-		queryOutput, err := parser.client.dynamodbService.QueryWithContext(ctx, parser.queryInput)
+		page, err := parser.nextPage(ctx)
 		if err != nil {
 			return err
 		}
+		parser.applyPage(page)
 
-		parser.exclusiveStartkey = queryOutput.LastEvaluatedKey
-		parser.currentPage++
-		parser.bufferedItems = queryOutput.Items
+		parser.bufferedItems = page.output.Items
 		parser.currentBufferIndex = 0
+		parser.removeUnselectedAttributes()
 	}
 
-	currentItem := parser.bufferedItems[parser.currentBufferIndex]
-	parser.currentBufferIndex++
+	return nil
+}
 
-	return dynamodbattribute.UnmarshalMap(currentItem, returnItem)
+// invokeComplete passes the query's statistics through the client's interceptors the first time
+// parsing completes.
+func (parser *Parser) invokeComplete(ctx context.Context) {
+	if parser.completeInvoked {
+		return
+	}
+	parser.completeInvoked = true
+
+	parser.client.invoke(ctx, OperationCompleteQuery,
+		&QueryCompletionInput{TableName: parser.tableName, Stats: parser.stats.copy()},
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return nil, nil
+		})
+}
+
+// applyPage updates the parser's query state and stats with a page queried from DynamoDB.
+func (parser *Parser) applyPage(page *pageResult) {
+	queryOutput := page.output
+
+	parser.exclusiveStartkey = queryOutput.LastEvaluatedKey
+	parser.currentPage++
+	parser.stats.addQueryOutput(queryOutput, page.cached)
+	parser.stats.Retries += page.retries
+	for _, capacity := range page.backFetchCapacity {
+		parser.stats.ConsumedCapacity.add(capacity)
+	}
+	if parser.backFetch != nil {
+		parser.stats.ItemsBackFetched += len(queryOutput.Items)
+	}
+	parser.currentPageOutput = queryOutput
+}
+
+// removeUnselectedAttributes removes attributes from buffered items which were only queried in
+// order to order the items client-side.
+func (parser *Parser) removeUnselectedAttributes() {
+	if len(parser.unselectedAttributes) == 0 {
+		return
+	}
+	for i, item := range parser.bufferedItems {
+		selectedItem := map[string]*dynamodb.AttributeValue{}
+		for attr, value := range item {
+			if !containsString(parser.unselectedAttributes, attr) {
+				selectedItem[attr] = value
+			}
+		}
+		parser.bufferedItems[i] = selectedItem
+	}
 }
 
 // SetMaxPagination sets the maximum number of pages to query.
 // By default, the parser will consume additional pages until all query items have been read.
 func (parser *Parser) SetMaxPagination(maxPages int) *Parser {
+	parser.stopPrefetch()
 	parser.maxPagesSpecified = true
 	parser.maxPages = maxPages
 	return parser
@@ -91,6 +202,7 @@ func (parser *Parser) SetMaxPagination(maxPages int) *Parser {
 
 // UnsetMaxPagination unsets the maximum pagination limit.
 func (parser *Parser) UnsetMaxPagination() *Parser {
+	parser.stopPrefetch()
 	parser.maxPagesSpecified = false
 	return parser
 }
@@ -98,6 +210,7 @@ func (parser *Parser) UnsetMaxPagination() *Parser {
 // SetLimitPerPage sets the limit parameter for each page query call to DynamoDB.
 // The limit parameter restricts the number of evaluated items, not the number of returned items.
 func (parser *Parser) SetLimitPerPage(limit int) *Parser {
+	parser.stopPrefetch()
 	parser.limitPerPageSpecified = true
 	parser.limitPerPage = limit
 	return parser
@@ -105,6 +218,7 @@ func (parser *Parser) SetLimitPerPage(limit int) *Parser {
 
 // UnsetLimitPerPage unsets the limit parameter for each page query call to DynamoDB.
 func (parser *Parser) UnsetLimitPerPage() *Parser {
+	parser.stopPrefetch()
 	parser.limitPerPageSpecified = false
 	return parser
 }
@@ -112,10 +226,64 @@ func (parser *Parser) UnsetLimitPerPage() *Parser {
 // SetExclusiveStartKey sets the exclusive start key for the next page query call to DynamoDB.
 func (parser *Parser) SetExclusiveStartKey(
 	exclusiveStartKey map[string]*dynamodb.AttributeValue) *Parser {
+	parser.stopPrefetch()
 	parser.exclusiveStartkey = exclusiveStartKey
 	return parser
 }
 
+// SetReturnConsumedCapacity sets the ReturnConsumedCapacity parameter for each page query call to
+// DynamoDB. Valid values are "TOTAL", "INDEXES" and "NONE". The consumed capacity is accumulated
+// and may be retrieved using Parser.Stats. While a rate limit in capacity units applies to the
+// query, "NONE" is overridden with "TOTAL" so that the limit can be debited.
+func (parser *Parser) SetReturnConsumedCapacity(returnConsumedCapacity string) *Parser {
+	parser.stopPrefetch()
+	parser.returnConsumedCapacitySpecified = true
+	parser.returnConsumedCapacity = returnConsumedCapacity
+	return parser
+}
+
+// UnsetReturnConsumedCapacity unsets the ReturnConsumedCapacity parameter for each page query
+// call to DynamoDB.
+func (parser *Parser) UnsetReturnConsumedCapacity() *Parser {
+	parser.stopPrefetch()
+	parser.returnConsumedCapacitySpecified = false
+	return parser
+}
+
+// Stats returns cumulative statistics for the page query calls made by the parser so far.
+func (parser *Parser) Stats() ParserStats {
+	return parser.stats.copy()
+}
+
+// SetRetryPolicy sets the retry policy for failed page query calls, overriding the client's
+// retry policy. A nil policy disables retries for the parser.
+func (parser *Parser) SetRetryPolicy(policy *RetryPolicy) *Parser {
+	parser.stopPrefetch()
+	parser.retryPolicySpecified = true
+	parser.retryPolicy = policy
+	return parser
+}
+
+// UnsetRetryPolicy unsets the parser's retry policy, so the client's retry policy is used.
+func (parser *Parser) UnsetRetryPolicy() *Parser {
+	parser.stopPrefetch()
+	parser.retryPolicySpecified = false
+	return parser
+}
+
+// SetDecoder sets the decoder used to unmarshal items, overriding the client's decoder.
+func (parser *Parser) SetDecoder(decoder Decoder) *Parser {
+	parser.decoderSpecified = true
+	parser.decoder = decoder
+	return parser
+}
+
+// UnsetDecoder unsets the parser's decoder, so the client's decoder is used.
+func (parser *Parser) UnsetDecoder() *Parser {
+	parser.decoderSpecified = false
+	return parser
+}
+
 // TODO: is this possible?
 // // LastParsedKey returns the key of the most recent item parsed by Next.
 // //
@@ -137,29 +305,296 @@ func (parser *Parser) maxPaginationReached() bool {
 	return parser.maxPagesSpecified && (parser.currentPage >= parser.maxPages)
 }
 
+func (parser *Parser) getDecoder() Decoder {
+	if parser.decoderSpecified {
+		return parser.decoder
+	}
+	return parser.client.Decoder
+}
+
+func (parser *Parser) getRetryPolicy() *RetryPolicy {
+	if parser.retryPolicySpecified {
+		return parser.retryPolicy
+	}
+	return parser.client.RetryPolicy
+}
+
+// hasCapacityRateLimit returns true if the queried index, or the table when items are
+// back-fetched, has a rate limit in capacity units.
+func (parser *Parser) hasCapacityRateLimit(indexName string, backFetch bool) bool {
+	limiter := parser.client.readRateLimiter(parser.tableName, indexName)
+	if limiter != nil && limiter.unit == ReadCapacityUnitsPerSecond {
+		return true
+	}
+	if backFetch {
+		limiter = parser.client.readRateLimiter(parser.tableName, "")
+		return limiter != nil && limiter.unit == ReadCapacityUnitsPerSecond
+	}
+	return false
+}
+
+type pageResult struct {
+	output  *dynamodb.QueryOutput
+	retries int
+	err     error
+
+	// cached is true if the page was served from the client's result cache
+	cached bool
+
+	// backFetchCapacity contains the capacity consumed by back-fetching items from the table
+	backFetchCapacity []*dynamodb.ConsumedCapacity
+}
+
+// nextPage returns the next page of the query, either by calling DynamoDB directly or by
+// receiving a page from the parser's prefetcher.
+func (parser *Parser) nextPage(ctx context.Context) (*pageResult, error) {
+	if parser.prefetchDepth <= 0 {
+		page := parser.newPageFetcher().fetchPage(ctx, parser.queryInput, parser.currentPage+1)
+		return page, page.err
+	}
+
+	return parser.receivePrefetchedPage(ctx)
+}
+
+// pageFetcher makes page query calls for a parser. It holds a snapshot of the parser's settings
+// so that pages may be fetched in the background while the parser is in use.
+type pageFetcher struct {
+	client      *Client
+	tableName   string
+	retryPolicy *RetryPolicy
+	limiter     *tokenBucket
+	backFetch   *backFetchSpec
+
+	// backFetchLimiter is the rate limiter of the table's primary index
+	backFetchLimiter *tokenBucket
+}
+
+func (parser *Parser) newPageFetcher() *pageFetcher {
+	return &pageFetcher{
+		client:           parser.client,
+		tableName:        parser.tableName,
+		retryPolicy:      parser.getRetryPolicy(),
+		limiter:          parser.client.readRateLimiter(parser.tableName, parser.stats.IndexName),
+		backFetch:        parser.backFetch,
+		backFetchLimiter: parser.client.readRateLimiter(parser.tableName, ""),
+	}
+}
+
+// fetchPage executes a single page query call. The call is made according to the client's
+// rate limits and the parser's retry policy, unless the page is served from the client's cache.
+func (fetcher *pageFetcher) fetchPage(
+	ctx context.Context, queryInput *dynamodb.QueryInput, pageNumber int) *pageResult {
+
+	ctx = contextWithPage(ctx, pageNumber)
+
+	client, limiter := fetcher.client, fetcher.limiter
+
+	queryOnce := func(ctx context.Context) (*dynamodb.QueryOutput, error) {
+		if limiter == nil {
+			return client.dynamodbService.QueryWithContext(ctx, queryInput)
+		}
+
+		// block until rate limit allows the call, then debit consumed capacity
+		if err := limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		queryOutput, err := client.dynamodbService.QueryWithContext(ctx, queryInput)
+		if queryOutput != nil && queryOutput.ConsumedCapacity != nil {
+			limiter.debit(aws.Float64Value(queryOutput.ConsumedCapacity.CapacityUnits))
+		}
+		return queryOutput, err
+	}
+
+	page := &pageResult{}
+
+	cacheKey := ""
+	if client.isCacheableQuery(queryInput) {
+		if cacheKey, page.err = queryCacheKey(queryInput); page.err != nil {
+			return page
+		}
+		page.output, page.cached = client.getCachedQueryOutput(cacheKey)
+	}
+
+	fetch := func(ctx context.Context) (interface{}, error) {
+		fetched := &pageResult{}
+		if policy := fetcher.retryPolicy; policy == nil {
+			fetched.output, fetched.err = queryOnce(ctx)
+		} else {
+			fetched.retries, fetched.err = policy.do(ctx, func() error {
+				var err error
+				fetched.output, err = queryOnce(ctx)
+				return err
+			})
+		}
+
+		if fetched.err == nil && cacheKey != "" {
+			client.setCachedQueryOutput(cacheKey, fetched.output)
+		}
+		return fetched, nil
+	}
+
+	if !page.cached && client.CoalesceRequests && queryInput.ExclusiveStartKey == nil {
+		// only first pages are coalesced, since later pages of identical queries are rarely
+		// requested at the same time
+		flightKey, err := queryCacheKey(queryInput)
+		if err != nil {
+			page.err = err
+			return page
+		}
+		shared, issued, err := client.flights.do(ctx, flightKey, fetch)
+		if err != nil {
+			page.err = err
+			return page
+		}
+		sharedPage := shared.(*pageResult)
+		page.err = sharedPage.err
+		if page.err == nil {
+			page.output = copyQueryOutput(sharedPage.output)
+		}
+		if issued {
+			// the query's retries and consumed capacity are reported only by the parser which
+			// issued it, so that they are not counted once for every waiting parser
+			page.retries = sharedPage.retries
+			if page.err == nil {
+				page.output.ConsumedCapacity = sharedPage.output.ConsumedCapacity
+			}
+		}
+	} else if !page.cached {
+		fetched, _ := fetch(ctx)
+		page = fetched.(*pageResult)
+	}
+
+	if page.err == nil && fetcher.backFetch != nil {
+		// replace queried keys with items fetched from the table
+		result, err := fetcher.backFetchItems(ctx, page.output.Items,
+			queryInput.ReturnConsumedCapacity)
+		if err != nil {
+			page.err = err
+		} else {
+			page.output.Items = result.items
+			page.backFetchCapacity = result.consumedCapacity
+		}
+	}
+
+	return page
+}
+
 func (parser *Parser) buildQueryInput(ctx context.Context) error {
 	// select index and construct expression on first call
 	if parser.queryInput == nil {
-		queryIndex, err := parser.client.chooseIndex(ctx, parser.tableName, parser.expr)
+		expr, unselectedAttributes, err := parser.resolveExpression(ctx)
+		if err != nil {
+			return err
+		}
+		parser.unselectedAttributes = unselectedAttributes
+
+		queryIndex, err := parser.client.chooseIndex(
+			ctx, parser.tableName, expr, parser.options)
 		if err != nil {
 			return err
 		}
 
-		parser.queryInput, err = parser.expr.constructQueryInputGivenIndex(queryIndex)
+		parser.orderClientSide = parser.options.clientSideOrdering &&
+			!parser.options.countOnly && expr.orderSpecified &&
+			(expr.orderAttribute != queryIndex.SortKey || len(expr.thenByOrders) > 0)
+
+		queryExpr := expr
+		if parser.requiresBackFetch(queryIndex, expr) {
+			// query only the table keys from the index, then fetch items from the table
+			indexMetadata, err := parser.client.pullIndexMetadata(ctx, parser.tableName)
+			if err != nil {
+				return err
+			}
+			tablePrimaryIndex := indexMetadata.primaryIndex()
+			parser.backFetch, err = newBackFetchSpec(tablePrimaryIndex, expr)
+			if err != nil {
+				return err
+			}
+			queryExpr = backFetchQueryExpression(expr, tablePrimaryIndex)
+		}
+
+		parser.queryInput, err = queryExpr.constructQueryInputGivenIndex(
+			queryIndex, parser.options)
 		if err != nil {
 			return err
 		}
+
+		parser.stats.IndexName = aws.StringValue(parser.queryInput.IndexName)
 	}
 
-	parser.queryInput.TableName = aws.String(parser.tableName)
+	parser.setQueryInputOptions(parser.queryInput, parser.backFetch != nil)
+
+	return nil
+}
+
+// requiresBackFetch returns true if items queried from index are fetched from the table.
+func (parser *Parser) requiresBackFetch(index *tableIndex, expr *Expression) bool {
+	return parser.options.allowBackFetch && !parser.options.countOnly &&
+		index.requiresBackFetch(expr)
+}
+
+// backFetchQueryExpression returns the expression queried on an index when items are fetched
+// from the table, which selects only the table keys.
+func backFetchQueryExpression(expr *Expression, tablePrimaryIndex *tableIndex) *Expression {
+	queryExpr := expr.clone()
+	queryExpr.attributesSpecified = true
+	queryExpr.attributes = tablePrimaryIndex.getKeys()
+	return queryExpr
+}
+
+// setQueryInputOptions sets the table name, page limit, consumed capacity and start key of a
+// query input according to the parser's options.
+func (parser *Parser) setQueryInputOptions(input *dynamodb.QueryInput, backFetch bool) {
+	input.TableName = aws.String(parser.tableName)
 
 	if parser.limitPerPageSpecified {
-		parser.queryInput.Limit = aws.Int64(int64(parser.limitPerPage))
+		input.Limit = aws.Int64(int64(parser.limitPerPage))
 	} else {
-		parser.queryInput.Limit = nil
+		input.Limit = nil
 	}
 
-	parser.queryInput.ExclusiveStartKey = parser.exclusiveStartkey
+	capacityRequired := parser.hasCapacityRateLimit(aws.StringValue(input.IndexName), backFetch)
+	if parser.returnConsumedCapacitySpecified && (!capacityRequired ||
+		parser.returnConsumedCapacity != dynamodb.ReturnConsumedCapacityNone) {
+		input.ReturnConsumedCapacity = aws.String(parser.returnConsumedCapacity)
+	} else if capacityRequired {
+		// capacity-based rate limits require consumed capacity from each call
+		input.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+	} else {
+		input.ReturnConsumedCapacity = nil
+	}
 
-	return nil
+	input.ExclusiveStartKey = parser.exclusiveStartkey
+}
+
+// resolveExpression returns the expression to be queried, with its entity type resolved and with
+// any order attributes which must be queried to order items client-side added to its selected
+// attributes. The added attributes are also returned so they may be removed from result items.
+func (parser *Parser) resolveExpression(ctx context.Context) (*Expression, []string, error) {
+	expr, err := parser.client.resolveEntityType(ctx, parser.tableName, parser.expr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// condition values are marshaled in the same way as items
+	if parser.client.Encoder != nil {
+		if expr, err = expr.encodeValues(parser.client.Encoder); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var unselectedAttributes []string
+	if parser.options.clientSideOrdering && !parser.options.countOnly &&
+		expr.orderSpecified && expr.attributesSpecified {
+		// order attributes must be queried in order to order items client-side
+		expr = expr.clone()
+		for _, order := range expr.orders() {
+			if !containsString(expr.attributes, order.attribute) {
+				expr.attributes = append(expr.attributes, order.attribute)
+				unselectedAttributes = append(unselectedAttributes, order.attribute)
+			}
+		}
+	}
+
+	return expr, unselectedAttributes, nil
 }
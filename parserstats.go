@@ -0,0 +1,103 @@
+package autoquery
+
+import "github.com/aws/aws-sdk-go/service/dynamodb"
+
+// ParserStats contains cumulative statistics for the page query calls made by a Parser.
+type ParserStats struct {
+	// IndexName is the name of the index selected for the query. If the table's primary index
+	// is selected, IndexName is empty.
+	IndexName string
+
+	// PagesFetched is the number of page query calls made to DynamoDB.
+	PagesFetched int
+
+	// PagesFromCache is the number of pages served from the client's result cache instead of
+	// DynamoDB. See Client.Cache.
+	PagesFromCache int
+
+	// ItemsScanned is the number of items evaluated by DynamoDB before filters were applied.
+	ItemsScanned int
+
+	// ItemsReturned is the number of items returned by DynamoDB after filters were applied.
+	ItemsReturned int
+
+	// ItemsBackFetched is the number of items fetched from the table because the query index
+	// does not project all needed attributes. See Parser.SetAllowBackFetch.
+	ItemsBackFetched int
+
+	// Retries is the number of page query calls which were retried according to the parser's
+	// retry policy.
+	Retries int
+
+	// ConsumedCapacity contains the read capacity consumed by the query. Consumed capacity is
+	// only gathered when requested with Parser.SetReturnConsumedCapacity, or when a rate limit in
+	// capacity units applies to the query.
+	ConsumedCapacity ConsumedCapacityStats
+}
+
+// ConsumedCapacityStats contains cumulative read capacity units consumed by a query.
+type ConsumedCapacityStats struct {
+	// CapacityUnits is the total number of capacity units consumed by the query.
+	CapacityUnits float64
+
+	// TableCapacityUnits is the number of capacity units consumed on the table.
+	// This value is only populated when consumed capacity is set to INDEXES.
+	TableCapacityUnits float64
+
+	// IndexCapacityUnits is the number of capacity units consumed on each secondary index,
+	// keyed by index name. This value is only populated when consumed capacity is set to INDEXES.
+	IndexCapacityUnits map[string]float64
+}
+
+func (stats *ParserStats) addQueryOutput(output *dynamodb.QueryOutput, cached bool) {
+	if cached {
+		stats.PagesFromCache++
+	} else {
+		stats.PagesFetched++
+	}
+	if output.ScannedCount != nil {
+		stats.ItemsScanned += int(*output.ScannedCount)
+	}
+	if output.Count != nil {
+		stats.ItemsReturned += int(*output.Count)
+	}
+	stats.ConsumedCapacity.add(output.ConsumedCapacity)
+}
+
+func (stats *ConsumedCapacityStats) add(capacity *dynamodb.ConsumedCapacity) {
+	if capacity == nil {
+		return
+	}
+
+	if capacity.CapacityUnits != nil {
+		stats.CapacityUnits += *capacity.CapacityUnits
+	}
+	if capacity.Table != nil && capacity.Table.CapacityUnits != nil {
+		stats.TableCapacityUnits += *capacity.Table.CapacityUnits
+	}
+
+	addIndexCapacity := func(indexCapacity map[string]*dynamodb.Capacity) {
+		for indexName, c := range indexCapacity {
+			if c == nil || c.CapacityUnits == nil {
+				continue
+			}
+			if stats.IndexCapacityUnits == nil {
+				stats.IndexCapacityUnits = map[string]float64{}
+			}
+			stats.IndexCapacityUnits[indexName] += *c.CapacityUnits
+		}
+	}
+	addIndexCapacity(capacity.GlobalSecondaryIndexes)
+	addIndexCapacity(capacity.LocalSecondaryIndexes)
+}
+
+func (stats ParserStats) copy() ParserStats {
+	if stats.ConsumedCapacity.IndexCapacityUnits != nil {
+		indexCapacityUnits := map[string]float64{}
+		for k, v := range stats.ConsumedCapacity.IndexCapacityUnits {
+			indexCapacityUnits[k] = v
+		}
+		stats.ConsumedCapacity.IndexCapacityUnits = indexCapacityUnits
+	}
+	return stats
+}
@@ -0,0 +1,101 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestParserStatsCountPagesAndItems(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(5, 2)
+	client := NewClient(svc)
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+		SetReturnConsumedCapacity(dynamodb.ReturnConsumedCapacityTotal)
+	expectSortKeys(t, parseAllRaw(ctx, t, parser), 5)
+
+	stats := parser.Stats()
+	if stats.IndexName != "" {
+		t.Errorf("expected the table's primary index, found %s", stats.IndexName)
+	}
+	if stats.PagesFetched != 3 {
+		t.Errorf("expected 3 pages fetched, found %d", stats.PagesFetched)
+	}
+	if stats.ItemsScanned != 5 || stats.ItemsReturned != 5 {
+		t.Errorf("expected 5 items scanned and returned, found %d scanned and %d returned",
+			stats.ItemsScanned, stats.ItemsReturned)
+	}
+	if stats.ConsumedCapacity.CapacityUnits != 2.5 {
+		t.Errorf("expected 2.5 capacity units, found %v", stats.ConsumedCapacity.CapacityUnits)
+	}
+}
+
+func TestParserStatsOmitCapacityUnlessRequested(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(3, 2)
+	client := NewClient(svc)
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	expectSortKeys(t, parseAllRaw(ctx, t, parser), 3)
+
+	if units := parser.Stats().ConsumedCapacity.CapacityUnits; units != 0 {
+		t.Errorf("expected no capacity units, found %v", units)
+	}
+	if parser.Stats().PagesFetched != 2 {
+		t.Errorf("expected 2 pages fetched, found %d", parser.Stats().PagesFetched)
+	}
+}
+
+func TestConsumedCapacityStatsAddIndexCapacity(t *testing.T) {
+	stats := ParserStats{}
+	for i := 0; i < 2; i++ {
+		stats.addQueryOutput(&dynamodb.QueryOutput{
+			Count:        aws.Int64(2),
+			ScannedCount: aws.Int64(4),
+			ConsumedCapacity: &dynamodb.ConsumedCapacity{
+				CapacityUnits: aws.Float64(1.5),
+				Table:         &dynamodb.Capacity{CapacityUnits: aws.Float64(0.5)},
+				GlobalSecondaryIndexes: map[string]*dynamodb.Capacity{
+					"byStatus": {CapacityUnits: aws.Float64(1)},
+				},
+				LocalSecondaryIndexes: map[string]*dynamodb.Capacity{
+					"byAge": {},
+				},
+			},
+		}, i == 1)
+	}
+
+	if stats.PagesFetched != 1 || stats.PagesFromCache != 1 {
+		t.Errorf("expected 1 page fetched and 1 from cache, found %d and %d",
+			stats.PagesFetched, stats.PagesFromCache)
+	}
+	if stats.ItemsReturned != 4 || stats.ItemsScanned != 8 {
+		t.Errorf("expected 4 items returned of 8 scanned, found %d of %d",
+			stats.ItemsReturned, stats.ItemsScanned)
+	}
+
+	capacity := stats.ConsumedCapacity
+	if capacity.CapacityUnits != 3 || capacity.TableCapacityUnits != 1 {
+		t.Errorf("expected 3 total and 1 table capacity units, found %v and %v",
+			capacity.CapacityUnits, capacity.TableCapacityUnits)
+	}
+	if len(capacity.IndexCapacityUnits) != 1 || capacity.IndexCapacityUnits["byStatus"] != 2 {
+		t.Errorf("expected 2 capacity units on byStatus only, found %v",
+			capacity.IndexCapacityUnits)
+	}
+}
+
+func TestParserStatsReturnsCopy(t *testing.T) {
+	parser := &Parser{}
+	parser.stats.ConsumedCapacity.IndexCapacityUnits = map[string]float64{"byStatus": 1}
+
+	stats := parser.Stats()
+	stats.ConsumedCapacity.IndexCapacityUnits["byStatus"] = 5
+
+	if units := parser.stats.ConsumedCapacity.IndexCapacityUnits["byStatus"]; units != 1 {
+		t.Errorf("expected the parser's stats to be unchanged, found %v", units)
+	}
+}
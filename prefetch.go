@@ -0,0 +1,118 @@
+package autoquery
+
+import "context"
+
+// SetPrefetchDepth enables concurrent page prefetching. When depth is greater than 0, the parser
+// queries subsequent pages in the background while buffered items are consumed with Next. At
+// most depth pages are held in memory ahead of the current page.
+//
+// The background queries use the context passed to the call to Next which started prefetching.
+// If that context is canceled, prefetching is restarted with the context of the next call to
+// Next. Close should be called to stop background queries if the parser is abandoned before
+// parsing is complete.
+//
+// By default, prefetching is disabled and each page is queried when the buffer is drained.
+func (parser *Parser) SetPrefetchDepth(depth int) *Parser {
+	parser.stopPrefetch()
+	parser.prefetchDepth = depth
+	return parser
+}
+
+// Close stops any background page queries and any stream started by the parser, and waits for
+// them to return. Close does not need to be called if parsing is complete, or if neither
+// prefetching nor Stream is used. The parser may continue to be used after Close, in which case
+// prefetching will resume on the next page query.
+func (parser *Parser) Close() error {
+	parser.stopStream()
+	parser.stopPrefetch()
+	return nil
+}
+
+type prefetcher struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	results chan *pageResult
+	done    chan struct{}
+}
+
+func (parser *Parser) startPrefetch(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	pf := &prefetcher{
+		ctx:    ctx,
+		cancel: cancel,
+		// the page being sent is held in addition to the buffered pages
+		results: make(chan *pageResult, parser.prefetchDepth-1),
+		done:    make(chan struct{}),
+	}
+	parser.prefetcher = pf
+
+	// snapshot query state so that the background queries do not race with the parser
+	fetcher := parser.newPageFetcher()
+	queryInput := *parser.queryInput
+	pageNumber := parser.currentPage + 1
+	maxPagesSpecified, maxPages := parser.maxPagesSpecified, parser.maxPages
+
+	go func() {
+		defer close(pf.done)
+		defer close(pf.results)
+		for {
+			input := queryInput
+			page := fetcher.fetchPage(ctx, &input, pageNumber)
+
+			select {
+			case pf.results <- page:
+			case <-ctx.Done():
+				return
+			}
+
+			// stop on error, or when the last page or max pagination has been reached
+			if page.err != nil || len(page.output.LastEvaluatedKey) == 0 ||
+				(maxPagesSpecified && pageNumber >= maxPages) {
+				return
+			}
+
+			queryInput.ExclusiveStartKey = page.output.LastEvaluatedKey
+			pageNumber++
+		}
+	}()
+}
+
+// stopPrefetch cancels the parser's background queries and waits for them to return, so that the
+// parser's state may be modified.
+func (parser *Parser) stopPrefetch() {
+	if parser.prefetcher != nil {
+		parser.prefetcher.cancel()
+		<-parser.prefetcher.done
+		parser.prefetcher = nil
+	}
+}
+
+func (parser *Parser) receivePrefetchedPage(ctx context.Context) (*pageResult, error) {
+	for {
+		if parser.prefetcher == nil {
+			parser.startPrefetch(ctx)
+		}
+		pf := parser.prefetcher
+
+		select {
+		case page, ok := <-pf.results:
+			if !ok || (page.err != nil && pf.ctx.Err() != nil) {
+				// the prefetcher stopped before the parser completed, or failed because the
+				// context it was started with was canceled, so restart from the current state
+				parser.stopPrefetch()
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if page.err != nil {
+				// the failed page is retried from the current state on the next call
+				parser.stopPrefetch()
+				return nil, page.err
+			}
+			return page, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package autoquery
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func parseAllRaw(ctx context.Context, t *testing.T, parser *Parser) []int {
+	t.Helper()
+	sortKeys := []int{}
+	for {
+		item, err := parser.NextRaw(ctx)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			return sortKeys
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		sk, err := strconv.Atoi(aws.StringValue(item["sk"].N))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sortKeys = append(sortKeys, sk)
+	}
+}
+
+func expectSortKeys(t *testing.T, sortKeys []int, n int) {
+	t.Helper()
+	if len(sortKeys) != n {
+		t.Fatalf("expected %d items, found %d", n, len(sortKeys))
+	}
+	for i, sk := range sortKeys {
+		if sk != i {
+			t.Fatalf("expected item %d at position %d, found %d", i, i, sk)
+		}
+	}
+}
+
+func TestPrefetchReturnsAllItems(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(25, 2)
+	client := NewClient(svc)
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).SetPrefetchDepth(3)
+	defer parser.Close()
+
+	expectSortKeys(t, parseAllRaw(ctx, t, parser), 25)
+	if queryCalls, _, _ := svc.calls(); queryCalls != 13 {
+		t.Errorf("expected 13 query calls, found %d", queryCalls)
+	}
+}
+
+func TestPrefetchHoldsAtMostDepthPages(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(20, 1)
+	client := NewClient(svc)
+
+	const depth = 3
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+		SetPrefetchDepth(depth)
+	defer parser.Close()
+
+	if _, err := parser.NextRaw(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the current page and depth pages ahead of it are queried, and no more
+	deadline := time.Now().Add(time.Second)
+	for queryCalls, _, _ := svc.calls(); queryCalls < depth+1; queryCalls, _, _ = svc.calls() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d query calls, found %d", depth+1, queryCalls)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if queryCalls, _, _ := svc.calls(); queryCalls != depth+1 {
+		t.Errorf("expected %d query calls, found %d", depth+1, queryCalls)
+	}
+}
+
+func TestPrefetchSettersDoNotRace(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(30, 2)
+	client := NewClient(svc)
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).SetPrefetchDepth(2)
+	defer parser.Close()
+
+	sortKeys := []int{}
+	for i := 0; ; i++ {
+		// modifying the parser stops the background queries before its state is changed
+		switch i % 3 {
+		case 0:
+			parser.SetRetryPolicy(DefaultRetryPolicy())
+		case 1:
+			parser.UnsetRetryPolicy()
+		case 2:
+			parser.Close()
+		}
+
+		item, err := parser.NextRaw(ctx)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		sk, err := strconv.Atoi(aws.StringValue(item["sk"].N))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sortKeys = append(sortKeys, sk)
+	}
+	expectSortKeys(t, sortKeys, 30)
+}
+
+func TestPrefetchRestartsAfterContextCanceled(t *testing.T) {
+	svc := newFakeService(10, 2)
+	svc.block = make(chan struct{})
+	client := NewClient(svc)
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).SetPrefetchDepth(2)
+	defer parser.Close()
+
+	// the first call is canceled while its page is being queried
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := parser.NextRaw(canceledCtx); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, found %v", err)
+	}
+
+	// later calls with a live context are not failed by the canceled prefetcher
+	close(svc.block)
+	expectSortKeys(t, parseAllRaw(context.Background(), t, parser), 10)
+}
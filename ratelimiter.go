@@ -0,0 +1,185 @@
+package autoquery
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitUnit is the unit of a read rate limit.
+type RateLimitUnit int
+
+const (
+	// ReadCapacityUnitsPerSecond limits reads by the read capacity units consumed by each page
+	// query call, as reported by DynamoDB.
+	ReadCapacityUnitsPerSecond RateLimitUnit = iota
+
+	// RequestsPerSecond limits reads by the number of page query calls.
+	RequestsPerSecond
+)
+
+// ReadRateLimit defines a client-side token bucket limit on page query calls.
+type ReadRateLimit struct {
+	// Rate is the number of tokens added to the bucket per second. If Rate is 0 or less, the
+	// limit is not enforced.
+	Rate float64
+
+	// Burst is the maximum number of tokens in the bucket. If Burst is 0 or less, the bucket
+	// holds up to Rate tokens. In RequestsPerSecond, the bucket always holds at least one token,
+	// so that rates below one request per second still allow a call once a token has been added.
+	Burst float64
+
+	// Unit is the unit of Rate and Burst.
+	Unit RateLimitUnit
+}
+
+// SetTableReadRateLimit sets a read rate limit shared by all queries on a table through the
+// client. The limit does not apply to queries on indexes with their own limit set by
+// SetIndexReadRateLimit.
+//
+// Before each page query call, parsers block until tokens are available in the bucket. When the
+// limit is in ReadCapacityUnitsPerSecond, the bucket is debited by the capacity consumed by each
+// call, and parsers request TOTAL consumed capacity unless INDEXES is set with
+// Parser.SetReturnConsumedCapacity. Items back-fetched from the table are debited from the limit
+// on the table's primary index.
+func (client *Client) SetTableReadRateLimit(tableName string, limit ReadRateLimit) *Client {
+	return client.SetIndexReadRateLimit(tableName, tableRateLimitIndexName, limit)
+}
+
+// SetIndexReadRateLimit sets a read rate limit shared by all queries on a specific index of a
+// table through the client. The table's primary index is specified with an empty indexName.
+//
+// See SetTableReadRateLimit for how the limit is applied.
+func (client *Client) SetIndexReadRateLimit(
+	tableName, indexName string, limit ReadRateLimit) *Client {
+
+	client.rateLimitersMutex.Lock()
+	defer client.rateLimitersMutex.Unlock()
+
+	if client.rateLimiters == nil {
+		client.rateLimiters = map[rateLimiterKey]*tokenBucket{}
+	}
+	client.rateLimiters[rateLimiterKey{tableName, indexName}] = newTokenBucket(limit)
+
+	return client
+}
+
+// UnsetTableReadRateLimit removes the read rate limit on a table.
+func (client *Client) UnsetTableReadRateLimit(tableName string) *Client {
+	return client.UnsetIndexReadRateLimit(tableName, tableRateLimitIndexName)
+}
+
+// UnsetIndexReadRateLimit removes the read rate limit on a specific index of a table.
+func (client *Client) UnsetIndexReadRateLimit(tableName, indexName string) *Client {
+	client.rateLimitersMutex.Lock()
+	defer client.rateLimitersMutex.Unlock()
+
+	delete(client.rateLimiters, rateLimiterKey{tableName, indexName})
+
+	return client
+}
+
+// tableRateLimitIndexName is the index name used for table-wide rate limiters.
+const tableRateLimitIndexName = "#table"
+
+type rateLimiterKey struct {
+	tableName string
+	indexName string
+}
+
+func (client *Client) readRateLimiter(tableName, indexName string) *tokenBucket {
+	client.rateLimitersMutex.Lock()
+	defer client.rateLimitersMutex.Unlock()
+
+	// index limiters take precedence over table limiters
+	if limiter, found := client.rateLimiters[rateLimiterKey{tableName, indexName}]; found {
+		return limiter
+	}
+	return client.rateLimiters[rateLimiterKey{tableName, tableRateLimitIndexName}]
+}
+
+type tokenBucket struct {
+	mutex sync.Mutex
+
+	rate   float64
+	burst  float64
+	unit   RateLimitUnit
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit ReadRateLimit) *tokenBucket {
+	burst := limit.Burst
+	if burst <= 0.0 {
+		burst = limit.Rate
+	}
+	if limit.Unit == RequestsPerSecond && burst < 1.0 {
+		// each call takes a whole token, which a smaller bucket could never hold
+		burst = 1.0
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		unit:   limit.Unit,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a page query call may be made. When the bucket is in requests, one token is
+// taken for the call; when the bucket is in capacity units, the call may be made as soon as the
+// bucket has a positive balance and is debited after the call returns.
+func (bucket *tokenBucket) wait(ctx context.Context) error {
+	if bucket.rate <= 0.0 {
+		return nil
+	}
+
+	for {
+		bucket.mutex.Lock()
+		bucket.refill(time.Now())
+
+		var deficit float64
+		if bucket.unit == RequestsPerSecond {
+			deficit = 1.0 - bucket.tokens
+			if deficit <= 0.0 {
+				bucket.tokens -= 1.0
+			}
+		} else if bucket.tokens <= 0.0 {
+			deficit = -bucket.tokens + 1e-3
+		}
+		bucket.mutex.Unlock()
+
+		if deficit <= 0.0 {
+			return nil
+		}
+
+		timer := time.NewTimer(time.Duration(deficit / bucket.rate * float64(time.Second)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// debit removes consumed capacity units from the bucket.
+func (bucket *tokenBucket) debit(capacityUnits float64) {
+	if bucket.unit != ReadCapacityUnitsPerSecond {
+		return
+	}
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.refill(time.Now())
+	bucket.tokens -= capacityUnits
+}
+
+func (bucket *tokenBucket) refill(now time.Time) {
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.last = now
+}
@@ -0,0 +1,174 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// waitWithin calls bucket.wait with a timeout and returns the wait's duration and error.
+func waitWithin(bucket *tokenBucket, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := bucket.wait(ctx)
+	return time.Since(start), err
+}
+
+func TestTokenBucketRequestsPerSecond(t *testing.T) {
+	bucket := newTokenBucket(ReadRateLimit{Rate: 100, Burst: 2, Unit: RequestsPerSecond})
+
+	// the burst is available immediately, then calls are spaced by the rate
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := waitWithin(bucket, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("expected 4 calls beyond the burst to take at least 40ms, took %v", elapsed)
+	}
+}
+
+func TestTokenBucketFractionalRequestRate(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit ReadRateLimit
+	}{
+		{"default burst", ReadRateLimit{Rate: 0.5, Unit: RequestsPerSecond}},
+		{"fractional burst", ReadRateLimit{Rate: 0.5, Burst: 0.25, Unit: RequestsPerSecond}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket := newTokenBucket(test.limit)
+
+			// the first call is made immediately
+			if _, err := waitWithin(bucket, 100*time.Millisecond); err != nil {
+				t.Fatalf("expected the first call to be allowed, found %v", err)
+			}
+
+			// the next call waits for a token to be added after 2 seconds
+			if _, err := waitWithin(bucket, 20*time.Millisecond); err != context.DeadlineExceeded {
+				t.Fatalf("expected the second call to wait, found %v", err)
+			}
+			bucket.mutex.Lock()
+			bucket.last = bucket.last.Add(-2 * time.Second)
+			bucket.mutex.Unlock()
+			if _, err := waitWithin(bucket, 100*time.Millisecond); err != nil {
+				t.Fatalf("expected a call to be allowed after 2 seconds, found %v", err)
+			}
+		})
+	}
+}
+
+func TestTokenBucketDebitsCapacityUnits(t *testing.T) {
+	bucket := newTokenBucket(ReadRateLimit{Rate: 100, Burst: 10})
+
+	// calls are allowed while the balance is positive, regardless of the capacity they consume
+	if _, err := waitWithin(bucket, 10*time.Millisecond); err != nil {
+		t.Fatalf("expected the first call to be allowed immediately, found %v", err)
+	}
+	bucket.debit(15)
+
+	// the bucket is 5 units in debt, which takes 50ms to repay at 100 units per second
+	elapsed, err := waitWithin(bucket, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed < 40*time.Millisecond {
+		t.Errorf("expected the call to wait about 50ms, waited %v", elapsed)
+	}
+}
+
+func TestTokenBucketIgnoresDebitInRequests(t *testing.T) {
+	bucket := newTokenBucket(ReadRateLimit{Rate: 1, Burst: 1, Unit: RequestsPerSecond})
+	bucket.debit(100)
+	if _, err := waitWithin(bucket, 100*time.Millisecond); err != nil {
+		t.Errorf("expected the call to be allowed, found %v", err)
+	}
+}
+
+func TestTokenBucketWithoutRate(t *testing.T) {
+	bucket := newTokenBucket(ReadRateLimit{})
+	for i := 0; i < 10; i++ {
+		if _, err := waitWithin(bucket, 10*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParserDebitsTableCapacityLimit(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(6, 2)
+	client := NewClient(svc).SetTableReadRateLimit(fakeTableName, ReadRateLimit{
+		Rate:  0.001,
+		Burst: 100,
+	})
+
+	// consumed capacity is requested from each call in order to debit the limit
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	expectSortKeys(t, parseAllRaw(ctx, t, parser), 6)
+
+	if units := parser.Stats().ConsumedCapacity.CapacityUnits; units != 3 {
+		t.Errorf("expected 3 capacity units, found %v", units)
+	}
+	limiter := client.readRateLimiter(fakeTableName, "")
+	limiter.mutex.Lock()
+	tokens := limiter.tokens
+	limiter.mutex.Unlock()
+	if tokens > 97.01 {
+		t.Errorf("expected 3 capacity units to be debited, found %v tokens", tokens)
+	}
+}
+
+func TestParserKeepsRequestedCapacityUnderLimit(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(2, 2)
+	client := NewClient(svc).SetTableReadRateLimit(fakeTableName, ReadRateLimit{Rate: 100})
+
+	// INDEXES includes the total consumed capacity, so it is not overridden
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+		SetReturnConsumedCapacity(dynamodb.ReturnConsumedCapacityIndexes)
+	if _, err := parser.NextRaw(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if capacity := *parser.queryInput.ReturnConsumedCapacity; capacity != "INDEXES" {
+		t.Errorf("expected INDEXES consumed capacity, found %s", capacity)
+	}
+
+	// NONE is overridden, since the limit requires consumed capacity
+	parser = client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+		SetReturnConsumedCapacity(dynamodb.ReturnConsumedCapacityNone)
+	if _, err := parser.NextRaw(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if capacity := *parser.queryInput.ReturnConsumedCapacity; capacity != "TOTAL" {
+		t.Errorf("expected TOTAL consumed capacity, found %s", capacity)
+	}
+}
+
+func TestParserWaitsForRequestLimit(t *testing.T) {
+	svc := newFakeService(4, 1)
+	client := NewClient(svc).SetIndexReadRateLimit(fakeTableName, "", ReadRateLimit{
+		Rate: 0.5,
+		Unit: RequestsPerSecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	if _, err := parser.NextRaw(ctx); err != nil {
+		t.Fatalf("expected the first page to be queried, found %v", err)
+	}
+	if _, err := parser.NextRaw(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the second page to wait for the limit, found %v", err)
+	}
+	if queryCalls, _, _ := svc.calls(); queryCalls != 1 {
+		t.Errorf("expected 1 query call, found %d", queryCalls)
+	}
+}
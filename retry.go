@@ -0,0 +1,127 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// RetryPolicy defines how failed page query calls are retried by a Parser. A retried page is
+// queried again with the same input, so the parser's position in the query is not lost.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts for each page query call, including the first
+	// attempt. If MaxAttempts is 1 or less, failed calls are not retried.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. The delay doubles after each failed attempt.
+	BaseDelay time.Duration
+
+	// MaxDelay is the maximum delay between attempts. If MaxDelay is 0, the delay is not capped,
+	// although it stops doubling once it reaches the maximum time.Duration.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction of each delay which is randomized, between 0.0 and 1.0. For example,
+	// a Jitter of 0.5 results in a delay between 50% and 100% of the exponential backoff delay.
+	Jitter float64
+
+	// Retryable determines whether a failed call should be retried. If Retryable is nil,
+	// IsRetryableError is used.
+	Retryable func(err error) bool
+}
+
+// maxRetryDelay is the delay limit when a policy's MaxDelay is not set, which prevents doubled
+// delays from overflowing.
+const maxRetryDelay = time.Duration(math.MaxInt64)
+
+// DefaultRetryPolicy returns a retry policy with 5 max attempts, exponential backoff starting at
+// 50 milliseconds up to 5 seconds, and full jitter.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      1.0,
+	}
+}
+
+// IsRetryableError returns true if err is a DynamoDB throttling error or an internal server
+// error.
+func IsRetryableError(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	switch aerr.Code() {
+	case dynamodb.ErrCodeProvisionedThroughputExceededException,
+		dynamodb.ErrCodeRequestLimitExceeded,
+		dynamodb.ErrCodeInternalServerError,
+		"ThrottlingException",
+		"ServiceUnavailable":
+		return true
+	}
+
+	return false
+}
+
+// do calls fn until it succeeds, returns a non-retryable error, or max attempts have been made.
+// The number of retries is returned along with the final error.
+func (policy *RetryPolicy) do(ctx context.Context, fn func() error) (int, error) {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryableError
+	}
+
+	retries := 0
+	for {
+		err := fn()
+		if err == nil || retries+1 >= policy.MaxAttempts || !retryable(err) {
+			return retries, err
+		}
+
+		timer := time.NewTimer(policy.delay(retries))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retries, ctx.Err()
+		case <-timer.C:
+		}
+
+		retries++
+	}
+}
+
+func (policy *RetryPolicy) delay(retry int) time.Duration {
+	maxDelay := policy.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = maxRetryDelay
+	}
+
+	delay := policy.BaseDelay
+	for i := 0; i < retry && delay < maxDelay; i++ {
+		if delay > maxDelay/2 {
+			// doubling would exceed the max delay, or overflow if the delay is not capped
+			delay = maxDelay
+			break
+		}
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	jitter := policy.Jitter
+	if jitter > 1.0 {
+		jitter = 1.0
+	}
+	if jitter > 0.0 {
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+
+	return delay
+}
@@ -0,0 +1,227 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func throttlingError() error {
+	return awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throttled", nil)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"throughput exceeded", throttlingError(), true},
+		{"request limit exceeded",
+			awserr.New(dynamodb.ErrCodeRequestLimitExceeded, "", nil), true},
+		{"internal server error", awserr.New(dynamodb.ErrCodeInternalServerError, "", nil), true},
+		{"throttling", awserr.New("ThrottlingException", "", nil), true},
+		{"service unavailable", awserr.New("ServiceUnavailable", "", nil), true},
+		{"wrapped throttling", fmt.Errorf("page 2: %w", throttlingError()), true},
+		{"validation", awserr.New("ValidationException", "", nil), false},
+		{"condition failed",
+			awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil), false},
+		{"not an aws error", errors.New("throttled"), false},
+		{"context canceled", context.Canceled, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if retryable := IsRetryableError(test.err); retryable != test.retryable {
+				t.Errorf("expected retryable to be %v", test.retryable)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyAttempts(t *testing.T) {
+	ctx := context.Background()
+	validationErr := awserr.New("ValidationException", "", nil)
+
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		errs      []error
+		attempts  int
+		retries   int
+		succeeded bool
+	}{
+		{
+			name:      "succeeds after retries",
+			policy:    RetryPolicy{MaxAttempts: 3},
+			errs:      []error{throttlingError(), throttlingError()},
+			attempts:  3,
+			retries:   2,
+			succeeded: true,
+		},
+		{
+			name:     "stops at max attempts",
+			policy:   RetryPolicy{MaxAttempts: 3},
+			errs:     []error{throttlingError(), throttlingError(), throttlingError()},
+			attempts: 3,
+			retries:  2,
+		},
+		{
+			name:     "does not retry with one max attempt",
+			policy:   RetryPolicy{MaxAttempts: 1},
+			errs:     []error{throttlingError()},
+			attempts: 1,
+		},
+		{
+			name:     "does not retry non-retryable errors",
+			policy:   RetryPolicy{MaxAttempts: 3},
+			errs:     []error{validationErr},
+			attempts: 1,
+		},
+		{
+			name: "uses custom classifier",
+			policy: RetryPolicy{MaxAttempts: 3, Retryable: func(err error) bool {
+				return err == validationErr
+			}},
+			errs:      []error{validationErr},
+			attempts:  2,
+			retries:   1,
+			succeeded: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			retries, err := test.policy.do(ctx, func() error {
+				attempts++
+				if attempts <= len(test.errs) {
+					return test.errs[attempts-1]
+				}
+				return nil
+			})
+			if attempts != test.attempts || retries != test.retries {
+				t.Errorf("expected %d attempts and %d retries, found %d and %d",
+					test.attempts, test.retries, attempts, retries)
+			}
+			if succeeded := err == nil; succeeded != test.succeeded {
+				t.Errorf("expected success to be %v, found error %v", test.succeeded, err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Minute}
+	attempts := 0
+	_, err := policy.do(ctx, func() error {
+		attempts++
+		return throttlingError()
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, found %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, found %d", attempts)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for retry, delay := range expected {
+		if found := policy.delay(retry); found != delay*time.Millisecond {
+			t.Errorf("retry %d: expected delay %v, found %v", retry, delay*time.Millisecond, found)
+		}
+	}
+}
+
+func TestRetryPolicyDelayWithoutMaxDoesNotOverflow(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 50 * time.Millisecond, Jitter: 1.0}
+	previous := time.Duration(0)
+	for retry := 0; retry < 100; retry++ {
+		unjittered := RetryPolicy{BaseDelay: policy.BaseDelay}
+		delay := unjittered.delay(retry)
+		if delay < previous {
+			t.Fatalf("retry %d: delay decreased from %v to %v", retry, previous, delay)
+		}
+		previous = delay
+
+		if jittered := policy.delay(retry); jittered < 0 || jittered > delay {
+			t.Fatalf("retry %d: expected delay between 0 and %v, found %v",
+				retry, delay, jittered)
+		}
+	}
+	if previous != maxRetryDelay {
+		t.Errorf("expected delay to stop at %v, found %v", maxRetryDelay, previous)
+	}
+}
+
+func TestRetryPolicyJitterBounds(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 1000; i++ {
+		delay := policy.delay(1)
+		if delay < 100*time.Millisecond || delay > 200*time.Millisecond {
+			t.Fatalf("expected delay between 100ms and 200ms, found %v", delay)
+		}
+	}
+
+	// jitter above 1.0 is treated as full jitter
+	policy.Jitter = 2.0
+	for i := 0; i < 1000; i++ {
+		if delay := policy.delay(0); delay < 0 || delay > 100*time.Millisecond {
+			t.Fatalf("expected delay between 0 and 100ms, found %v", delay)
+		}
+	}
+}
+
+func TestParserRetriesPageInPlace(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(6, 2)
+	// the first query succeeds, then the second page is throttled twice
+	svc.queryErrs = []error{nil, throttlingError(), throttlingError()}
+	client := NewClient(svc)
+	client.RetryPolicy = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	expectSortKeys(t, parseAllRaw(ctx, t, parser), 6)
+
+	if retries := parser.Stats().Retries; retries != 2 {
+		t.Errorf("expected 2 retries, found %d", retries)
+	}
+	if queryCalls, _, _ := svc.calls(); queryCalls != 5 {
+		t.Errorf("expected 5 query calls, found %d", queryCalls)
+	}
+}
+
+func TestParserKeepsPositionAfterFailedPage(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(6, 2)
+	svc.queryErrs = []error{nil, throttlingError()}
+	client := NewClient(svc)
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	sortKeys := []int{}
+	for i := 0; i < 2; i++ {
+		item := testItem{}
+		if err := parser.Next(ctx, &item); err != nil {
+			t.Fatal(err)
+		}
+		sortKeys = append(sortKeys, item.SK)
+	}
+
+	// without a retry policy the error is returned, and the next call queries the page again
+	if _, err := parser.NextRaw(ctx); !IsRetryableError(err) {
+		t.Fatalf("expected a throttling error, found %v", err)
+	}
+	sortKeys = append(sortKeys, parseAllRaw(ctx, t, parser)...)
+	expectSortKeys(t, sortKeys, 6)
+}
@@ -0,0 +1,363 @@
+package autoquery
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// TableSchema defines a DynamoDB table, including its key schema, secondary indexes, time to live
+// attribute and billing mode. A schema may be built programmatically or derived from struct tags
+// with SchemaFromStruct.
+//
+// A schema may be used to create or update a table with Client.CreateTable and
+// Client.UpdateTable, and may be used as a static TableDescriptionProvider.
+type TableSchema struct {
+	TableName    string
+	PartitionKey KeyAttribute
+	SortKey      *KeyAttribute
+
+	GlobalSecondaryIndexes []IndexSchema
+	LocalSecondaryIndexes  []IndexSchema
+
+	// TimeToLiveAttribute is the name of the attribute used as the time to live of items.
+	// If empty, time to live is disabled.
+	TimeToLiveAttribute string
+
+	// BillingMode is the billing mode of the table, either dynamodb.BillingModePayPerRequest or
+	// dynamodb.BillingModeProvisioned. If empty, the billing mode is PAY_PER_REQUEST.
+	BillingMode string
+
+	// ProvisionedThroughput is the throughput of the table, which is required when the billing
+	// mode is PROVISIONED.
+	ProvisionedThroughput *Throughput
+}
+
+// KeyAttribute is a key attribute of a table or index.
+type KeyAttribute struct {
+	Name string
+	// Type is the scalar attribute type of the key, one of dynamodb.ScalarAttributeTypeS,
+	// dynamodb.ScalarAttributeTypeN or dynamodb.ScalarAttributeTypeB.
+	Type string
+}
+
+// IndexSchema defines a secondary index of a table. Local secondary indexes always use the
+// table's partition key, so their PartitionKey is ignored.
+type IndexSchema struct {
+	Name         string
+	PartitionKey KeyAttribute
+	SortKey      *KeyAttribute
+
+	// ProjectionType is the projection of the index, one of dynamodb.ProjectionTypeAll,
+	// dynamodb.ProjectionTypeKeysOnly or dynamodb.ProjectionTypeInclude. If empty, all attributes
+	// are projected.
+	ProjectionType string
+	// NonKeyAttributes are the projected attributes when the projection type is INCLUDE.
+	NonKeyAttributes []string
+
+	// ProvisionedThroughput is the throughput of a global secondary index when the table's
+	// billing mode is PROVISIONED. If nil, the table's throughput is used.
+	ProvisionedThroughput *Throughput
+}
+
+// Throughput is the provisioned read and write capacity of a table or index.
+type Throughput struct {
+	ReadCapacityUnits  int64
+	WriteCapacityUnits int64
+}
+
+// SchemaFromStruct derives a table schema from the autoquery struct tags of item. Key attributes
+// and the time to live attribute are marked with the following tag options:
+//
+//	autoquery:"pk"           // partition key of the table
+//	autoquery:"sk"           // sort key of the table
+//	autoquery:"gsi:name:pk"  // partition key of global secondary index "name"
+//	autoquery:"gsi:name:sk"  // sort key of global secondary index "name"
+//	autoquery:"lsi:name:sk"  // sort key of local secondary index "name"
+//	autoquery:"ttl"          // time to live attribute
+//
+// Multiple options may be combined on a field, separated by commas. Key attribute types are
+// inferred from field types: strings are S, numbers are N and byte slices are B.
+//
+// Derived indexes project all attributes and the billing mode is PAY_PER_REQUEST. The returned
+// schema may be modified before use.
+func SchemaFromStruct(tableName string, item interface{}) (*TableSchema, error) {
+	itemType := structType(item)
+	if itemType == nil {
+		return nil, fmt.Errorf("schema item must be a struct, got %T", item)
+	}
+
+	schema := &TableSchema{TableName: tableName}
+	gsis := map[string]*IndexSchema{}
+	lsis := map[string]*IndexSchema{}
+	gsiNames, lsiNames := []string{}, []string{}
+
+	for _, field := range structFields(itemType) {
+		for _, option := range strings.Split(field.Tag.Get(autoqueryTagKey), ",") {
+			if option == "" || option == versionTagValue {
+				continue
+			}
+
+			if option == "ttl" {
+				schema.TimeToLiveAttribute = attributeName(field)
+				continue
+			}
+
+			keyAttribute, err := fieldKeyAttribute(field)
+			if err != nil {
+				return nil, err
+			}
+
+			parts := strings.Split(option, ":")
+			switch {
+			case option == "pk":
+				schema.PartitionKey = keyAttribute
+			case option == "sk":
+				schema.SortKey = &keyAttribute
+			case len(parts) == 3 && parts[0] == "gsi" && (parts[2] == "pk" || parts[2] == "sk"):
+				index, found := gsis[parts[1]]
+				if !found {
+					index = &IndexSchema{Name: parts[1]}
+					gsis[parts[1]] = index
+					gsiNames = append(gsiNames, parts[1])
+				}
+				if parts[2] == "pk" {
+					index.PartitionKey = keyAttribute
+				} else {
+					index.SortKey = &keyAttribute
+				}
+			case len(parts) == 3 && parts[0] == "lsi" && parts[2] == "sk":
+				index, found := lsis[parts[1]]
+				if !found {
+					index = &IndexSchema{Name: parts[1]}
+					lsis[parts[1]] = index
+					lsiNames = append(lsiNames, parts[1])
+				}
+				index.SortKey = &keyAttribute
+			default:
+				return nil, fmt.Errorf("invalid autoquery tag option %q on field %s",
+					option, field.Name)
+			}
+		}
+	}
+
+	for _, name := range gsiNames {
+		schema.GlobalSecondaryIndexes = append(schema.GlobalSecondaryIndexes, *gsis[name])
+	}
+	for _, name := range lsiNames {
+		lsi := *lsis[name]
+		lsi.PartitionKey = schema.PartitionKey
+		schema.LocalSecondaryIndexes = append(schema.LocalSecondaryIndexes, lsi)
+	}
+
+	if err := schema.validate(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// fieldKeyAttribute returns the key attribute of a struct field, inferring its scalar type.
+func fieldKeyAttribute(field reflect.StructField) (KeyAttribute, error) {
+	keyAttribute := KeyAttribute{Name: attributeName(field)}
+
+	fieldType := field.Type
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	tagOptions := strings.Split(field.Tag.Get("dynamodbav"), ",")[1:]
+	switch {
+	case containsString(tagOptions, "string"):
+		keyAttribute.Type = dynamodb.ScalarAttributeTypeS
+	case fieldType == reflect.TypeOf(time.Time{}):
+		if containsString(tagOptions, "unixtime") {
+			keyAttribute.Type = dynamodb.ScalarAttributeTypeN
+		} else {
+			keyAttribute.Type = dynamodb.ScalarAttributeTypeS
+		}
+	case fieldType.Kind() == reflect.String:
+		keyAttribute.Type = dynamodb.ScalarAttributeTypeS
+	case fieldType.Kind() >= reflect.Int && fieldType.Kind() <= reflect.Float64:
+		keyAttribute.Type = dynamodb.ScalarAttributeTypeN
+	case (fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array) &&
+		fieldType.Elem().Kind() == reflect.Uint8:
+		keyAttribute.Type = dynamodb.ScalarAttributeTypeB
+	default:
+		return keyAttribute, fmt.Errorf("key field %s has unsupported type %s",
+			field.Name, field.Type)
+	}
+
+	return keyAttribute, nil
+}
+
+func (schema *TableSchema) validate() error {
+	if schema.TableName == "" {
+		return fmt.Errorf("schema does not specify a table name")
+	} else if schema.PartitionKey.Name == "" {
+		return fmt.Errorf("schema for table %s does not specify a partition key",
+			schema.TableName)
+	} else if schema.billingMode() == dynamodb.BillingModeProvisioned &&
+		schema.ProvisionedThroughput == nil {
+		return fmt.Errorf("schema for table %s uses provisioned billing without throughput",
+			schema.TableName)
+	}
+
+	for _, gsi := range schema.GlobalSecondaryIndexes {
+		if gsi.PartitionKey.Name == "" {
+			return fmt.Errorf("global secondary index %s does not specify a partition key",
+				gsi.Name)
+		}
+	}
+	for _, lsi := range schema.LocalSecondaryIndexes {
+		if lsi.SortKey == nil {
+			return fmt.Errorf("local secondary index %s does not specify a sort key", lsi.Name)
+		}
+	}
+
+	// key attributes shared by the table and its indexes must have the same type
+	types := map[string]string{}
+	for _, keyAttribute := range schema.keyAttributes() {
+		if t, found := types[keyAttribute.Name]; found && t != keyAttribute.Type {
+			return fmt.Errorf("key attribute %s has conflicting types %s and %s",
+				keyAttribute.Name, t, keyAttribute.Type)
+		}
+		types[keyAttribute.Name] = keyAttribute.Type
+	}
+
+	return nil
+}
+
+func (schema *TableSchema) billingMode() string {
+	if schema.BillingMode == "" {
+		return dynamodb.BillingModePayPerRequest
+	}
+	return schema.BillingMode
+}
+
+// keyAttributes returns the key attributes of the table and its indexes.
+func (schema *TableSchema) keyAttributes() []KeyAttribute {
+	keyAttributes := keySchemaAttributes(schema.PartitionKey, schema.SortKey)
+	for _, gsi := range schema.GlobalSecondaryIndexes {
+		keyAttributes = append(keyAttributes,
+			keySchemaAttributes(gsi.PartitionKey, gsi.SortKey)...)
+	}
+	for _, lsi := range schema.LocalSecondaryIndexes {
+		keyAttributes = append(keyAttributes,
+			keySchemaAttributes(schema.PartitionKey, lsi.SortKey)...)
+	}
+	return keyAttributes
+}
+
+// attributeDefinitions returns the attribute definitions of the given key attributes, without
+// duplicates.
+func attributeDefinitions(keyAttributes []KeyAttribute) []*dynamodb.AttributeDefinition {
+	definitions := []*dynamodb.AttributeDefinition{}
+	defined := map[string]bool{}
+	for _, keyAttribute := range keyAttributes {
+		if defined[keyAttribute.Name] {
+			continue
+		}
+		defined[keyAttribute.Name] = true
+		definitions = append(definitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(keyAttribute.Name),
+			AttributeType: aws.String(keyAttribute.Type),
+		})
+	}
+	return definitions
+}
+
+func keySchemaAttributes(partitionKey KeyAttribute, sortKey *KeyAttribute) []KeyAttribute {
+	if sortKey == nil {
+		return []KeyAttribute{partitionKey}
+	}
+	return []KeyAttribute{partitionKey, *sortKey}
+}
+
+func keySchema(partitionKey KeyAttribute, sortKey *KeyAttribute) []*dynamodb.KeySchemaElement {
+	elements := []*dynamodb.KeySchemaElement{{
+		AttributeName: aws.String(partitionKey.Name),
+		KeyType:       aws.String(dynamodb.KeyTypeHash),
+	}}
+	if sortKey != nil {
+		elements = append(elements, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(sortKey.Name),
+			KeyType:       aws.String(dynamodb.KeyTypeRange),
+		})
+	}
+	return elements
+}
+
+func (index IndexSchema) projection() *dynamodb.Projection {
+	projection := &dynamodb.Projection{ProjectionType: aws.String(index.projectionType())}
+	if index.projectionType() == dynamodb.ProjectionTypeInclude {
+		projection.NonKeyAttributes = aws.StringSlice(index.NonKeyAttributes)
+	}
+	return projection
+}
+
+func (index IndexSchema) projectionType() string {
+	if index.ProjectionType == "" {
+		return dynamodb.ProjectionTypeAll
+	}
+	return index.ProjectionType
+}
+
+func (throughput *Throughput) provisionedThroughput() *dynamodb.ProvisionedThroughput {
+	return &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(throughput.ReadCapacityUnits),
+		WriteCapacityUnits: aws.Int64(throughput.WriteCapacityUnits),
+	}
+}
+
+// gsiThroughput returns the throughput of a global secondary index, or nil if the table's billing
+// mode is not PROVISIONED.
+func (schema *TableSchema) gsiThroughput(index IndexSchema) *dynamodb.ProvisionedThroughput {
+	if schema.billingMode() != dynamodb.BillingModeProvisioned {
+		return nil
+	} else if index.ProvisionedThroughput != nil {
+		return index.ProvisionedThroughput.provisionedThroughput()
+	}
+	return schema.ProvisionedThroughput.provisionedThroughput()
+}
+
+// CreateTableInput returns the input to create the table defined by the schema. The time to live
+// attribute is not part of the input, since it is enabled separately with UpdateTimeToLive.
+func (schema *TableSchema) CreateTableInput() (*dynamodb.CreateTableInput, error) {
+	if err := schema.validate(); err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.CreateTableInput{
+		TableName:            aws.String(schema.TableName),
+		KeySchema:            keySchema(schema.PartitionKey, schema.SortKey),
+		AttributeDefinitions: attributeDefinitions(schema.keyAttributes()),
+		BillingMode:          aws.String(schema.billingMode()),
+	}
+	if schema.billingMode() == dynamodb.BillingModeProvisioned {
+		input.ProvisionedThroughput = schema.ProvisionedThroughput.provisionedThroughput()
+	}
+
+	for _, gsi := range schema.GlobalSecondaryIndexes {
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes,
+			&dynamodb.GlobalSecondaryIndex{
+				IndexName:             aws.String(gsi.Name),
+				KeySchema:             keySchema(gsi.PartitionKey, gsi.SortKey),
+				Projection:            gsi.projection(),
+				ProvisionedThroughput: schema.gsiThroughput(gsi),
+			})
+	}
+	for _, lsi := range schema.LocalSecondaryIndexes {
+		input.LocalSecondaryIndexes = append(input.LocalSecondaryIndexes,
+			&dynamodb.LocalSecondaryIndex{
+				IndexName:  aws.String(lsi.Name),
+				KeySchema:  keySchema(schema.PartitionKey, lsi.SortKey),
+				Projection: lsi.projection(),
+			})
+	}
+
+	return input, nil
+}
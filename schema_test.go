@@ -0,0 +1,187 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type schemaItem struct {
+	UserID  string    `dynamodbav:"user_id" autoquery:"pk"`
+	Created time.Time `dynamodbav:"created,unixtime" autoquery:"sk"`
+	Score   int       `dynamodbav:"score" autoquery:"lsi:by-score:sk"`
+	Email   string    `autoquery:"gsi:by-email:pk"`
+	Group   []byte    `dynamodbav:"group" autoquery:"gsi:by-group:pk"`
+	ID      int64     `dynamodbav:"id,string" autoquery:"gsi:by-group:sk"`
+	Expires time.Time `dynamodbav:"expires,unixtime" autoquery:"ttl"`
+	Version int       `autoquery:"version"`
+	Ignored string    `dynamodbav:"-" autoquery:"pk"`
+}
+
+func expectKeySchema(t *testing.T, keySchema []*dynamodb.KeySchemaElement, names ...string) {
+	t.Helper()
+	if len(keySchema) != len(names) {
+		t.Fatalf("expected key schema %v, found %v", names, keySchema)
+	}
+	for i, name := range names {
+		keyType := dynamodb.KeyTypeHash
+		if i > 0 {
+			keyType = dynamodb.KeyTypeRange
+		}
+		if aws.StringValue(keySchema[i].AttributeName) != name ||
+			aws.StringValue(keySchema[i].KeyType) != keyType {
+			t.Errorf("expected key schema %v, found %v", names, keySchema)
+		}
+	}
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	schema, err := SchemaFromStruct("Users", &schemaItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.TimeToLiveAttribute != "expires" {
+		t.Errorf("expected time to live attribute expires, found %s", schema.TimeToLiveAttribute)
+	}
+
+	input, err := schema.CreateTableInput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.StringValue(input.TableName) != "Users" ||
+		aws.StringValue(input.BillingMode) != dynamodb.BillingModePayPerRequest ||
+		input.ProvisionedThroughput != nil {
+		t.Errorf("unexpected table input: %v", input)
+	}
+	expectKeySchema(t, input.KeySchema, "user_id", "created")
+
+	expectedTypes := map[string]string{
+		"user_id": "S", "created": "N", "score": "N", "Email": "S", "group": "B", "id": "S",
+	}
+	if len(input.AttributeDefinitions) != len(expectedTypes) {
+		t.Errorf("expected %d attribute definitions, found %v",
+			len(expectedTypes), input.AttributeDefinitions)
+	}
+	for _, definition := range input.AttributeDefinitions {
+		name := aws.StringValue(definition.AttributeName)
+		if aws.StringValue(definition.AttributeType) != expectedTypes[name] {
+			t.Errorf("unexpected attribute definition: %v", definition)
+		}
+	}
+
+	if len(input.GlobalSecondaryIndexes) != 2 || len(input.LocalSecondaryIndexes) != 1 {
+		t.Fatalf("expected 2 global and 1 local secondary indexes, found %v", input)
+	}
+	byEmail, byGroup := input.GlobalSecondaryIndexes[0], input.GlobalSecondaryIndexes[1]
+	expectKeySchema(t, byEmail.KeySchema, "Email")
+	expectKeySchema(t, byGroup.KeySchema, "group", "id")
+	if aws.StringValue(byGroup.Projection.ProjectionType) != dynamodb.ProjectionTypeAll ||
+		byGroup.ProvisionedThroughput != nil {
+		t.Errorf("unexpected global secondary index: %v", byGroup)
+	}
+	expectKeySchema(t, input.LocalSecondaryIndexes[0].KeySchema, "user_id", "score")
+}
+
+func TestSchemaFromStructErrors(t *testing.T) {
+	tests := map[string]interface{}{
+		"not a struct": "item",
+		"invalid option": struct {
+			ID string `autoquery:"pk,gsi:name"`
+		}{},
+		"unsupported key type": struct {
+			ID struct{} `autoquery:"pk"`
+		}{},
+		"no partition key": struct {
+			ID string `autoquery:"sk"`
+		}{},
+		"index without partition key": struct {
+			ID   string `autoquery:"pk"`
+			Name string `autoquery:"gsi:by-name:sk"`
+		}{},
+	}
+	for name, item := range tests {
+		if _, err := SchemaFromStruct("Items", item); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCreateTableInputProvisioned(t *testing.T) {
+	schema := &TableSchema{
+		TableName:             "Items",
+		PartitionKey:          KeyAttribute{Name: "pk", Type: "S"},
+		BillingMode:           dynamodb.BillingModeProvisioned,
+		ProvisionedThroughput: &Throughput{ReadCapacityUnits: 5, WriteCapacityUnits: 2},
+		GlobalSecondaryIndexes: []IndexSchema{
+			{
+				Name:                  "own-throughput",
+				PartitionKey:          KeyAttribute{Name: "a", Type: "S"},
+				ProjectionType:        dynamodb.ProjectionTypeInclude,
+				NonKeyAttributes:      []string{"b"},
+				ProvisionedThroughput: &Throughput{ReadCapacityUnits: 1, WriteCapacityUnits: 1},
+			},
+			{Name: "table-throughput", PartitionKey: KeyAttribute{Name: "b", Type: "S"}},
+		},
+	}
+
+	input, err := schema.CreateTableInput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.Int64Value(input.ProvisionedThroughput.ReadCapacityUnits) != 5 {
+		t.Errorf("unexpected table throughput: %v", input.ProvisionedThroughput)
+	}
+	own, table := input.GlobalSecondaryIndexes[0], input.GlobalSecondaryIndexes[1]
+	if aws.Int64Value(own.ProvisionedThroughput.ReadCapacityUnits) != 1 ||
+		aws.Int64Value(table.ProvisionedThroughput.ReadCapacityUnits) != 5 {
+		t.Errorf("unexpected index throughput: %v and %v",
+			own.ProvisionedThroughput, table.ProvisionedThroughput)
+	}
+	if len(own.Projection.NonKeyAttributes) != 1 {
+		t.Errorf("unexpected projection: %v", own.Projection)
+	}
+
+	schema.ProvisionedThroughput = nil
+	if _, err := schema.CreateTableInput(); err == nil {
+		t.Error("expected an error for provisioned billing without throughput")
+	}
+}
+
+func TestSchemaValidatesKeyTypes(t *testing.T) {
+	schema := &TableSchema{
+		TableName:    "Items",
+		PartitionKey: KeyAttribute{Name: "pk", Type: "S"},
+		GlobalSecondaryIndexes: []IndexSchema{
+			{Name: "by-pk", PartitionKey: KeyAttribute{Name: "pk", Type: "N"}},
+		},
+	}
+	if _, err := schema.CreateTableInput(); err == nil {
+		t.Error("expected an error for conflicting key attribute types")
+	}
+}
+
+func TestSchemaDescriptionProvider(t *testing.T) {
+	ctx := context.Background()
+	schema, err := SchemaFromStruct("Users", schemaItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewSchemaDescriptionProvider(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	description, err := provider.Get(ctx, "Users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(description.GlobalSecondaryIndexes) != 2 || aws.Int64Value(description.ItemCount) != 0 {
+		t.Errorf("unexpected description: %v", description)
+	}
+	if _, err := provider.Get(ctx, "Other"); err == nil {
+		t.Error("expected an error for a table without a schema")
+	}
+}
@@ -0,0 +1,203 @@
+package autoquery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// SchemaDiff describes the differences between a table schema and an existing table.
+//
+// Indexes are listed by name. An index is changed if its key schema or projection differs, which
+// requires the index to be recreated. Provisioned throughput of secondary indexes is not compared.
+type SchemaDiff struct {
+	KeySchemaChanged             bool
+	BillingModeChanged           bool
+	ProvisionedThroughputChanged bool
+	TimeToLiveChanged            bool
+
+	AddedGlobalSecondaryIndexes   []string
+	RemovedGlobalSecondaryIndexes []string
+	ChangedGlobalSecondaryIndexes []string
+
+	AddedLocalSecondaryIndexes   []string
+	RemovedLocalSecondaryIndexes []string
+	ChangedLocalSecondaryIndexes []string
+}
+
+// IsEmpty returns true if the schema matches the existing table.
+func (diff *SchemaDiff) IsEmpty() bool {
+	return !diff.KeySchemaChanged && !diff.BillingModeChanged &&
+		!diff.ProvisionedThroughputChanged && !diff.TimeToLiveChanged &&
+		len(diff.AddedGlobalSecondaryIndexes) == 0 &&
+		len(diff.RemovedGlobalSecondaryIndexes) == 0 &&
+		len(diff.ChangedGlobalSecondaryIndexes) == 0 &&
+		!diff.localSecondaryIndexesChanged()
+}
+
+func (diff *SchemaDiff) localSecondaryIndexesChanged() bool {
+	return len(diff.AddedLocalSecondaryIndexes) > 0 ||
+		len(diff.RemovedLocalSecondaryIndexes) > 0 ||
+		len(diff.ChangedLocalSecondaryIndexes) > 0
+}
+
+func (diff *SchemaDiff) String() string {
+	changes := []string{}
+	addChange := func(condition bool, change string) {
+		if condition {
+			changes = append(changes, change)
+		}
+	}
+	addIndexChanges := func(kind string, indexNames []string) {
+		for _, indexName := range indexNames {
+			changes = append(changes, fmt.Sprintf("%s %s", kind, indexName))
+		}
+	}
+
+	addChange(diff.KeySchemaChanged, "key schema changed")
+	addChange(diff.BillingModeChanged, "billing mode changed")
+	addChange(diff.ProvisionedThroughputChanged, "provisioned throughput changed")
+	addChange(diff.TimeToLiveChanged, "time to live changed")
+	addIndexChanges("added global secondary index", diff.AddedGlobalSecondaryIndexes)
+	addIndexChanges("removed global secondary index", diff.RemovedGlobalSecondaryIndexes)
+	addIndexChanges("changed global secondary index", diff.ChangedGlobalSecondaryIndexes)
+	addIndexChanges("added local secondary index", diff.AddedLocalSecondaryIndexes)
+	addIndexChanges("removed local secondary index", diff.RemovedLocalSecondaryIndexes)
+	addIndexChanges("changed local secondary index", diff.ChangedLocalSecondaryIndexes)
+
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, "; ")
+}
+
+// Diff compares the schema with a table description, such as the output of DescribeTable. Since
+// table descriptions do not include time to live settings, TimeToLiveChanged is always false; use
+// Client.DiffTable to also compare time to live.
+func (schema *TableSchema) Diff(description *dynamodb.TableDescription) *SchemaDiff {
+	diff := &SchemaDiff{}
+	types := describedAttributeTypes(description.AttributeDefinitions)
+	keyAttributes := schema.keyAttributes()
+
+	diff.KeySchemaChanged = !keySchemaEqual(
+		keySchema(schema.PartitionKey, schema.SortKey), description.KeySchema,
+		keyAttributes, types)
+
+	describedBillingMode := dynamodb.BillingModeProvisioned
+	if description.BillingModeSummary != nil && description.BillingModeSummary.BillingMode != nil {
+		describedBillingMode = *description.BillingModeSummary.BillingMode
+	}
+	diff.BillingModeChanged = schema.billingMode() != describedBillingMode
+
+	if schema.billingMode() == dynamodb.BillingModeProvisioned && !diff.BillingModeChanged {
+		throughput := description.ProvisionedThroughput
+		diff.ProvisionedThroughputChanged = throughput == nil ||
+			aws.Int64Value(throughput.ReadCapacityUnits) !=
+				schema.ProvisionedThroughput.ReadCapacityUnits ||
+			aws.Int64Value(throughput.WriteCapacityUnits) !=
+				schema.ProvisionedThroughput.WriteCapacityUnits
+	}
+
+	indexEqual := func(expectedKeySchema []*dynamodb.KeySchemaElement, index IndexSchema,
+		describedKeySchema []*dynamodb.KeySchemaElement,
+		describedProjection *dynamodb.Projection) bool {
+
+		return keySchemaEqual(expectedKeySchema, describedKeySchema, keyAttributes, types) &&
+			projectionEqual(index.projection(), describedProjection)
+	}
+
+	describedGSIs := map[string]*dynamodb.GlobalSecondaryIndexDescription{}
+	for _, gsi := range description.GlobalSecondaryIndexes {
+		describedGSIs[aws.StringValue(gsi.IndexName)] = gsi
+	}
+	for _, gsi := range schema.GlobalSecondaryIndexes {
+		described, found := describedGSIs[gsi.Name]
+		if !found {
+			diff.AddedGlobalSecondaryIndexes = append(diff.AddedGlobalSecondaryIndexes, gsi.Name)
+		} else if !indexEqual(keySchema(gsi.PartitionKey, gsi.SortKey), gsi,
+			described.KeySchema, described.Projection) {
+			diff.ChangedGlobalSecondaryIndexes = append(diff.ChangedGlobalSecondaryIndexes,
+				gsi.Name)
+		}
+		delete(describedGSIs, gsi.Name)
+	}
+	for indexName := range describedGSIs {
+		diff.RemovedGlobalSecondaryIndexes = append(diff.RemovedGlobalSecondaryIndexes, indexName)
+	}
+	sort.Strings(diff.RemovedGlobalSecondaryIndexes)
+
+	describedLSIs := map[string]*dynamodb.LocalSecondaryIndexDescription{}
+	for _, lsi := range description.LocalSecondaryIndexes {
+		describedLSIs[aws.StringValue(lsi.IndexName)] = lsi
+	}
+	for _, lsi := range schema.LocalSecondaryIndexes {
+		described, found := describedLSIs[lsi.Name]
+		if !found {
+			diff.AddedLocalSecondaryIndexes = append(diff.AddedLocalSecondaryIndexes, lsi.Name)
+		} else if !indexEqual(keySchema(schema.PartitionKey, lsi.SortKey), lsi,
+			described.KeySchema, described.Projection) {
+			diff.ChangedLocalSecondaryIndexes = append(diff.ChangedLocalSecondaryIndexes, lsi.Name)
+		}
+		delete(describedLSIs, lsi.Name)
+	}
+	for indexName := range describedLSIs {
+		diff.RemovedLocalSecondaryIndexes = append(diff.RemovedLocalSecondaryIndexes, indexName)
+	}
+	sort.Strings(diff.RemovedLocalSecondaryIndexes)
+
+	return diff
+}
+
+func describedAttributeTypes(definitions []*dynamodb.AttributeDefinition) map[string]string {
+	types := map[string]string{}
+	for _, definition := range definitions {
+		types[aws.StringValue(definition.AttributeName)] = aws.StringValue(definition.AttributeType)
+	}
+	return types
+}
+
+// keySchemaEqual compares key schemas by attribute names, key types and attribute types.
+func keySchemaEqual(expected, described []*dynamodb.KeySchemaElement,
+	keyAttributes []KeyAttribute, describedTypes map[string]string) bool {
+
+	if len(expected) != len(described) {
+		return false
+	}
+
+	expectedTypes := map[string]string{}
+	for _, keyAttribute := range keyAttributes {
+		expectedTypes[keyAttribute.Name] = keyAttribute.Type
+	}
+
+	for i := range expected {
+		name := aws.StringValue(expected[i].AttributeName)
+		if name != aws.StringValue(described[i].AttributeName) ||
+			aws.StringValue(expected[i].KeyType) != aws.StringValue(described[i].KeyType) ||
+			expectedTypes[name] != describedTypes[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func projectionEqual(expected, described *dynamodb.Projection) bool {
+	if described == nil ||
+		aws.StringValue(expected.ProjectionType) != aws.StringValue(described.ProjectionType) {
+		return false
+	}
+
+	expectedAttributes := aws.StringValueSlice(expected.NonKeyAttributes)
+	describedAttributes := aws.StringValueSlice(described.NonKeyAttributes)
+	if len(expectedAttributes) != len(describedAttributes) {
+		return false
+	}
+	for _, attribute := range expectedAttributes {
+		if !containsString(describedAttributes, attribute) {
+			return false
+		}
+	}
+	return true
+}
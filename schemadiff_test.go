@@ -0,0 +1,135 @@
+package autoquery
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func newDiffSchema() *TableSchema {
+	return &TableSchema{
+		TableName:             "Items",
+		PartitionKey:          KeyAttribute{Name: "pk", Type: "S"},
+		SortKey:               &KeyAttribute{Name: "sk", Type: "N"},
+		BillingMode:           dynamodb.BillingModeProvisioned,
+		ProvisionedThroughput: &Throughput{ReadCapacityUnits: 5, WriteCapacityUnits: 5},
+		GlobalSecondaryIndexes: []IndexSchema{
+			{Name: "by-a", PartitionKey: KeyAttribute{Name: "a", Type: "S"}},
+			{Name: "by-b", PartitionKey: KeyAttribute{Name: "b", Type: "S"}},
+			{Name: "by-c", PartitionKey: KeyAttribute{Name: "c", Type: "S"}},
+		},
+		LocalSecondaryIndexes: []IndexSchema{
+			{Name: "by-d", SortKey: &KeyAttribute{Name: "d", Type: "N"}},
+		},
+	}
+}
+
+func TestDiffMatchingSchema(t *testing.T) {
+	schema := newDiffSchema()
+	diff := schema.Diff(schema.tableDescription())
+	if !diff.IsEmpty() || diff.String() != "no changes" {
+		t.Errorf("expected no changes, found %s", diff)
+	}
+}
+
+func TestDiffGlobalSecondaryIndexes(t *testing.T) {
+	description := newDiffSchema().tableDescription()
+
+	schema := newDiffSchema()
+	schema.GlobalSecondaryIndexes = []IndexSchema{
+		// by-a is unchanged, by-b is removed and by-c is changed
+		schema.GlobalSecondaryIndexes[0],
+		{
+			Name:           "by-c",
+			PartitionKey:   KeyAttribute{Name: "c", Type: "S"},
+			ProjectionType: dynamodb.ProjectionTypeKeysOnly,
+		},
+		{Name: "by-e", PartitionKey: KeyAttribute{Name: "e", Type: "S"}},
+	}
+
+	diff := schema.Diff(description)
+	if strings.Join(diff.AddedGlobalSecondaryIndexes, ",") != "by-e" ||
+		strings.Join(diff.RemovedGlobalSecondaryIndexes, ",") != "by-b" ||
+		strings.Join(diff.ChangedGlobalSecondaryIndexes, ",") != "by-c" {
+		t.Errorf("unexpected index changes: %s", diff)
+	}
+	if diff.IsEmpty() || diff.KeySchemaChanged || diff.localSecondaryIndexesChanged() ||
+		diff.ProvisionedThroughputChanged {
+		t.Errorf("expected only global secondary index changes, found %s", diff)
+	}
+
+	// index key attribute types are compared
+	schema = newDiffSchema()
+	schema.GlobalSecondaryIndexes[0].PartitionKey.Type = "N"
+	diff = schema.Diff(description)
+	if strings.Join(diff.ChangedGlobalSecondaryIndexes, ",") != "by-a" {
+		t.Errorf("expected by-a to be changed, found %s", diff)
+	}
+}
+
+func TestDiffProvisionedThroughput(t *testing.T) {
+	description := newDiffSchema().tableDescription()
+
+	schema := newDiffSchema()
+	schema.ProvisionedThroughput.WriteCapacityUnits = 10
+	diff := schema.Diff(description)
+	if !diff.ProvisionedThroughputChanged || diff.BillingModeChanged {
+		t.Errorf("expected only a throughput change, found %s", diff)
+	}
+
+	// secondary index throughput is not compared
+	schema = newDiffSchema()
+	schema.GlobalSecondaryIndexes[0].ProvisionedThroughput = &Throughput{ReadCapacityUnits: 1}
+	if diff := schema.Diff(description); !diff.IsEmpty() {
+		t.Errorf("expected no changes, found %s", diff)
+	}
+
+	// throughput is not compared when the billing mode changes
+	schema = newDiffSchema()
+	schema.BillingMode = dynamodb.BillingModePayPerRequest
+	diff = schema.Diff(description)
+	if !diff.BillingModeChanged || diff.ProvisionedThroughputChanged {
+		t.Errorf("expected only a billing mode change, found %s", diff)
+	}
+
+	// tables without a billing mode summary use provisioned billing
+	description.BillingModeSummary = nil
+	if diff := newDiffSchema().Diff(description); !diff.IsEmpty() {
+		t.Errorf("expected no changes, found %s", diff)
+	}
+}
+
+func TestDiffKeySchemaAndLocalSecondaryIndexes(t *testing.T) {
+	description := newDiffSchema().tableDescription()
+
+	schema := newDiffSchema()
+	schema.SortKey = &KeyAttribute{Name: "sk", Type: "S"}
+	schema.LocalSecondaryIndexes = nil
+	diff := schema.Diff(description)
+	if !diff.KeySchemaChanged ||
+		strings.Join(diff.RemovedLocalSecondaryIndexes, ",") != "by-d" {
+		t.Errorf("expected key schema and local secondary index changes, found %s", diff)
+	}
+
+	description.KeySchema = description.KeySchema[:1]
+	if diff := newDiffSchema().Diff(description); !diff.KeySchemaChanged {
+		t.Errorf("expected a key schema change, found %s", diff)
+	}
+}
+
+func TestProjectionEqual(t *testing.T) {
+	include := func(attributes ...string) *dynamodb.Projection {
+		return &dynamodb.Projection{
+			ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+			NonKeyAttributes: aws.StringSlice(attributes),
+		}
+	}
+	if !projectionEqual(include("a", "b"), include("b", "a")) {
+		t.Error("expected projections with reordered attributes to be equal")
+	}
+	if projectionEqual(include("a"), include("a", "b")) || projectionEqual(include("a"), nil) {
+		t.Error("expected different projections not to be equal")
+	}
+}
@@ -0,0 +1,95 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Get returns a description of the table defined by the schema, which allows a schema to be used
+// as a static TableDescriptionProvider. The description reports an empty table, so secondary
+// indexes are considered sparse unless the client's SecondaryIndexSparsenessThreshold is 0.0 or
+// less. An error is returned if the schema is invalid.
+func (schema *TableSchema) Get(
+	ctx context.Context, tableName string) (*dynamodb.TableDescription, error) {
+
+	if tableName != schema.TableName {
+		return nil, fmt.Errorf("no schema defined for table %s", tableName)
+	} else if err := schema.validate(); err != nil {
+		return nil, err
+	}
+	return schema.tableDescription(), nil
+}
+
+type schemaDescriptionProvider struct {
+	schemas map[string]*TableSchema
+}
+
+// NewSchemaDescriptionProvider creates a static TableDescriptionProvider which describes tables
+// from their schemas. This allows index selection without DescribeTable calls, such as when a
+// client lacks permission to describe tables. An error is returned if any schema is invalid.
+func NewSchemaDescriptionProvider(schemas ...*TableSchema) (TableDescriptionProvider, error) {
+	provider := &schemaDescriptionProvider{schemas: map[string]*TableSchema{}}
+	for _, schema := range schemas {
+		if err := schema.validate(); err != nil {
+			return nil, err
+		}
+		provider.schemas[schema.TableName] = schema
+	}
+	return provider, nil
+}
+
+func (p *schemaDescriptionProvider) Get(
+	ctx context.Context, tableName string) (*dynamodb.TableDescription, error) {
+
+	schema, found := p.schemas[tableName]
+	if !found {
+		return nil, fmt.Errorf("no schema defined for table %s", tableName)
+	} else if err := schema.validate(); err != nil {
+		return nil, err
+	}
+	return schema.tableDescription(), nil
+}
+
+func (schema *TableSchema) tableDescription() *dynamodb.TableDescription {
+	description := &dynamodb.TableDescription{
+		TableName:            aws.String(schema.TableName),
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		KeySchema:            keySchema(schema.PartitionKey, schema.SortKey),
+		AttributeDefinitions: attributeDefinitions(schema.keyAttributes()),
+		ItemCount:            aws.Int64(0),
+		BillingModeSummary: &dynamodb.BillingModeSummary{
+			BillingMode: aws.String(schema.billingMode()),
+		},
+	}
+	if schema.billingMode() == dynamodb.BillingModeProvisioned {
+		description.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(schema.ProvisionedThroughput.ReadCapacityUnits),
+			WriteCapacityUnits: aws.Int64(schema.ProvisionedThroughput.WriteCapacityUnits),
+		}
+	}
+
+	for _, gsi := range schema.GlobalSecondaryIndexes {
+		description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes,
+			&dynamodb.GlobalSecondaryIndexDescription{
+				IndexName:   aws.String(gsi.Name),
+				IndexStatus: aws.String(dynamodb.IndexStatusActive),
+				KeySchema:   keySchema(gsi.PartitionKey, gsi.SortKey),
+				Projection:  gsi.projection(),
+				ItemCount:   aws.Int64(0),
+			})
+	}
+	for _, lsi := range schema.LocalSecondaryIndexes {
+		description.LocalSecondaryIndexes = append(description.LocalSecondaryIndexes,
+			&dynamodb.LocalSecondaryIndexDescription{
+				IndexName:  aws.String(lsi.Name),
+				KeySchema:  keySchema(schema.PartitionKey, lsi.SortKey),
+				Projection: lsi.projection(),
+				ItemCount:  aws.Int64(0),
+			})
+	}
+
+	return description
+}
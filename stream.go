@@ -0,0 +1,114 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// ForEach calls fn for each remaining item in the query. Before each call to fn, the next item is
+// unmarshaled into returnItem in the same way as Next.
+//
+// ForEach returns nil once parsing is complete. If Next or fn returns an error, iteration stops
+// and the error is returned.
+func (parser *Parser) ForEach(ctx context.Context, returnItem interface{}, fn func() error) error {
+	for {
+		if err := parser.Next(ctx, returnItem); err != nil {
+			if isParsingComplete(err) {
+				return nil
+			}
+			return err
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+}
+
+// Stream parses the remaining items in the query in the background and sends them on the
+// returned item channel. Each item is a newly allocated value of the same type as itemPrototype,
+// which must be a pointer to a type that can be unmarshaled by Next, such as &Movie{}.
+//
+// The item channel is closed once parsing is complete or an error occurs. If an error occurs,
+// including cancellation of ctx, it is sent on the error channel before the item channel is
+// closed. The error channel is closed after the item channel. The parser should not be used by
+// the caller until the item channel is closed, except to call Close.
+//
+// A caller which stops receiving items before the item channel is closed must cancel ctx or call
+// Parser.Close, otherwise the background parsing is never stopped. Close stops the stream without
+// sending an error and waits for the item channel to be closed.
+func (parser *Parser) Stream(
+	ctx context.Context, itemPrototype interface{}) (<-chan interface{}, <-chan error) {
+
+	items := make(chan interface{})
+	errs := make(chan error, 1)
+
+	itemType := reflect.TypeOf(itemPrototype)
+	if itemType == nil || itemType.Kind() != reflect.Ptr {
+		errs <- fmt.Errorf("stream item prototype must be a pointer, got %T", itemPrototype)
+		close(items)
+		close(errs)
+		return items, errs
+	}
+
+	parser.stopStream()
+	streamCtx, cancel := context.WithCancel(ctx)
+	st := &streamer{cancel: cancel, done: make(chan struct{})}
+	parser.streamer = st
+
+	go func() {
+		defer close(st.done)
+		defer close(errs)
+		defer close(items)
+		defer cancel()
+
+		// errors caused by Close are not sent, since the caller has stopped the stream
+		sendErr := func(err error) {
+			if ctx.Err() != nil || streamCtx.Err() == nil {
+				errs <- err
+			}
+		}
+
+		for {
+			item := reflect.New(itemType.Elem()).Interface()
+			if err := parser.Next(streamCtx, item); err != nil {
+				if !isParsingComplete(err) {
+					sendErr(err)
+				}
+				return
+			}
+
+			select {
+			case items <- item:
+			case <-streamCtx.Done():
+				sendErr(streamCtx.Err())
+				return
+			}
+		}
+	}()
+
+	return items, errs
+}
+
+type streamer struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stopStream stops the parser's stream, if any, and waits for its goroutine to return.
+func (parser *Parser) stopStream() {
+	if parser.streamer != nil {
+		parser.streamer.cancel()
+		<-parser.streamer.done
+		parser.streamer = nil
+	}
+}
+
+func isParsingComplete(err error) bool {
+	switch err.(type) {
+	case *ErrParsingComplete, ErrParsingComplete:
+		return true
+	}
+	return false
+}
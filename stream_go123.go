@@ -0,0 +1,39 @@
+//go:build go1.23
+
+package autoquery
+
+import (
+	"context"
+	"iter"
+)
+
+// Items returns an iterator over the remaining items in the query. Each item is unmarshaled into
+// a new value of type T in the same way as Parser.Next.
+//
+// Iteration ends once parsing is complete. If an error occurs, it is yielded with the zero value
+// of T and iteration ends.
+//
+//	for movie, err := range autoquery.Items[Movie](ctx, parser) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(movie.Title)
+//	}
+func Items[T any](ctx context.Context, parser *Parser) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			var item T
+			if err := parser.Next(ctx, &item); err != nil {
+				if !isParsingComplete(err) {
+					var zero T
+					yield(zero, err)
+				}
+				return
+			}
+
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+//go:build go1.23
+
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestItems(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 2))
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	sortKeys := []int{}
+	for item, err := range Items[testItem](ctx, parser) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sortKeys = append(sortKeys, item.SK)
+	}
+	expectSortKeys(t, sortKeys, 5)
+}
+
+func TestItemsBreakKeepsPosition(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 2))
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	sortKeys := []int{}
+	for item, err := range Items[testItem](ctx, parser) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sortKeys = append(sortKeys, item.SK)
+		if len(sortKeys) == 3 {
+			break
+		}
+	}
+
+	// iteration does not parse ahead, so a later iteration continues after the last item
+	for item, err := range Items[testItem](ctx, parser) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sortKeys = append(sortKeys, item.SK)
+	}
+	expectSortKeys(t, sortKeys, 5)
+}
+
+func TestItemsYieldsError(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(5, 2)
+	svc.queryErrs = []error{errors.New("query failed")}
+	client := NewClient(svc)
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	errs := 0
+	for _, err := range Items[testItem](ctx, parser) {
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("expected 1 error, found %d", errs)
+	}
+}
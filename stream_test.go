@@ -0,0 +1,137 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// expectGoroutines waits until at most n goroutines are running.
+func expectGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected at most %d goroutines, found %d", n, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 2))
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	item := testItem{}
+	sortKeys := []int{}
+	err := parser.ForEach(ctx, &item, func() error {
+		sortKeys = append(sortKeys, item.SK)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSortKeys(t, sortKeys, 5)
+}
+
+func TestForEachStopsOnError(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 2))
+	stop := errors.New("stop")
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	calls := 0
+	err := parser.ForEach(ctx, &testItem{}, func() error {
+		calls++
+		if calls == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 2 {
+		t.Errorf("expected to stop after 2 calls, found %d calls and error %v", calls, err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(5, 2))
+	goroutines := runtime.NumGoroutine()
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	items, errs := parser.Stream(ctx, &testItem{})
+	sortKeys := []int{}
+	for item := range items {
+		sortKeys = append(sortKeys, item.(*testItem).SK)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	expectSortKeys(t, sortKeys, 5)
+	expectGoroutines(t, goroutines)
+}
+
+func TestStreamRejectsNonPointerPrototype(t *testing.T) {
+	client := NewClient(newFakeService(1, 1))
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+
+	items, errs := parser.Stream(context.Background(), testItem{})
+	if _, open := <-items; open {
+		t.Error("expected the item channel to be closed")
+	}
+	if err := <-errs; err == nil {
+		t.Error("expected an error for a non-pointer prototype")
+	}
+}
+
+func TestStreamStopsWhenContextCanceled(t *testing.T) {
+	client := NewClient(newFakeService(10, 2))
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	items, errs := parser.Stream(ctx, &testItem{})
+	<-items
+	cancel()
+
+	for range items {
+	}
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("expected context.Canceled, found %v", err)
+	}
+	expectGoroutines(t, goroutines)
+}
+
+func TestStreamStopsOnClose(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(10, 2))
+	goroutines := runtime.NumGoroutine()
+
+	parser := client.Query(fakeTableName, NewExpression().Equal("pk", "p"))
+	items, errs := parser.Stream(ctx, &testItem{})
+	first := (<-items).(*testItem)
+	if err := parser.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Close waits for the stream to stop, and the stream does not report an error
+	if _, open := <-items; open {
+		t.Error("expected the item channel to be closed")
+	}
+	if err, open := <-errs; open {
+		t.Errorf("expected no error, found %v", err)
+	}
+	expectGoroutines(t, goroutines)
+
+	// the parser continues after the items which were parsed by the stream
+	next := testItem{}
+	if err := parser.Next(ctx, &next); err != nil {
+		t.Fatal(err)
+	}
+	if next.SK <= first.SK {
+		t.Errorf("expected an item after %d, found %d", first.SK, next.SK)
+	}
+}
@@ -1,6 +1,9 @@
 package autoquery
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 // Table represents a specific DynamoDB table.
 type Table struct {
@@ -21,15 +24,102 @@ func (client *Client) Table(tableName string) *Table {
 // The item is returned in returnItem, which should have dynamodbav attribute tags pertaining to
 // the desired return attributes in the table.
 //
+// If the type of itemKey is registered with RegisterType, then itemKey may be a full item, and
+// only the table's key attributes are used. To retrieve an item into the same struct which holds
+// its key, use Load.
+//
 // If the item is not found, ErrItemNotFound is returned.
 func (table Table) Get(ctx context.Context, itemKey, returnItem interface{}) error {
-	return table.autoqueryClient.Get(ctx, table.name, itemKey, returnItem)
+	key, err := table.marshalKey(ctx, itemKey)
+	if err != nil {
+		return err
+	}
+	return table.autoqueryClient.getItem(ctx, table.name, key, returnItem)
 }
 
 // Put inserts a new item into the table, or replaces it if an item with the same primary key
 // already exists. The item should be a struct with the appropriate dynamodbav attribute tags.
-func (table Table) Put(ctx context.Context, item interface{}) error {
-	return table.autoqueryClient.Put(ctx, table.name, item)
+// See Client.Put for write options. If the type of item is a registered entity of the table, its
+// composite key attributes are encoded from its fields and written with the item.
+//
+// If the item has an integer field tagged with `autoquery:"version"`, then Put uses optimistic
+// locking: the item is only written if the stored item's version matches the item's version, or
+// if the item's version is 0 and no item is stored. The item must be a pointer to a struct, and
+// its version is incremented when the write succeeds. If the version does not match, an
+// *ErrVersionConflict error is returned, unless other conditions are specified, in which case an
+// *ErrConditionFailed error is returned.
+func (table Table) Put(ctx context.Context, item interface{}, opts ...WriteOption) error {
+	field, err := findVersionField(item)
+	if err != nil {
+		return err
+	} else if field != nil {
+		return table.putVersioned(ctx, item, field, opts)
+	}
+
+	tableItem, err := table.marshalItem(item)
+	if err != nil {
+		return err
+	}
+	return table.autoqueryClient.putItem(ctx, table.name, tableItem, newWriteOptions(opts))
+}
+
+// Delete deletes a single item by its key. See Client.Delete.
+//
+// If the type of itemKey is registered with RegisterType, then itemKey may be a full item, and
+// only the table's key attributes are used.
+func (table Table) Delete(ctx context.Context, itemKey interface{}, opts ...WriteOption) error {
+	key, err := table.marshalKey(ctx, itemKey)
+	if err != nil {
+		return err
+	}
+	return table.autoqueryClient.deleteItem(ctx, table.name, key, newWriteOptions(opts))
+}
+
+// Update applies update actions to a single item by its key. See Client.Update.
+//
+// If itemKey has an integer field tagged with `autoquery:"version"`, then Update uses optimistic
+// locking in the same way as Put: only the table's key attributes of itemKey are used as the key,
+// the update is only applied if the stored version matches, and the stored and in-memory versions
+// are incremented when the update succeeds.
+//
+// If the type of itemKey is registered with RegisterType, then itemKey may be a full item, and
+// only the table's key attributes are used.
+func (table Table) Update(
+	ctx context.Context, itemKey interface{}, update *Update, opts ...WriteOption) error {
+
+	field, err := findVersionField(itemKey)
+	if err != nil {
+		return err
+	} else if field != nil {
+		return table.updateVersioned(ctx, itemKey, update, field, opts)
+	}
+
+	if update == nil {
+		return fmt.Errorf("update does not contain any actions")
+	}
+
+	key, err := table.marshalKey(ctx, itemKey)
+	if err != nil {
+		return err
+	}
+	return table.autoqueryClient.updateItem(ctx, table.name, key, update, newWriteOptions(opts))
+}
+
+// BatchGet retrieves multiple items by their keys. See Client.BatchGet.
+func (table Table) BatchGet(
+	ctx context.Context, itemKeys, returnItems interface{}) ([]bool, error) {
+
+	return table.autoqueryClient.BatchGet(ctx, table.name, itemKeys, returnItems)
+}
+
+// BatchPut inserts or replaces multiple items. See Client.BatchPut.
+func (table Table) BatchPut(ctx context.Context, items interface{}) error {
+	return table.autoqueryClient.BatchPut(ctx, table.name, items)
+}
+
+// BatchDelete deletes multiple items by their keys. See Client.BatchDelete.
+func (table Table) BatchDelete(ctx context.Context, itemKeys interface{}) error {
+	return table.autoqueryClient.BatchDelete(ctx, table.name, itemKeys)
 }
 
 // Query initializes a query defined by expr on a table. The returned parser may be used to
@@ -37,3 +127,8 @@ func (table Table) Put(ctx context.Context, item interface{}) error {
 func (table Table) Query(expr *Expression) *Parser {
 	return table.autoqueryClient.Query(table.name, expr)
 }
+
+// Count returns the number of items matching the expression on the table. See Client.Count.
+func (table Table) Count(ctx context.Context, expr *Expression) (*CountResult, error) {
+	return table.autoqueryClient.Count(ctx, table.name, expr)
+}
@@ -0,0 +1,246 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// tableStatusPollInterval is the interval between DescribeTable calls when waiting for a table
+// to become active.
+const tableStatusPollInterval = time.Second
+
+// CreateTable creates the table defined by schema. If the schema specifies a time to live
+// attribute, CreateTable waits for the table to become active and then enables time to live.
+// Otherwise, CreateTable returns once the table is being created; use WaitForActive to wait for
+// the table to become active.
+func (client *Client) CreateTable(ctx context.Context, schema *TableSchema) error {
+	input, err := schema.CreateTableInput()
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.dynamodbService.CreateTableWithContext(ctx, input); err != nil {
+		return err
+	}
+	client.invalidateIndexMetadata(schema.TableName)
+
+	if schema.TimeToLiveAttribute == "" {
+		return nil
+	}
+	if err := client.WaitForActive(ctx, schema.TableName); err != nil {
+		return err
+	}
+	return client.updateTimeToLive(ctx, schema.TableName, schema.TimeToLiveAttribute, true)
+}
+
+// WaitForActive waits until the table and all of its global secondary indexes are active, or
+// until the context is done.
+func (client *Client) WaitForActive(ctx context.Context, tableName string) error {
+	for {
+		output, err := client.dynamodbService.DescribeTableWithContext(ctx,
+			&dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			return err
+		} else if isTableActive(output.Table) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(tableStatusPollInterval):
+		}
+	}
+}
+
+func isTableActive(table *dynamodb.TableDescription) bool {
+	if aws.StringValue(table.TableStatus) != dynamodb.TableStatusActive {
+		return false
+	}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexStatus) != dynamodb.IndexStatusActive {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffTable compares schema with the existing table, including its time to live settings.
+func (client *Client) DiffTable(ctx context.Context, schema *TableSchema) (*SchemaDiff, error) {
+	diff, _, err := client.diffTable(ctx, schema)
+	return diff, err
+}
+
+// diffTable returns the diff of schema with the existing table, and the table's current time to
+// live attribute if time to live is enabled.
+func (client *Client) diffTable(
+	ctx context.Context, schema *TableSchema) (*SchemaDiff, string, error) {
+
+	if err := schema.validate(); err != nil {
+		return nil, "", err
+	}
+
+	describeOutput, err := client.dynamodbService.DescribeTableWithContext(ctx,
+		&dynamodb.DescribeTableInput{TableName: aws.String(schema.TableName)})
+	if err != nil {
+		return nil, "", err
+	}
+	ttlOutput, err := client.dynamodbService.DescribeTimeToLiveWithContext(ctx,
+		&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(schema.TableName)})
+	if err != nil {
+		return nil, "", err
+	}
+
+	ttlAttribute := ""
+	if ttl := ttlOutput.TimeToLiveDescription; ttl != nil {
+		switch aws.StringValue(ttl.TimeToLiveStatus) {
+		case dynamodb.TimeToLiveStatusEnabled, dynamodb.TimeToLiveStatusEnabling:
+			ttlAttribute = aws.StringValue(ttl.AttributeName)
+		}
+	}
+
+	diff := schema.Diff(describeOutput.Table)
+	diff.TimeToLiveChanged = ttlAttribute != schema.TimeToLiveAttribute
+	return diff, ttlAttribute, nil
+}
+
+// UpdateTable updates the existing table to match schema. Changes are applied one at a time,
+// waiting for the table to become active after each change: billing mode and throughput are
+// updated first, then removed and changed global secondary indexes are deleted, then added and
+// changed global secondary indexes are created, and finally time to live is updated.
+//
+// The key schema and local secondary indexes of a table cannot be changed after creation. If the
+// schema differs from the table in either of these, an error is returned and the table is not
+// modified.
+//
+// Changing the time to live attribute of a table is a two-step operation, since DynamoDB does not
+// allow time to live to be enabled again for about an hour after it is disabled. If the schema
+// specifies a different time to live attribute than the table's, an error is returned and the
+// table is not modified. Instead, update the table with a schema without TimeToLiveAttribute to
+// disable time to live, then update it with the new attribute once DynamoDB allows it.
+func (client *Client) UpdateTable(ctx context.Context, schema *TableSchema) error {
+	diff, ttlAttribute, err := client.diffTable(ctx, schema)
+	if err != nil {
+		return err
+	} else if diff.KeySchemaChanged {
+		return fmt.Errorf("cannot update key schema of table %s", schema.TableName)
+	} else if diff.localSecondaryIndexesChanged() {
+		return fmt.Errorf("cannot update local secondary indexes of table %s", schema.TableName)
+	} else if diff.TimeToLiveChanged && ttlAttribute != "" && schema.TimeToLiveAttribute != "" {
+		return fmt.Errorf("cannot change time to live attribute of table %s from %s to %s in one "+
+			"update, time to live must be disabled first", schema.TableName, ttlAttribute,
+			schema.TimeToLiveAttribute)
+	}
+	defer client.invalidateIndexMetadata(schema.TableName)
+
+	update := func(input *dynamodb.UpdateTableInput) error {
+		input.TableName = aws.String(schema.TableName)
+		if _, err := client.dynamodbService.UpdateTableWithContext(ctx, input); err != nil {
+			return err
+		}
+		return client.WaitForActive(ctx, schema.TableName)
+	}
+
+	if diff.BillingModeChanged || diff.ProvisionedThroughputChanged {
+		if err := update(client.billingUpdateInput(schema, diff)); err != nil {
+			return err
+		}
+	}
+
+	deletedIndexes := uniqueStrings(diff.RemovedGlobalSecondaryIndexes,
+		diff.ChangedGlobalSecondaryIndexes)
+	for _, indexName := range deletedIndexes {
+		err := update(&dynamodb.UpdateTableInput{
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+				Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{
+					IndexName: aws.String(indexName),
+				},
+			}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	createdIndexes := uniqueStrings(diff.AddedGlobalSecondaryIndexes,
+		diff.ChangedGlobalSecondaryIndexes)
+	for _, gsi := range schema.GlobalSecondaryIndexes {
+		if !containsString(createdIndexes, gsi.Name) {
+			continue
+		}
+		err := update(&dynamodb.UpdateTableInput{
+			AttributeDefinitions: attributeDefinitions(
+				keySchemaAttributes(gsi.PartitionKey, gsi.SortKey)),
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+				Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+					IndexName:             aws.String(gsi.Name),
+					KeySchema:             keySchema(gsi.PartitionKey, gsi.SortKey),
+					Projection:            gsi.projection(),
+					ProvisionedThroughput: schema.gsiThroughput(gsi),
+				},
+			}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// time to live is either disabled or enabled, since changing its attribute is not allowed
+	if diff.TimeToLiveChanged && ttlAttribute != "" {
+		return client.updateTimeToLive(ctx, schema.TableName, ttlAttribute, false)
+	} else if diff.TimeToLiveChanged {
+		return client.updateTimeToLive(ctx, schema.TableName, schema.TimeToLiveAttribute, true)
+	}
+
+	return nil
+}
+
+// billingUpdateInput returns the update input for the billing mode and throughput of schema.
+// When switching to provisioned billing, existing global secondary indexes which are kept are
+// also given provisioned throughput.
+func (client *Client) billingUpdateInput(
+	schema *TableSchema, diff *SchemaDiff) *dynamodb.UpdateTableInput {
+
+	input := &dynamodb.UpdateTableInput{
+		BillingMode: aws.String(schema.billingMode()),
+	}
+	if schema.billingMode() != dynamodb.BillingModeProvisioned {
+		return input
+	}
+
+	input.ProvisionedThroughput = schema.ProvisionedThroughput.provisionedThroughput()
+	if diff.BillingModeChanged {
+		for _, gsi := range schema.GlobalSecondaryIndexes {
+			if containsString(diff.AddedGlobalSecondaryIndexes, gsi.Name) ||
+				containsString(diff.ChangedGlobalSecondaryIndexes, gsi.Name) {
+				continue
+			}
+			input.GlobalSecondaryIndexUpdates = append(input.GlobalSecondaryIndexUpdates,
+				&dynamodb.GlobalSecondaryIndexUpdate{
+					Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
+						IndexName:             aws.String(gsi.Name),
+						ProvisionedThroughput: schema.gsiThroughput(gsi),
+					},
+				})
+		}
+	}
+	return input
+}
+
+func (client *Client) updateTimeToLive(
+	ctx context.Context, tableName, attribute string, enabled bool) error {
+
+	_, err := client.dynamodbService.UpdateTimeToLiveWithContext(ctx,
+		&dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String(tableName),
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+				AttributeName: aws.String(attribute),
+				Enabled:       aws.Bool(enabled),
+			},
+		})
+	return err
+}
@@ -0,0 +1,196 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeAdminService describes a single active table and records table administration calls.
+type fakeAdminService struct {
+	dynamodbiface.DynamoDBAPI
+
+	table        *dynamodb.TableDescription
+	ttlAttribute string
+
+	createInputs []*dynamodb.CreateTableInput
+	updateInputs []*dynamodb.UpdateTableInput
+	ttlInputs    []*dynamodb.UpdateTimeToLiveInput
+}
+
+func (svc *fakeAdminService) CreateTableWithContext(ctx aws.Context,
+	input *dynamodb.CreateTableInput,
+	opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+
+	svc.createInputs = append(svc.createInputs, input)
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (svc *fakeAdminService) DescribeTableWithContext(ctx aws.Context,
+	input *dynamodb.DescribeTableInput,
+	opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+	return &dynamodb.DescribeTableOutput{Table: svc.table}, nil
+}
+
+func (svc *fakeAdminService) UpdateTableWithContext(ctx aws.Context,
+	input *dynamodb.UpdateTableInput,
+	opts ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+
+	svc.updateInputs = append(svc.updateInputs, input)
+	return &dynamodb.UpdateTableOutput{}, nil
+}
+
+func (svc *fakeAdminService) DescribeTimeToLiveWithContext(ctx aws.Context,
+	input *dynamodb.DescribeTimeToLiveInput,
+	opts ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+
+	status := dynamodb.TimeToLiveStatusDisabled
+	if svc.ttlAttribute != "" {
+		status = dynamodb.TimeToLiveStatusEnabled
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{
+		TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+			AttributeName:    aws.String(svc.ttlAttribute),
+			TimeToLiveStatus: aws.String(status),
+		},
+	}, nil
+}
+
+func (svc *fakeAdminService) UpdateTimeToLiveWithContext(ctx aws.Context,
+	input *dynamodb.UpdateTimeToLiveInput,
+	opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+
+	svc.ttlInputs = append(svc.ttlInputs, input)
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
+
+func TestCreateTable(t *testing.T) {
+	ctx := context.Background()
+	schema := newDiffSchema()
+	schema.TimeToLiveAttribute = "expires"
+	svc := &fakeAdminService{table: schema.tableDescription()}
+
+	if err := NewClient(svc).CreateTable(ctx, schema); err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.createInputs) != 1 || aws.StringValue(svc.createInputs[0].TableName) != "Items" {
+		t.Errorf("expected the table to be created, found %v", svc.createInputs)
+	}
+	if len(svc.ttlInputs) != 1 ||
+		aws.StringValue(svc.ttlInputs[0].TimeToLiveSpecification.AttributeName) != "expires" ||
+		!aws.BoolValue(svc.ttlInputs[0].TimeToLiveSpecification.Enabled) {
+		t.Errorf("expected time to live to be enabled, found %v", svc.ttlInputs)
+	}
+}
+
+func TestUpdateTable(t *testing.T) {
+	ctx := context.Background()
+	svc := &fakeAdminService{table: newDiffSchema().tableDescription()}
+
+	schema := newDiffSchema()
+	schema.BillingMode = dynamodb.BillingModePayPerRequest
+	schema.ProvisionedThroughput = nil
+	schema.GlobalSecondaryIndexes = []IndexSchema{
+		schema.GlobalSecondaryIndexes[0],
+		{
+			Name:           "by-c",
+			PartitionKey:   KeyAttribute{Name: "c", Type: "S"},
+			ProjectionType: dynamodb.ProjectionTypeKeysOnly,
+		},
+	}
+	schema.TimeToLiveAttribute = "expires"
+
+	if err := NewClient(svc).UpdateTable(ctx, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	// billing is updated first, then indexes are deleted and created
+	if len(svc.updateInputs) != 4 {
+		t.Fatalf("expected 4 table updates, found %v", svc.updateInputs)
+	}
+	billing := svc.updateInputs[0]
+	if aws.StringValue(billing.BillingMode) != dynamodb.BillingModePayPerRequest ||
+		billing.ProvisionedThroughput != nil {
+		t.Errorf("unexpected billing update: %v", billing)
+	}
+	deleted := []string{}
+	for _, input := range svc.updateInputs[1:3] {
+		deleted = append(deleted,
+			aws.StringValue(input.GlobalSecondaryIndexUpdates[0].Delete.IndexName))
+	}
+	if deleted[0] != "by-b" || deleted[1] != "by-c" {
+		t.Errorf("expected by-b and by-c to be deleted, found %v", deleted)
+	}
+	created := svc.updateInputs[3].GlobalSecondaryIndexUpdates[0].Create
+	if aws.StringValue(created.IndexName) != "by-c" || created.ProvisionedThroughput != nil {
+		t.Errorf("unexpected created index: %v", created)
+	}
+	if len(svc.ttlInputs) != 1 ||
+		!aws.BoolValue(svc.ttlInputs[0].TimeToLiveSpecification.Enabled) {
+		t.Errorf("expected time to live to be enabled, found %v", svc.ttlInputs)
+	}
+}
+
+func TestUpdateTableToProvisionedBilling(t *testing.T) {
+	ctx := context.Background()
+	described := newDiffSchema()
+	described.BillingMode = dynamodb.BillingModePayPerRequest
+	svc := &fakeAdminService{table: described.tableDescription()}
+
+	schema := newDiffSchema()
+	schema.GlobalSecondaryIndexes[0].ProvisionedThroughput = &Throughput{
+		ReadCapacityUnits: 1, WriteCapacityUnits: 1,
+	}
+	if err := NewClient(svc).UpdateTable(ctx, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	// kept global secondary indexes are given throughput with the table
+	if len(svc.updateInputs) != 1 {
+		t.Fatalf("expected a single table update, found %v", svc.updateInputs)
+	}
+	updates := svc.updateInputs[0].GlobalSecondaryIndexUpdates
+	if len(updates) != 3 ||
+		aws.Int64Value(updates[0].Update.ProvisionedThroughput.ReadCapacityUnits) != 1 ||
+		aws.Int64Value(updates[1].Update.ProvisionedThroughput.ReadCapacityUnits) != 5 {
+		t.Errorf("unexpected index throughput updates: %v", updates)
+	}
+}
+
+func TestUpdateTableRejectsUnsupportedChanges(t *testing.T) {
+	ctx := context.Background()
+
+	keyChanged := newDiffSchema()
+	keyChanged.SortKey = nil
+	lsiChanged := newDiffSchema()
+	lsiChanged.LocalSecondaryIndexes = nil
+	ttlChanged := newDiffSchema()
+	ttlChanged.TimeToLiveAttribute = "expires"
+
+	tests := []struct {
+		name         string
+		schema       *TableSchema
+		ttlAttribute string
+	}{
+		{"key schema", keyChanged, ""},
+		{"local secondary index", lsiChanged, ""},
+		{"time to live attribute", ttlChanged, "ttl"},
+	}
+	for _, test := range tests {
+		svc := &fakeAdminService{
+			table:        newDiffSchema().tableDescription(),
+			ttlAttribute: test.ttlAttribute,
+		}
+		if err := NewClient(svc).UpdateTable(ctx, test.schema); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+		if len(svc.updateInputs) != 0 || len(svc.ttlInputs) != 0 {
+			t.Errorf("%s: expected the table not to be modified", test.name)
+		}
+	}
+}
@@ -44,6 +44,45 @@ func (index tableIndex) getKeys() []string {
 	return []string{index.PartitionKey}
 }
 
+// queryIndexName returns the index name as used in query inputs, which is empty for the table's
+// primary index.
+func (index tableIndex) queryIndexName() string {
+	if index.Name == tablePrimaryIndexName {
+		return ""
+	}
+	return index.Name
+}
+
+// missingAttributes returns the attributes which are not projected by the index.
+func (index tableIndex) missingAttributes(attributes []string) []string {
+	missing := []string{}
+	if index.IncludesAllAttributes {
+		return missing
+	}
+	for _, attribute := range attributes {
+		if _, found := index.AttributeSet[attribute]; !found {
+			missing = append(missing, attribute)
+		}
+	}
+	return missing
+}
+
+// requiresBackFetch returns true if the index does not project all attributes needed by expr.
+func (index tableIndex) requiresBackFetch(expr *Expression) bool {
+	if index.IncludesAllAttributes {
+		return false
+	} else if !expr.attributesSpecified {
+		return true
+	}
+
+	for _, selectedAttr := range expr.attributes {
+		if _, found := index.AttributeSet[selectedAttr]; !found {
+			return true
+		}
+	}
+	return false
+}
+
 func (index *tableIndex) loadAttributesFromProjection(
 	projection *dynamodb.Projection, tablePrimaryIndexKeys []string) {
 
@@ -3,3 +3,12 @@ package autoquery
 type tableIndexMetadata struct {
 	Indexes []*tableIndex
 }
+
+func (metadata tableIndexMetadata) primaryIndex() *tableIndex {
+	for _, index := range metadata.Indexes {
+		if index.Name == tablePrimaryIndexName {
+			return index
+		}
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// TransactWrite accumulates write operations to be committed atomically with
+// TransactWriteItems. Operations may target items in multiple tables, but each item may only be
+// targeted by a single operation in the transaction.
+type TransactWrite struct {
+	client *Client
+
+	items              []*dynamodb.TransactWriteItem
+	clientRequestToken *string
+	err                error
+}
+
+// TransactWrite initializes a new write transaction.
+func (client *Client) TransactWrite() *TransactWrite {
+	return &TransactWrite{
+		client: client,
+		items:  []*dynamodb.TransactWriteItem{},
+	}
+}
+
+// Put adds an operation which inserts or replaces an item in a table. The item should be a struct
+// with the appropriate dynamodbav attribute tags. Conditions may be specified with WithCondition.
+func (tx *TransactWrite) Put(
+	tableName string, item interface{}, opts ...WriteOption) *TransactWrite {
+
+	tableItem, err := encodeItem(tx.client.Encoder, item)
+	if err != nil {
+		return tx.fail(err)
+	}
+
+	put := &dynamodb.Put{
+		TableName: aws.String(tableName),
+		Item:      tableItem,
+	}
+	options := newWriteOptions(opts)
+	dynamodbExpr, hasExpression, err := options.buildExpression(tx.client.Encoder, nil)
+	if err != nil {
+		return tx.fail(err)
+	} else if hasExpression {
+		put.ConditionExpression = dynamodbExpr.Condition()
+		put.ExpressionAttributeNames = dynamodbExpr.Names()
+		put.ExpressionAttributeValues = dynamodbExpr.Values()
+	}
+
+	return tx.add(&dynamodb.TransactWriteItem{Put: put})
+}
+
+// Update adds an operation which applies update actions to an item in a table. The key should be
+// a struct with the appropriate dynamodbav attribute tags pertaining to the table's primary key.
+// Conditions may be specified with WithCondition.
+func (tx *TransactWrite) Update(tableName string, itemKey interface{}, update *Update,
+	opts ...WriteOption) *TransactWrite {
+
+	if update == nil {
+		return tx.fail(fmt.Errorf("update does not contain any actions"))
+	}
+
+	key, err := encodeItem(tx.client.Encoder, itemKey)
+	if err != nil {
+		return tx.fail(err)
+	}
+
+	dynamodbExpr, _, err := newWriteOptions(opts).buildExpression(tx.client.Encoder, update)
+	if err != nil {
+		return tx.fail(err)
+	}
+
+	return tx.add(&dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+		TableName:                 aws.String(tableName),
+		Key:                       key,
+		UpdateExpression:          dynamodbExpr.Update(),
+		ConditionExpression:       dynamodbExpr.Condition(),
+		ExpressionAttributeNames:  dynamodbExpr.Names(),
+		ExpressionAttributeValues: dynamodbExpr.Values(),
+	}})
+}
+
+// Delete adds an operation which deletes an item in a table. The key should be a struct with the
+// appropriate dynamodbav attribute tags pertaining to the table's primary key. Conditions may be
+// specified with WithCondition.
+func (tx *TransactWrite) Delete(
+	tableName string, itemKey interface{}, opts ...WriteOption) *TransactWrite {
+
+	key, err := encodeItem(tx.client.Encoder, itemKey)
+	if err != nil {
+		return tx.fail(err)
+	}
+
+	del := &dynamodb.Delete{
+		TableName: aws.String(tableName),
+		Key:       key,
+	}
+	options := newWriteOptions(opts)
+	dynamodbExpr, hasExpression, err := options.buildExpression(tx.client.Encoder, nil)
+	if err != nil {
+		return tx.fail(err)
+	} else if hasExpression {
+		del.ConditionExpression = dynamodbExpr.Condition()
+		del.ExpressionAttributeNames = dynamodbExpr.Names()
+		del.ExpressionAttributeValues = dynamodbExpr.Values()
+	}
+
+	return tx.add(&dynamodb.TransactWriteItem{Delete: del})
+}
+
+// ConditionCheck adds an operation which checks a condition on an item in a table without
+// modifying it. If the condition is not met, the transaction is canceled.
+func (tx *TransactWrite) ConditionCheck(tableName string, itemKey interface{},
+	condition expression.ConditionBuilder) *TransactWrite {
+
+	key, err := encodeItem(tx.client.Encoder, itemKey)
+	if err != nil {
+		return tx.fail(err)
+	}
+
+	dynamodbExpr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return tx.fail(err)
+	}
+
+	return tx.add(&dynamodb.TransactWriteItem{ConditionCheck: &dynamodb.ConditionCheck{
+		TableName:                 aws.String(tableName),
+		Key:                       key,
+		ConditionExpression:       dynamodbExpr.Condition(),
+		ExpressionAttributeNames:  dynamodbExpr.Names(),
+		ExpressionAttributeValues: dynamodbExpr.Values(),
+	}})
+}
+
+// SetClientRequestToken sets the client request token of the transaction, which makes the
+// transaction idempotent for 10 minutes after it is first committed.
+func (tx *TransactWrite) SetClientRequestToken(token string) *TransactWrite {
+	tx.clientRequestToken = aws.String(token)
+	return tx
+}
+
+// Commit executes all operations in the transaction atomically. If any operation fails to build,
+// its error is returned without calling DynamoDB.
+//
+// If the transaction is canceled, such as when an operation's condition is not met, an
+// *ErrTransactionCanceled error is returned with the reason for each operation.
+func (tx *TransactWrite) Commit(ctx context.Context) error {
+	if tx.err != nil {
+		return tx.err
+	}
+
+	cacheKeys := []string{}
+	for _, item := range tx.items {
+		tableName, writtenItem := transactWrittenItem(item)
+		if tableName == "" {
+			continue
+		}
+		itemCacheKeys, err := tx.client.itemCacheKeys(ctx, tableName, writtenItem)
+		if err != nil {
+			return err
+		}
+		cacheKeys = append(cacheKeys, itemCacheKeys...)
+	}
+	defer tx.client.invalidateCachedItems(cacheKeys)
+
+	_, err := tx.client.dynamodbService.TransactWriteItemsWithContext(ctx,
+		&dynamodb.TransactWriteItemsInput{
+			TransactItems:      tx.items,
+			ClientRequestToken: tx.clientRequestToken,
+		})
+
+	return convertTransactionError(err)
+}
+
+// transactWrittenItem returns the table and the item or key written by a transaction operation.
+// Condition checks do not write items, so an empty table name is returned for them.
+func transactWrittenItem(
+	item *dynamodb.TransactWriteItem) (string, map[string]*dynamodb.AttributeValue) {
+
+	switch {
+	case item.Put != nil:
+		return aws.StringValue(item.Put.TableName), item.Put.Item
+	case item.Update != nil:
+		return aws.StringValue(item.Update.TableName), item.Update.Key
+	case item.Delete != nil:
+		return aws.StringValue(item.Delete.TableName), item.Delete.Key
+	}
+	return "", nil
+}
+
+func (tx *TransactWrite) add(item *dynamodb.TransactWriteItem) *TransactWrite {
+	tx.items = append(tx.items, item)
+	return tx
+}
+
+func (tx *TransactWrite) fail(err error) *TransactWrite {
+	if tx.err == nil {
+		tx.err = fmt.Errorf("transaction operation %d: %w", len(tx.items), err)
+	}
+	return tx
+}
+
+// TransactGet accumulates get operations to be executed atomically with TransactGetItems.
+type TransactGet struct {
+	client *Client
+
+	items       []*dynamodb.TransactGetItem
+	returnItems []interface{}
+	err         error
+}
+
+// TransactGet initializes a new get transaction.
+func (client *Client) TransactGet() *TransactGet {
+	return &TransactGet{
+		client:      client,
+		items:       []*dynamodb.TransactGetItem{},
+		returnItems: []interface{}{},
+	}
+}
+
+// Get adds an operation which retrieves a single item by its key in the same way as Client.Get.
+// The item is returned in returnItem when the transaction is executed.
+func (tx *TransactGet) Get(tableName string, itemKey, returnItem interface{}) *TransactGet {
+	key, err := encodeItem(tx.client.Encoder, itemKey)
+	if err != nil && tx.err == nil {
+		tx.err = fmt.Errorf("transaction operation %d: %w", len(tx.items), err)
+	}
+
+	tx.items = append(tx.items, &dynamodb.TransactGetItem{Get: &dynamodb.Get{
+		TableName: aws.String(tableName),
+		Key:       key,
+	}})
+	tx.returnItems = append(tx.returnItems, returnItem)
+
+	return tx
+}
+
+// Execute retrieves all items in the transaction atomically. The returned found flags report
+// whether the item for each operation was found, in the order in which operations were added.
+// Items which are not found are not modified.
+//
+// If the transaction is canceled, an *ErrTransactionCanceled error is returned with the reason
+// for each operation.
+func (tx *TransactGet) Execute(ctx context.Context) ([]bool, error) {
+	if tx.err != nil {
+		return nil, tx.err
+	}
+
+	output, err := tx.client.dynamodbService.TransactGetItemsWithContext(ctx,
+		&dynamodb.TransactGetItemsInput{
+			TransactItems: tx.items,
+		})
+	if err != nil {
+		return nil, convertTransactionError(err)
+	}
+
+	found := make([]bool, len(tx.items))
+	for i, response := range output.Responses {
+		if i >= len(tx.returnItems) || response == nil || response.Item == nil {
+			continue
+		}
+		found[i] = true
+		tableName := aws.StringValue(tx.items[i].Get.TableName)
+		err := tx.client.decodeTableItem(tableName, tx.client.Decoder, response.Item,
+			tx.returnItems[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return found, nil
+}
+
+// convertTransactionError converts transaction cancellations into *ErrTransactionCanceled.
+func convertTransactionError(err error) error {
+	var canceledErr *dynamodb.TransactionCanceledException
+	if !errors.As(err, &canceledErr) {
+		return err
+	}
+
+	reasons := make([]TransactionCancellationReason, 0, len(canceledErr.CancellationReasons))
+	for i, reason := range canceledErr.CancellationReasons {
+		reasons = append(reasons, TransactionCancellationReason{
+			OperationIndex: i,
+			Code:           aws.StringValue(reason.Code),
+			Message:        aws.StringValue(reason.Message),
+		})
+	}
+
+	return &ErrTransactionCanceled{
+		Message: canceledErr.Message(),
+		Reasons: reasons,
+	}
+}
@@ -0,0 +1,145 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func TestTransactWrite(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(3, 3))
+
+	err := client.TransactWrite().
+		Put(fakeTableName, testItem{PK: "p", SK: 5, Value: "new"},
+			WithCondition(expression.AttributeNotExists(expression.Name("pk")))).
+		Update(fakeTableName, testKey{PK: "p", SK: 0}, NewUpdate().Set("value", "updated")).
+		Delete(fakeTableName, testKey{PK: "p", SK: 1}).
+		ConditionCheck(fakeTableName, testKey{PK: "p", SK: 2},
+			expression.Name("value").Equal(expression.Value("v2"))).
+		Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := []testItem{}
+	if _, err := client.Query(fakeTableName, NewExpression().Equal("pk", "p")).
+		NextPage(ctx, &items); err != nil {
+		t.Fatal(err)
+	}
+	values := []string{}
+	for _, item := range items {
+		values = append(values, item.Value)
+	}
+	if strings.Join(values, ",") != "updated,v2,new" {
+		t.Errorf("unexpected items after transaction: %v", values)
+	}
+}
+
+func TestTransactWriteCanceled(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(3, 3))
+
+	err := client.TransactWrite().
+		Put(fakeTableName, testItem{PK: "p", SK: 5}).
+		ConditionCheck(fakeTableName, testKey{PK: "p", SK: 0},
+			expression.Name("value").Equal(expression.Value("wrong"))).
+		Delete(fakeTableName, testKey{PK: "p", SK: 1},
+			WithCondition(expression.AttributeNotExists(expression.Name("value")))).
+		Commit(ctx)
+
+	canceledErr, canceled := err.(*ErrTransactionCanceled)
+	if !canceled {
+		t.Fatalf("expected ErrTransactionCanceled, found %v", err)
+	}
+	if len(canceledErr.Reasons) != 3 || canceledErr.Reasons[0].Code != "None" {
+		t.Errorf("expected a reason for each operation, found %+v", canceledErr.Reasons)
+	}
+	failed := canceledErr.FailedOperations()
+	if len(failed) != 2 || failed[0].OperationIndex != 1 || failed[1].OperationIndex != 2 ||
+		failed[0].Code != "ConditionalCheckFailed" {
+		t.Errorf("expected operations 1 and 2 to fail, found %+v", failed)
+	}
+	if !strings.Contains(err.Error(), "operation 1: ConditionalCheckFailed") {
+		t.Errorf("expected the error to describe the failed operation, found %v", err)
+	}
+
+	// no operation is applied
+	if err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 1}, &testItem{}); err != nil {
+		t.Errorf("expected the item to remain, found %v", err)
+	}
+	err = client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 5}, &testItem{})
+	if _, notFound := err.(*ErrItemNotFound); !notFound {
+		t.Errorf("expected ErrItemNotFound, found %v", err)
+	}
+}
+
+func TestTransactWriteBuildError(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(1, 1))
+
+	err := client.TransactWrite().
+		Delete(fakeTableName, testKey{PK: "p", SK: 0}).
+		Update(fakeTableName, testKey{PK: "p", SK: 0}, NewUpdate()).
+		Commit(ctx)
+	if err == nil || !strings.HasPrefix(err.Error(), "transaction operation 1:") {
+		t.Errorf("expected an error for operation 1, found %v", err)
+	}
+
+	// the transaction is not committed
+	if err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 0}, &testItem{}); err != nil {
+		t.Errorf("expected the item to remain, found %v", err)
+	}
+}
+
+func TestTransactGet(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(3, 3))
+
+	first, missing, last := testItem{}, testItem{Value: "unchanged"}, testItem{}
+	found, err := client.TransactGet().
+		Get(fakeTableName, testKey{PK: "p", SK: 0}, &first).
+		Get(fakeTableName, testKey{PK: "p", SK: 9}, &missing).
+		Get(fakeTableName, testKey{PK: "p", SK: 2}, &last).
+		Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 || !found[0] || found[1] || !found[2] {
+		t.Errorf("expected items 0 and 2 to be found, found %v", found)
+	}
+	if first.Value != "v0" || last.Value != "v2" || missing.Value != "unchanged" {
+		t.Errorf("unexpected items: %+v, %+v, %+v", first, missing, last)
+	}
+}
+
+func TestConvertTransactionError(t *testing.T) {
+	canceled := &dynamodb.TransactionCanceledException{
+		Message_: aws.String("Transaction cancelled"),
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("TransactionConflict"), Message: aws.String("conflict")},
+		},
+	}
+
+	err := convertTransactionError(fmt.Errorf("request failed: %w", canceled))
+	canceledErr, ok := err.(*ErrTransactionCanceled)
+	if !ok {
+		t.Fatalf("expected ErrTransactionCanceled, found %v", err)
+	}
+	failed := canceledErr.FailedOperations()
+	if canceledErr.Message != "Transaction cancelled" || len(failed) != 1 ||
+		failed[0].OperationIndex != 1 || failed[0].Message != "conflict" {
+		t.Errorf("unexpected error: %+v", canceledErr)
+	}
+
+	other := fmt.Errorf("other")
+	if err := convertTransactionError(other); err != other {
+		t.Errorf("expected other errors to be unchanged, found %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package autoquery
+
+import "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+
+// Update contains actions to be applied to an item by Client.Update.
+type Update struct {
+	actions []updateAction
+}
+
+type updateAction func(builder expression.UpdateBuilder, value valueFunc) expression.UpdateBuilder
+
+// valueFunc builds the operand of a value in an update action.
+type valueFunc func(v interface{}) expression.ValueBuilder
+
+// NewUpdate creates a new Update instance.
+func NewUpdate() *Update {
+	return &Update{
+		actions: []updateAction{},
+	}
+}
+
+// Set sets the attribute attr to v.
+func (update *Update) Set(attr string, v interface{}) *Update {
+	return update.add(func(builder expression.UpdateBuilder,
+		value valueFunc) expression.UpdateBuilder {
+
+		return builder.Set(expression.Name(attr), value(v))
+	})
+}
+
+// SetIfNotExists sets the attribute attr to v only if the item does not already have the
+// attribute.
+func (update *Update) SetIfNotExists(attr string, v interface{}) *Update {
+	return update.add(func(builder expression.UpdateBuilder,
+		value valueFunc) expression.UpdateBuilder {
+
+		return builder.Set(expression.Name(attr),
+			expression.Name(attr).IfNotExists(value(v)))
+	})
+}
+
+// ListAppend appends the values in list v to the list attribute attr.
+func (update *Update) ListAppend(attr string, v interface{}) *Update {
+	return update.add(func(builder expression.UpdateBuilder,
+		value valueFunc) expression.UpdateBuilder {
+
+		return builder.Set(expression.Name(attr),
+			expression.Name(attr).ListAppend(value(v)))
+	})
+}
+
+// Add adds the number v to the number attribute attr, or adds the elements of set v to the set
+// attribute attr. If the item does not have the attribute, it is created.
+func (update *Update) Add(attr string, v interface{}) *Update {
+	return update.add(func(builder expression.UpdateBuilder,
+		value valueFunc) expression.UpdateBuilder {
+
+		return builder.Add(expression.Name(attr), value(v))
+	})
+}
+
+// Remove removes the attribute attr from the item.
+func (update *Update) Remove(attr string) *Update {
+	return update.add(func(builder expression.UpdateBuilder,
+		value valueFunc) expression.UpdateBuilder {
+
+		return builder.Remove(expression.Name(attr))
+	})
+}
+
+// Delete removes the elements of set v from the set attribute attr.
+func (update *Update) Delete(attr string, v interface{}) *Update {
+	return update.add(func(builder expression.UpdateBuilder,
+		value valueFunc) expression.UpdateBuilder {
+
+		return builder.Delete(expression.Name(attr), value(v))
+	})
+}
+
+func (update *Update) add(action updateAction) *Update {
+	update.actions = append(update.actions, action)
+	return update
+}
+
+func (update *Update) hasActions() bool {
+	return len(update.actions) > 0
+}
+
+// builder returns a new update builder with all actions applied. Values are marshaled with
+// encoder in the same way as items, or with the default dynamodbattribute rules if encoder is nil.
+func (update *Update) builder(encoder Encoder) (expression.UpdateBuilder, error) {
+	var err error
+	value := func(v interface{}) expression.ValueBuilder {
+		encoded, encodeErr := encodeValue(encoder, v)
+		if encodeErr != nil && err == nil {
+			err = encodeErr
+		}
+		return expression.Value(encoded)
+	}
+
+	builder := expression.UpdateBuilder{}
+	for _, action := range update.actions {
+		builder = action(builder, value)
+	}
+	return builder, err
+}
+
+// clone returns a copy of the update which may be modified without affecting the original.
+func (update *Update) clone() *Update {
+	return &Update{
+		actions: append([]updateAction{}, update.actions...),
+	}
+}
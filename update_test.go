@@ -0,0 +1,115 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+)
+
+type counterItem struct {
+	PK    string   `dynamodbav:"pk"`
+	SK    int      `dynamodbav:"sk"`
+	Value string   `dynamodbav:"value,omitempty"`
+	Count int      `dynamodbav:"count"`
+	Tags  []string `dynamodbav:"tags,omitempty"`
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(3, 2))
+
+	update := NewUpdate().Set("tags", []string{"a"}).Add("count", 2).Remove("value")
+	newItem := counterItem{}
+	err := client.Update(ctx, fakeTableName, testKey{PK: "p", SK: 1}, update,
+		WithReturnValues("ALL_NEW", &newItem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newItem.Count != 2 || newItem.Value != "" || len(newItem.Tags) != 1 {
+		t.Errorf("unexpected updated item: %+v", newItem)
+	}
+
+	// the returned old item does not include the update
+	oldItem := counterItem{}
+	err = client.Update(ctx, fakeTableName, testKey{PK: "p", SK: 1}, NewUpdate().Add("count", 3),
+		WithReturnValues("ALL_OLD", &oldItem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := counterItem{}
+	if err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 1}, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if oldItem.Count != 2 || stored.Count != 5 {
+		t.Errorf("expected count 2 before and 5 after update, found %d and %d",
+			oldItem.Count, stored.Count)
+	}
+}
+
+func TestUpdateCreatesItem(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(1, 2))
+
+	err := client.Update(ctx, fakeTableName, testKey{PK: "p", SK: 7},
+		NewUpdate().Set("value", "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := testItem{}
+	if err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 7}, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Value != "new" {
+		t.Errorf("unexpected created item: %+v", item)
+	}
+}
+
+func TestUpdateRequiresActions(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(1, 2))
+
+	for _, update := range []*Update{nil, NewUpdate()} {
+		if err := client.Update(ctx, fakeTableName, testKey{PK: "p"}, update); err == nil {
+			t.Error("expected an error for an update without actions")
+		}
+	}
+}
+
+func TestUpdateCloneIsIndependent(t *testing.T) {
+	update := NewUpdate().Set("value", "a")
+	clone := update.clone().Remove("tags")
+	if len(update.actions) != 1 || len(clone.actions) != 2 {
+		t.Errorf("expected 1 and 2 actions, found %d and %d",
+			len(update.actions), len(clone.actions))
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(3, 2)
+	client := NewClient(svc)
+
+	deleted := testItem{}
+	err := client.Delete(ctx, fakeTableName, testKey{PK: "p", SK: 1},
+		WithReturnValues("ALL_OLD", &deleted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.SK != 1 || deleted.Value != "v1" {
+		t.Errorf("unexpected deleted item: %+v", deleted)
+	}
+	err = client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 1}, &testItem{})
+	if _, notFound := err.(*ErrItemNotFound); !notFound {
+		t.Errorf("expected ErrItemNotFound, found %v", err)
+	}
+
+	// deleting a missing item is not an error and does not return attributes
+	missing := testItem{Value: "unchanged"}
+	err = client.Delete(ctx, fakeTableName, testKey{PK: "p", SK: 1},
+		WithReturnValues("ALL_OLD", &missing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing.Value != "unchanged" {
+		t.Errorf("expected the return item to be unchanged, found %+v", missing)
+	}
+}
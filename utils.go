@@ -5,3 +5,25 @@ import "reflect"
 func typesMatch(a, b interface{}) bool {
 	return reflect.TypeOf(a) == reflect.TypeOf(b)
 }
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueStrings returns the unique values across all lists, in order of first appearance.
+func uniqueStrings(lists ...[]string) []string {
+	unique := []string{}
+	for _, values := range lists {
+		for _, value := range values {
+			if !containsString(unique, value) {
+				unique = append(unique, value)
+			}
+		}
+	}
+	return unique
+}
@@ -0,0 +1,170 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// autoqueryTagKey is the struct tag key for autoquery field options.
+const autoqueryTagKey = "autoquery"
+
+// versionTagValue marks a struct field as the version attribute of an item, which enables
+// optimistic locking on Table.Put and Table.Update.
+const versionTagValue = "version"
+
+// versionField is an integer struct field tagged as the version attribute of an item.
+type versionField struct {
+	attribute string
+	value     reflect.Value
+}
+
+// findVersionField returns the version field of item, or nil if item does not have a field
+// tagged with `autoquery:"version"`. Only top-level fields of the struct are considered.
+func findVersionField(item interface{}) (*versionField, error) {
+	itemValue := reflect.ValueOf(item)
+	for itemValue.Kind() == reflect.Ptr && !itemValue.IsNil() {
+		itemValue = itemValue.Elem()
+	}
+	if itemValue.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	itemType := itemValue.Type()
+	for i := 0; i < itemType.NumField(); i++ {
+		field := itemType.Field(i)
+		if !hasAutoqueryTagOption(field, versionTagValue) {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return nil, fmt.Errorf("version field %s must be an integer, got %s",
+				field.Name, field.Type)
+		}
+
+		return &versionField{
+			attribute: attributeName(field),
+			value:     itemValue.Field(i),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (field *versionField) get() int64 {
+	if field.value.Kind() >= reflect.Uint && field.value.Kind() <= reflect.Uint64 {
+		return int64(field.value.Uint())
+	}
+	return field.value.Int()
+}
+
+func (field *versionField) set(version int64) {
+	if field.value.Kind() >= reflect.Uint && field.value.Kind() <= reflect.Uint64 {
+		field.value.SetUint(uint64(version))
+	} else {
+		field.value.SetInt(version)
+	}
+}
+
+// condition returns a condition that the stored version of the item matches version. An item
+// with version 0 is expected not to exist in the table.
+func (field *versionField) condition(version int64) expression.ConditionBuilder {
+	if version == 0 {
+		return expression.AttributeNotExists(expression.Name(field.attribute))
+	}
+	return expression.Name(field.attribute).Equal(expression.Value(version))
+}
+
+// putVersioned puts an item with optimistic locking on its version field.
+func (table Table) putVersioned(ctx context.Context,
+	item interface{}, field *versionField, opts []WriteOption) error {
+
+	if !field.value.CanSet() {
+		return fmt.Errorf("versioned item must be a pointer to a struct, got %T", item)
+	}
+
+	options := newWriteOptions(opts)
+	hasOtherConditions := len(options.conditions) > 0
+
+	version := field.get()
+	options.conditions = append(options.conditions, field.condition(version))
+
+	// the new version is written with the item and kept only if the write succeeds
+	field.set(version + 1)
+	tableItem, err := table.marshalItem(item)
+	if err == nil {
+		err = table.autoqueryClient.putItem(ctx, table.name, tableItem, options)
+	}
+	if err != nil {
+		field.set(version)
+		return convertVersionError(err, table.name, version, hasOtherConditions)
+	}
+
+	return nil
+}
+
+// updateVersioned updates an item with optimistic locking on the version field of itemKey.
+func (table Table) updateVersioned(ctx context.Context, itemKey interface{},
+	update *Update, field *versionField, opts []WriteOption) error {
+
+	if !field.value.CanSet() {
+		return fmt.Errorf("versioned item must be a pointer to a struct, got %T", itemKey)
+	} else if update == nil {
+		return fmt.Errorf("update does not contain any actions")
+	}
+
+	// itemKey holds the version and may be a full item, so only the table's key attributes are used
+	key, err := table.extractKey(ctx, itemKey)
+	if err != nil {
+		return err
+	}
+
+	options := newWriteOptions(opts)
+	hasOtherConditions := len(options.conditions) > 0
+
+	version := field.get()
+	options.conditions = append(options.conditions, field.condition(version))
+	update = update.clone().Set(field.attribute, version+1)
+
+	if err := table.autoqueryClient.updateItem(ctx, table.name, key, update, options); err != nil {
+		return convertVersionError(err, table.name, version, hasOtherConditions)
+	}
+
+	field.set(version + 1)
+	return nil
+}
+
+// convertVersionError converts condition failures into *ErrVersionConflict, unless the write has
+// other conditions which may have caused the failure.
+func convertVersionError(
+	err error, tableName string, version int64, hasOtherConditions bool) error {
+
+	if _, ok := err.(*ErrConditionFailed); ok && !hasOtherConditions {
+		return &ErrVersionConflict{TableName: tableName, ExpectedVersion: version}
+	}
+	return err
+}
+
+func hasAutoqueryTagOption(field reflect.StructField, option string) bool {
+	for _, tagOption := range strings.Split(field.Tag.Get(autoqueryTagKey), ",") {
+		if tagOption == option {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeName returns the DynamoDB attribute name of a struct field, using the name in its
+// dynamodbav tag if specified.
+func attributeName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("dynamodbav"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
@@ -0,0 +1,133 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+type versionedItem struct {
+	PK      string `dynamodbav:"pk"`
+	SK      int    `dynamodbav:"sk"`
+	Value   string `dynamodbav:"value"`
+	Version int    `dynamodbav:"version" autoquery:"version"`
+}
+
+func expectVersionConflict(t *testing.T, err error, expectedVersion int64) {
+	t.Helper()
+	conflict, ok := err.(*ErrVersionConflict)
+	if !ok || conflict.ExpectedVersion != expectedVersion {
+		t.Errorf("expected ErrVersionConflict on version %d, found %v", expectedVersion, err)
+	}
+}
+
+func TestPutVersioned(t *testing.T) {
+	ctx := context.Background()
+	table := NewClient(newFakeService(0, 2)).Table(fakeTableName)
+
+	item := versionedItem{PK: "p", SK: 1, Value: "a"}
+	if err := table.Put(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	stale := item
+	if err := table.Put(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Version != 2 {
+		t.Errorf("expected version 2, found %d", item.Version)
+	}
+
+	// the version of a failed write is restored
+	stale.Value = "b"
+	expectVersionConflict(t, table.Put(ctx, &stale), 1)
+	if stale.Version != 1 {
+		t.Errorf("expected version 1 to be restored, found %d", stale.Version)
+	}
+
+	// an item with version 0 is expected not to exist
+	expectVersionConflict(t, table.Put(ctx, &versionedItem{PK: "p", SK: 1}), 0)
+
+	stored := versionedItem{}
+	if err := table.Get(ctx, testKey{PK: "p", SK: 1}, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Value != "a" || stored.Version != 2 {
+		t.Errorf("unexpected stored item: %+v", stored)
+	}
+}
+
+func TestPutVersionedRestoresVersionOnError(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(0, 2)
+	failed := errors.New("put failed")
+	svc.writeErrs = []error{failed}
+	table := NewClient(svc).Table(fakeTableName)
+
+	item := versionedItem{PK: "p", SK: 1}
+	if err := table.Put(ctx, &item); err != failed {
+		t.Errorf("expected the service error, found %v", err)
+	}
+	if item.Version != 0 {
+		t.Errorf("expected version 0 to be restored, found %d", item.Version)
+	}
+
+	// conflicts are not reported as version conflicts when other conditions may have failed
+	if err := table.Put(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	item.Version = 0
+	err := table.Put(ctx, &item,
+		WithCondition(expression.AttributeExists(expression.Name("value"))))
+	expectConditionFailed(t, err)
+	if item.Version != 0 {
+		t.Errorf("expected version 0 to be restored, found %d", item.Version)
+	}
+}
+
+func TestPutVersionedRequiresPointer(t *testing.T) {
+	table := NewClient(newFakeService(0, 2)).Table(fakeTableName)
+	if err := table.Put(context.Background(), versionedItem{PK: "p"}); err == nil {
+		t.Error("expected an error for a versioned item which is not a pointer")
+	}
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	ctx := context.Background()
+	table := NewClient(newFakeService(0, 2)).Table(fakeTableName)
+
+	item := versionedItem{PK: "p", SK: 1, Value: "a"}
+	if err := table.Put(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	stale := item
+
+	// the full item is used as the key, and only its key attributes are sent
+	if err := table.Update(ctx, &item, NewUpdate().Set("value", "b")); err != nil {
+		t.Fatal(err)
+	}
+	if item.Version != 2 {
+		t.Errorf("expected version 2, found %d", item.Version)
+	}
+
+	expectVersionConflict(t, table.Update(ctx, &stale, NewUpdate().Set("value", "c")), 1)
+	if stale.Version != 1 {
+		t.Errorf("expected version 1 to be unchanged, found %d", stale.Version)
+	}
+
+	stored := versionedItem{}
+	if err := table.Get(ctx, testKey{PK: "p", SK: 1}, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Value != "b" || stored.Version != 2 {
+		t.Errorf("unexpected stored item: %+v", stored)
+	}
+}
+
+func TestUpdateVersionedRequiresActions(t *testing.T) {
+	table := NewClient(newFakeService(0, 2)).Table(fakeTableName)
+	if err := table.Update(context.Background(), &versionedItem{PK: "p"}, nil); err == nil {
+		t.Error("expected an error for an update without actions")
+	}
+}
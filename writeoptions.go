@@ -0,0 +1,121 @@
+package autoquery
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// WriteOption configures a Put, Update or Delete call.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	conditions   []expression.ConditionBuilder
+	returnValues string
+	returnItem   interface{}
+}
+
+// WithCondition adds a condition to a write. The write only succeeds if the condition is met by
+// the existing item, otherwise an *ErrConditionFailed error is returned. Multiple conditions are
+// combined with AND.
+//
+// For example, a create-only Put may be specified with:
+//
+//	autoquery.WithCondition(expression.AttributeNotExists(expression.Name("id")))
+func WithCondition(condition expression.ConditionBuilder) WriteOption {
+	return func(options *writeOptions) {
+		options.conditions = append(options.conditions, condition)
+	}
+}
+
+// WithReturnValues sets the ReturnValues parameter of a write, such as "ALL_OLD" or "ALL_NEW".
+// The returned attributes are unmarshaled into returnItem in the same way as Client.Get. If no
+// attributes are returned, returnItem is not modified.
+//
+// Put and Delete only support "NONE" and "ALL_OLD".
+func WithReturnValues(returnValues string, returnItem interface{}) WriteOption {
+	return func(options *writeOptions) {
+		options.returnValues = returnValues
+		options.returnItem = returnItem
+	}
+}
+
+func newWriteOptions(opts []WriteOption) *writeOptions {
+	options := &writeOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// conditionExpression builds the combined condition expression of the write, if any.
+func (options *writeOptions) conditionExpression() (*expression.ConditionBuilder, bool) {
+	switch len(options.conditions) {
+	case 0:
+		return nil, false
+	case 1:
+		return &options.conditions[0], true
+	}
+	condition := expression.And(
+		options.conditions[0], options.conditions[1], options.conditions[2:]...)
+	return &condition, true
+}
+
+// buildExpression builds the condition and update expressions of a write, marshaling update
+// values with encoder. If the write does not have a condition or update, hasExpression is false.
+func (options *writeOptions) buildExpression(encoder Encoder,
+	update *Update) (dynamodbExpr expression.Expression, hasExpression bool, err error) {
+
+	builder := expression.NewBuilder()
+	if update != nil {
+		if !update.hasActions() {
+			return dynamodbExpr, false, fmt.Errorf("update does not contain any actions")
+		}
+		updateBuilder, err := update.builder(encoder)
+		if err != nil {
+			return dynamodbExpr, false, err
+		}
+		builder = builder.WithUpdate(updateBuilder)
+		hasExpression = true
+	}
+	if condition, hasCondition := options.conditionExpression(); hasCondition {
+		builder = builder.WithCondition(*condition)
+		hasExpression = true
+	}
+
+	if !hasExpression {
+		return dynamodbExpr, false, nil
+	}
+
+	dynamodbExpr, err = builder.Build()
+	return dynamodbExpr, err == nil, err
+}
+
+func (options *writeOptions) returnValuesParam() *string {
+	if options.returnValues == "" {
+		return nil
+	}
+	return aws.String(options.returnValues)
+}
+
+func (options *writeOptions) decodeReturnItem(client *Client, tableName string,
+	attributes map[string]*dynamodb.AttributeValue) error {
+
+	if options.returnItem == nil || len(attributes) == 0 {
+		return nil
+	}
+	return client.decodeTableItem(tableName, client.Decoder, attributes, options.returnItem)
+}
+
+// convertWriteError converts conditional check failures into *ErrConditionFailed.
+func convertWriteError(err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return &ErrConditionFailed{Message: aerr.Message()}
+	}
+	return err
+}
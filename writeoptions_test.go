@@ -0,0 +1,102 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func expectConditionFailed(t *testing.T, err error) {
+	t.Helper()
+	if _, failed := err.(*ErrConditionFailed); !failed {
+		t.Errorf("expected ErrConditionFailed, found %v", err)
+	}
+}
+
+func TestConditionalPut(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(2, 2))
+	createOnly := WithCondition(expression.AttributeNotExists(expression.Name("pk")))
+
+	err := client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 1, Value: "new"}, createOnly)
+	expectConditionFailed(t, err)
+
+	item := testItem{}
+	if err := client.Get(ctx, fakeTableName, testKey{PK: "p", SK: 1}, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Value != "v1" {
+		t.Errorf("expected the item to be unchanged, found %+v", item)
+	}
+
+	err = client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 2, Value: "new"}, createOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConditionalUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(2, 2))
+	valueIs := func(value string) WriteOption {
+		return WithCondition(expression.Name("value").Equal(expression.Value(value)))
+	}
+	key := testKey{PK: "p", SK: 1}
+
+	err := client.Update(ctx, fakeTableName, key, NewUpdate().Set("value", "x"), valueIs("v0"))
+	expectConditionFailed(t, err)
+	err = client.Update(ctx, fakeTableName, key, NewUpdate().Set("value", "x"), valueIs("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectConditionFailed(t, client.Delete(ctx, fakeTableName, key, valueIs("v1")))
+	if err := client.Delete(ctx, fakeTableName, key, valueIs("x")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultipleConditions(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newFakeService(2, 2))
+	key := testKey{PK: "p", SK: 0}
+	exists := WithCondition(expression.AttributeExists(expression.Name("value")))
+
+	// every condition must be met
+	err := client.Delete(ctx, fakeTableName, key, exists,
+		WithCondition(expression.Name("value").Equal(expression.Value("v1"))))
+	expectConditionFailed(t, err)
+
+	err = client.Delete(ctx, fakeTableName, key, exists,
+		WithCondition(expression.Name("value").Equal(expression.Value("v0"))),
+		WithCondition(expression.AttributeNotExists(expression.Name("tags"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertWriteError(t *testing.T) {
+	ctx := context.Background()
+	svc := newFakeService(1, 1)
+	throttled := awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException,
+		"throttled", nil)
+	svc.writeErrs = []error{fmt.Errorf("put failed: %w", conditionalCheckFailed()), throttled}
+	client := NewClient(svc)
+
+	err := client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 0})
+	conditionErr, failed := err.(*ErrConditionFailed)
+	if !failed || conditionErr.Message != "The conditional request failed" {
+		t.Errorf("expected ErrConditionFailed with the service message, found %v", err)
+	}
+
+	// other errors are returned unchanged
+	err = client.Put(ctx, fakeTableName, testItem{PK: "p", SK: 0})
+	if !errors.Is(err, throttled) {
+		t.Errorf("expected the service error, found %v", err)
+	}
+}